@@ -0,0 +1,174 @@
+// opensnitch-cli manages a running opensnitchd through its control socket
+// (the -service-socket of the daemon, disabled by default), applying the same
+// actions the GUI does.
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/evilsocket/opensnitch/daemon/core"
+	"github.com/evilsocket/opensnitch/daemon/ui/protocol"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+const name = "opensnitch-cli"
+
+var (
+	socket  = "unix:///run/opensnitchd.sock"
+	timeout = 10 * time.Second
+
+	// certificates to connect to a daemon listening on a TCP address
+	caCert     = ""
+	clientCert = ""
+	clientKey  = ""
+	serverName = ""
+)
+
+type command struct {
+	usage string
+	help  string
+	run   func(client protocol.DaemonClient, args []string) error
+}
+
+var commands = map[string]command{
+	"rules":    {"", "list the rules", cmdRules},
+	"add":      {"-file rule.json | -name NAME -action ACTION [options]", "add or replace a rule", cmdAdd},
+	"enable":   {"NAME", "enable a rule", cmdEnable},
+	"disable":  {"NAME", "disable a rule", cmdDisable},
+	"delete":   {"NAME", "delete a rule", cmdDelete},
+	"stats":    {"", "show the statistics", cmdStats},
+	"events":   {"", "show the connections events as they happen", cmdEvents},
+	"history":  {"[-process PATH] [-host HOST] [-rule NAME] [-since 168h]", "query the connections history", cmdHistory},
+	"firewall": {"on|off", "load or unload the firewall rules", cmdFirewall},
+	"loglevel": {"LEVEL", "change the log level (0 debug, 1 info, 2 important, 3 warning, 4 error)", cmdLogLevel},
+}
+
+var commandsOrder = []string{"rules", "add", "enable", "disable", "delete", "stats", "events", "history", "firewall", "loglevel"}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [-socket ADDRESS] COMMAND [ARGS]\n\nCommands:\n", name)
+	for _, c := range commandsOrder {
+		fmt.Fprintf(os.Stderr, "  %-9s %-10s %s\n", c, commands[c].usage, commands[c].help)
+	}
+	fmt.Fprintf(os.Stderr, "\nOptions:\n")
+	flag.PrintDefaults()
+}
+
+// tlsConfig returns the configuration to connect to a daemon listening on a
+// TCP address, which only accepts the clients with a certificate.
+func tlsConfig() (*tls.Config, error) {
+	if caCert == "" || clientCert == "" || clientKey == "" {
+		return nil, fmt.Errorf("The CA of the daemon, and the certificate and key of the client are needed to connect to a TCP address")
+	}
+	raw, err := ioutil.ReadFile(caCert)
+	if err != nil {
+		return nil, fmt.Errorf("Error loading CA certificate %s: %s", caCert, err)
+	}
+	pool := x509.NewCertPool()
+	if pool.AppendCertsFromPEM(raw) == false {
+		return nil, fmt.Errorf("No valid certificates found in %s", caCert)
+	}
+	cert, err := tls.LoadX509KeyPair(clientCert, clientKey)
+	if err != nil {
+		return nil, fmt.Errorf("Error loading client certificate %s: %s", clientCert, err)
+	}
+	return &tls.Config{
+		RootCAs:      pool,
+		Certificates: []tls.Certificate{cert},
+		ServerName:   serverName,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+func dial(address string) (*grpc.ClientConn, error) {
+	if strings.HasPrefix(address, "unix://") == true {
+		return grpc.Dial(address[7:], grpc.WithInsecure(),
+			grpc.WithDialer(func(addr string, timeout time.Duration) (net.Conn, error) {
+				return net.DialTimeout("unix", addr, timeout)
+			}))
+	}
+	conf, err := tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	return grpc.Dial(address, grpc.WithTransportCredentials(credentials.NewTLS(conf)))
+}
+
+// notify sends an action to the daemon, and checks its reply.
+func notify(client protocol.DaemonClient, action protocol.Action, data string, rules []*protocol.Rule) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	reply, err := client.Notify(ctx, &protocol.Notification{
+		Id:         uint64(time.Now().UnixNano()),
+		ClientName: fmt.Sprintf("%s@%s", name, core.GetHostname()),
+		Type:       action,
+		Data:       data,
+		Rules:      rules,
+	})
+	if err != nil {
+		return err
+	}
+	if reply.Code == protocol.NotificationReplyCode_ERROR {
+		return fmt.Errorf("%s: %s", action, reply.Data)
+	}
+	return nil
+}
+
+func cmdFirewall(client protocol.DaemonClient, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("Usage: firewall on|off")
+	}
+	switch args[0] {
+	case "on":
+		return notify(client, protocol.Action_LOAD_FIREWALL, "", nil)
+	case "off":
+		return notify(client, protocol.Action_UNLOAD_FIREWALL, "", nil)
+	}
+	return fmt.Errorf("Unknown firewall state %s, expected on or off", args[0])
+}
+
+func main() {
+	flag.StringVar(&socket, "socket", socket, "Address of the daemon control socket (unix:///path/to/socket, or host:port).")
+	flag.DurationVar(&timeout, "timeout", timeout, "Timeout of the requests to the daemon.")
+	flag.StringVar(&caCert, "ca", caCert, "CA the certificate of a daemon listening on a TCP address must be signed by.")
+	flag.StringVar(&clientCert, "cert", clientCert, "Certificate to connect to a daemon listening on a TCP address.")
+	flag.StringVar(&clientKey, "key", clientKey, "Key of the certificate to connect to a daemon listening on a TCP address.")
+	flag.StringVar(&serverName, "server-name", serverName, "Name expected in the certificate of the daemon, if it's not the host of the address.")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, found := commands[flag.Arg(0)]
+	if !found {
+		fmt.Fprintf(os.Stderr, "Unknown command %s\n\n", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+
+	con, err := dial(socket)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error connecting to %s: %s\n", socket, err)
+		os.Exit(1)
+	}
+	defer con.Close()
+
+	if err := cmd.run(protocol.NewDaemonClient(con), flag.Args()[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+}
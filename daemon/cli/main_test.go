@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/evilsocket/opensnitch/daemon/ui/protocol"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+// fakeDaemon replies with the configured rules and configuration, and saves
+// the notifications sent to it.
+type fakeDaemon struct {
+	protocol.DaemonClient
+	rules    []*protocol.Rule
+	config   string
+	notified []*protocol.Notification
+}
+
+func (d *fakeDaemon) GetRules(ctx context.Context, in *protocol.Notification, opts ...grpc.CallOption) (*protocol.Notification, error) {
+	return &protocol.Notification{Rules: d.rules, Data: d.config}, nil
+}
+
+func (d *fakeDaemon) Notify(ctx context.Context, in *protocol.Notification, opts ...grpc.CallOption) (*protocol.NotificationReply, error) {
+	d.notified = append(d.notified, in)
+	return &protocol.NotificationReply{Id: in.Id, Code: protocol.NotificationReplyCode_OK}, nil
+}
+
+func TestCommandsArguments(t *testing.T) {
+	d := &fakeDaemon{rules: []*protocol.Rule{{Name: "allow-curl"}}}
+
+	for _, tc := range []struct {
+		name string
+		run  func(client protocol.DaemonClient, args []string) error
+		args []string
+	}{
+		{"enable", cmdEnable, nil},
+		{"enable", cmdEnable, []string{"allow-curl", "allow-wget"}},
+		{"enable", cmdEnable, []string{"allow-wget"}},
+		{"disable", cmdDisable, nil},
+		{"delete", cmdDelete, nil},
+		{"firewall", cmdFirewall, nil},
+		{"firewall", cmdFirewall, []string{"maybe"}},
+		{"loglevel", cmdLogLevel, nil},
+		{"loglevel", cmdLogLevel, []string{"debug"}},
+		{"loglevel", cmdLogLevel, []string{"5"}},
+		{"add", cmdAdd, []string{"-action", "deny"}},
+		{"add", cmdAdd, []string{"-file", "/nonexistent/rule.json"}},
+	} {
+		if err := tc.run(d, tc.args); err == nil {
+			t.Errorf("%s %v: error expected", tc.name, tc.args)
+		}
+	}
+	if len(d.notified) != 0 {
+		t.Error("Invalid commands sent to the daemon:", d.notified)
+	}
+
+	if err := cmdDisable(d, []string{"allow-curl"}); err != nil {
+		t.Error("disable:", err)
+	}
+	if err := cmdFirewall(d, []string{"off"}); err != nil {
+		t.Error("firewall:", err)
+	}
+	if len(d.notified) != 2 || d.notified[0].Type != protocol.Action_DISABLE_RULE || d.notified[0].Rules[0].Name != "allow-curl" ||
+		d.notified[1].Type != protocol.Action_UNLOAD_FIREWALL {
+		t.Error("Commands not sent to the daemon:", d.notified)
+	}
+}
+
+func TestCmdLogLevel(t *testing.T) {
+	d := &fakeDaemon{config: `{"DefaultAction": "deny", "LogLevel": 1, "Server": {"Address": "unix:///tmp/osui.sock"}}`}
+	if err := cmdLogLevel(d, []string{"3"}); err != nil {
+		t.Fatal("loglevel:", err)
+	}
+	if len(d.notified) != 1 || d.notified[0].Type != protocol.Action_CHANGE_CONFIG {
+		t.Fatal("Configuration not sent:", d.notified)
+	}
+
+	conf := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(d.notified[0].Data), &conf); err != nil {
+		t.Fatal("Invalid configuration sent:", err)
+	}
+	if conf["LogLevel"] != float64(3) {
+		t.Error("LogLevel not changed:", conf["LogLevel"])
+	}
+	server, ok := conf["Server"].(map[string]interface{})
+	if conf["DefaultAction"] != "deny" || !ok || server["Address"] != "unix:///tmp/osui.sock" {
+		t.Error("Options of the configuration not kept:", conf)
+	}
+
+	d.config = "not json"
+	if err := cmdLogLevel(d, []string{"3"}); err == nil {
+		t.Error("Invalid configuration not detected")
+	}
+}
+
+func TestPrintTop(t *testing.T) {
+	var out bytes.Buffer
+	printTop(&out, "By port", map[string]uint64{"80": 5, "443": 20, "53": 5, "22": 1})
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	expected := []string{"By port", "443\t20", "53\t5", "80\t5", "22\t1"}
+	if len(lines) != len(expected) {
+		t.Fatal("Unexpected output:", out.String())
+	}
+	for i, line := range lines {
+		if strings.TrimSpace(line) != expected[i] {
+			t.Errorf("Line %d: expected %q, got %q", i, expected[i], line)
+		}
+	}
+
+	out.Reset()
+	printTop(&out, "By port", nil)
+	if out.Len() != 0 {
+		t.Error("Empty map printed:", out.String())
+	}
+}
+
+func TestPrintTraffic(t *testing.T) {
+	var out bytes.Buffer
+	printTraffic(&out, "By executable", map[string]*protocol.Traffic{
+		"/usr/bin/wget": {Connections: 1, BytesIn: 10, BytesOut: 10},
+		"/usr/bin/curl": {Connections: 2, BytesIn: 2000, BytesOut: 200, Duration: 90},
+	})
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "By executable") {
+		t.Fatal("Unexpected output:", out.String())
+	}
+	if fields := strings.Fields(lines[1]); len(fields) != 5 || fields[0] != "/usr/bin/curl" || fields[4] != "1m30s" {
+		t.Error("Processes not sorted by bytes:", out.String())
+	}
+
+	out.Reset()
+	printTraffic(&out, "By rule", nil)
+	if out.Len() != 0 {
+		t.Error("Empty map printed:", out.String())
+	}
+}
+
+func TestDialTCPWithoutTLS(t *testing.T) {
+	if con, err := dial("127.0.0.1:50052"); err == nil {
+		con.Close()
+		t.Error("Plaintext connection to a TCP address not refused")
+	}
+	if con, err := dial("unix:///tmp/opensnitchd-test.sock"); err != nil {
+		t.Error("Connection to a unix socket refused:", err)
+	} else {
+		con.Close()
+	}
+}
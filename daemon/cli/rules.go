@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"text/tabwriter"
+
+	"github.com/evilsocket/opensnitch/daemon/rule"
+	"github.com/evilsocket/opensnitch/daemon/ui/protocol"
+
+	"golang.org/x/net/context"
+)
+
+// getRules returns the rules loaded by the daemon in the Rules field, and its
+// configuration in the Data field.
+func getRules(client protocol.DaemonClient) (*protocol.Notification, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return client.GetRules(ctx, &protocol.Notification{})
+}
+
+func findRule(client protocol.DaemonClient, name string) (*protocol.Rule, error) {
+	reply, err := getRules(client)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range reply.Rules {
+		if r.Name == name {
+			return r, nil
+		}
+	}
+	return nil, fmt.Errorf("Rule %s not found", name)
+}
+
+func cmdRules(client protocol.DaemonClient, args []string) error {
+	reply, err := getRules(client)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tENABLED\tACTION\tDURATION\tOPERATOR")
+	for _, r := range reply.Rules {
+		op := ""
+		if r.Operator != nil {
+			op = fmt.Sprintf("%s %s %s", r.Operator.Type, r.Operator.Operand, r.Operator.Data)
+		}
+		fmt.Fprintf(w, "%s\t%v\t%s\t%s\t%s\n", r.Name, r.Enabled, r.Action, r.Duration, op)
+	}
+	return w.Flush()
+}
+
+func cmdAdd(client protocol.DaemonClient, args []string) error {
+	flags := flag.NewFlagSet("add", flag.ContinueOnError)
+	file := flags.String("file", "", "JSON file with the rule, in the format of the rules path of the daemon.")
+	name := flags.String("name", "", "Name of the rule.")
+	action := flags.String("action", string(rule.Allow), "Action of the rule: allow, deny, reject.")
+	duration := flags.String("duration", string(rule.Always), "Duration of the rule: once, always, until restart, 30s, 5m, 1h...")
+	opType := flags.String("type", string(rule.Simple), "Type of the operator: simple, regexp, network.")
+	operand := flags.String("operand", string(rule.OpTrue), "What to check of the connections: process.path, dest.host, dest.port...")
+	data := flags.String("data", "", "Value to check the operand against.")
+	sensitive := flags.Bool("sensitive", false, "Case-sensitive match.")
+	precedence := flags.Bool("precedence", false, "Apply the rule before the rest of the rules.")
+	disabled := flags.Bool("disabled", false, "Add the rule disabled.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var r *rule.Rule
+	if *file != "" {
+		raw, err := ioutil.ReadFile(*file)
+		if err != nil {
+			return err
+		}
+		r = &rule.Rule{}
+		if err := json.Unmarshal(raw, r); err != nil {
+			return fmt.Errorf("Error parsing rule %s: %s", *file, err)
+		}
+	} else {
+		op, err := rule.NewOperator(rule.Type(*opType), rule.Sensitive(*sensitive), rule.Operand(*operand), *data, make([]rule.Operator, 0))
+		if err != nil {
+			return fmt.Errorf("Invalid operator: %s", err)
+		}
+		r = rule.Create(*name, !*disabled, *precedence, rule.Action(*action), rule.Duration(*duration), op)
+	}
+	if r.Name == "" {
+		return fmt.Errorf("The rule needs a name")
+	}
+
+	return notify(client, protocol.Action_CHANGE_RULE, "", []*protocol.Rule{r.Serialize()})
+}
+
+// setEnabled sends the rule back to the daemon with the new state, which
+// replaces it.
+func setEnabled(client protocol.DaemonClient, args []string, action protocol.Action) error {
+	if len(args) != 1 {
+		return fmt.Errorf("Usage: %s NAME", action)
+	}
+	r, err := findRule(client, args[0])
+	if err != nil {
+		return err
+	}
+	return notify(client, action, "", []*protocol.Rule{r})
+}
+
+func cmdEnable(client protocol.DaemonClient, args []string) error {
+	return setEnabled(client, args, protocol.Action_ENABLE_RULE)
+}
+
+func cmdDisable(client protocol.DaemonClient, args []string) error {
+	return setEnabled(client, args, protocol.Action_DISABLE_RULE)
+}
+
+func cmdDelete(client protocol.DaemonClient, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("Usage: delete NAME")
+	}
+	return notify(client, protocol.Action_DELETE_RULE, "", []*protocol.Rule{&protocol.Rule{Name: args[0]}})
+}
+
+// cmdLogLevel changes the LogLevel of the configuration of the daemon, leaving
+// the rest of the options untouched.
+func cmdLogLevel(client protocol.DaemonClient, args []string) error {
+	var level uint32
+	if len(args) != 1 {
+		return fmt.Errorf("Usage: loglevel LEVEL")
+	}
+	if _, err := fmt.Sscanf(args[0], "%d", &level); err != nil || level > 4 {
+		return fmt.Errorf("Invalid log level %s, expected 0 to 4", args[0])
+	}
+
+	reply, err := getRules(client)
+	if err != nil {
+		return err
+	}
+	conf := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(reply.Data), &conf); err != nil {
+		return fmt.Errorf("Error parsing the configuration of the daemon: %s", err)
+	}
+	conf["LogLevel"] = level
+	raw, err := json.MarshalIndent(conf, "", "    ")
+	if err != nil {
+		return err
+	}
+	return notify(client, protocol.Action_CHANGE_CONFIG, string(raw), nil)
+}
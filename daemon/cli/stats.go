@@ -0,0 +1,173 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/evilsocket/opensnitch/daemon/ui/protocol"
+
+	"golang.org/x/net/context"
+)
+
+// printTop prints the entries of a By* map of the statistics, by hits.
+func printTop(w io.Writer, title string, m map[string]uint64) {
+	if len(m) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if m[keys[i]] == m[keys[j]] {
+			return keys[i] < keys[j]
+		}
+		return m[keys[i]] > m[keys[j]]
+	})
+
+	fmt.Fprintf(w, "\n%s\n", title)
+	for _, k := range keys {
+		fmt.Fprintf(w, "  %s\t%d\n", k, m[k])
+	}
+}
+
+// printTraffic prints the data transferred by the connections closed of the
+// processes or rules, by bytes.
+func printTraffic(w io.Writer, title string, m map[string]*protocol.Traffic) {
+	if len(m) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	bytes := func(k string) uint64 {
+		return m[k].BytesIn + m[k].BytesOut
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if bytes(keys[i]) == bytes(keys[j]) {
+			return keys[i] < keys[j]
+		}
+		return bytes(keys[i]) > bytes(keys[j])
+	})
+
+	fmt.Fprintf(w, "\n%s\tconnections\tin\tout\ttime\n", title)
+	for _, k := range keys {
+		t := m[k]
+		fmt.Fprintf(w, "  %s\t%d\t%d\t%d\t%s\n", k, t.Connections, t.BytesIn, t.BytesOut, time.Duration(t.Duration)*time.Second)
+	}
+}
+
+func cmdStats(client protocol.DaemonClient, args []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	st, err := client.GetStatistics(ctx, &protocol.Notification{})
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "Version\t%s\n", st.DaemonVersion)
+	fmt.Fprintf(w, "Uptime\t%s\n", time.Duration(st.Uptime)*time.Second)
+	fmt.Fprintf(w, "Rules\t%d\n", st.Rules)
+	fmt.Fprintf(w, "Connections\t%d\n", st.Connections)
+	fmt.Fprintf(w, "Accepted\t%d\n", st.Accepted)
+	fmt.Fprintf(w, "Dropped\t%d\n", st.Dropped)
+	fmt.Fprintf(w, "Requeued\t%d\n", st.Requeued)
+	fmt.Fprintf(w, "Ignored\t%d\n", st.Ignored)
+	fmt.Fprintf(w, "Rule hits\t%d\n", st.RuleHits)
+	fmt.Fprintf(w, "Rule misses\t%d\n", st.RuleMisses)
+	fmt.Fprintf(w, "DNS responses\t%d\n", st.DnsResponses)
+	printTop(w, "By executable", st.ByExecutable)
+	printTop(w, "By host", st.ByHost)
+	printTop(w, "By address", st.ByAddress)
+	printTop(w, "By port", st.ByPort)
+	printTop(w, "By protocol", st.ByProto)
+	printTop(w, "By user", st.ByUid)
+	printTraffic(w, "Traffic by executable", st.TrafficByExecutable)
+	printTraffic(w, "Traffic by rule", st.TrafficByRule)
+	return w.Flush()
+}
+
+func printEvent(ev *protocol.Event) {
+	con := ev.Connection
+	action, ruleName := "", ""
+	if ev.Rule != nil {
+		action, ruleName = ev.Rule.Action, ev.Rule.Name
+	}
+	host := con.DstHost
+	if host == "" {
+		host = con.DstIp
+	}
+	fmt.Printf("%s %-7s %s (%d) -> %s:%d %s [%s]\n",
+		ev.Time, action, con.ProcessPath, con.ProcessId, host, con.DstPort, con.Protocol, ruleName)
+}
+
+// cmdEvents prints the connections events as they're applied, until the
+// daemon closes the stream or the command is interrupted.
+func cmdEvents(client protocol.DaemonClient, args []string) error {
+	stream, err := client.StreamEvents(context.Background(), &protocol.Notification{})
+	if err != nil {
+		return err
+	}
+	for {
+		ev, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		printEvent(ev)
+	}
+}
+
+// cmdHistory prints the connections saved to the history, newest first.
+func cmdHistory(client protocol.DaemonClient, args []string) error {
+	flags := flag.NewFlagSet("history", flag.ContinueOnError)
+	process := flags.String("process", "", "Path of the process.")
+	host := flags.String("host", "", "Host name or IP of the destination.")
+	ruleName := flags.String("rule", "", "Name of the rule applied.")
+	since := flags.Duration("since", 0, "Only the connections of the last period of time (168h for the last week).")
+	until := flags.Duration("until", 0, "Only the connections older than this period of time.")
+	limit := flags.Uint("limit", 100, "Max number of connections, 0 for no limit.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	q := &protocol.HistoryQuery{
+		Process: *process,
+		Host:    *host,
+		Rule:    *ruleName,
+		Limit:   uint32(*limit),
+	}
+	now := time.Now()
+	if *since > 0 {
+		q.From = now.Add(-*since).UnixNano()
+	}
+	if *until > 0 {
+		q.To = now.Add(-*until).UnixNano()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	stream, err := client.QueryHistory(ctx, q)
+	if err != nil {
+		return err
+	}
+	for {
+		ev, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		printEvent(ev)
+	}
+}
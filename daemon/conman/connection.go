@@ -26,6 +26,9 @@ type Connection struct {
 	DstIP    net.IP
 	DstPort  uint
 	DstHost  string
+	// input and output network interfaces, if known
+	IfaceIn  string
+	IfaceOut string
 	Entry    *netstat.Entry
 	Process  *procmon.Process
 
@@ -69,7 +72,9 @@ func newConnectionImpl(nfp *netfilter.Packet, c *Connection, protoType string) (
 	if c.parseDirection(protoType) == false {
 		return nil, nil
 	}
-	log.Debug("new connection %s => %d:%v -> %v:%d uid: ", c.Protocol, c.SrcPort, c.SrcIP, c.DstIP, c.DstPort, nfp.UID)
+	c.IfaceIn = getIfaceName(nfp.IfaceInIdx)
+	c.IfaceOut = getIfaceName(nfp.IfaceOutIdx)
+	log.Debug("new connection %s => %d:%v -> %v:%d uid: %d", c.Protocol, c.SrcPort, c.SrcIP, c.DstIP, c.DstPort, nfp.UID)
 
 	c.Entry = &netstat.Entry{
 		Proto:   c.Protocol,
@@ -202,6 +207,19 @@ func (c *Connection) parseDirection(protoType string) bool {
 	return ret
 }
 
+// getIfaceName returns the name of a network interface given its index.
+func getIfaceName(idx int) string {
+	if idx <= 0 {
+		return ""
+	}
+	iface, err := net.InterfaceByIndex(idx)
+	if err != nil {
+		log.Debug("Unable to get the name of the interface %d: %s", idx, err)
+		return ""
+	}
+	return iface.Name
+}
+
 func (c *Connection) getDomains(nfp *netfilter.Packet, con *Connection) {
 	domains := dns.GetQuestions(nfp)
 	if len(domains) > 0 {
@@ -233,18 +251,28 @@ func (c *Connection) String() string {
 
 // Serialize returns a connection serialized.
 func (c *Connection) Serialize() *protocol.Connection {
+	tree := make([]*protocol.ProcessParent, len(c.Process.Tree))
+	for i, parent := range c.Process.Tree {
+		tree[i] = &protocol.ProcessParent{
+			Pid:  uint32(parent.ID),
+			Path: parent.Path,
+		}
+	}
+
 	return &protocol.Connection{
-		Protocol:    c.Protocol,
-		SrcIp:       c.SrcIP.String(),
-		SrcPort:     uint32(c.SrcPort),
-		DstIp:       c.DstIP.String(),
-		DstHost:     c.DstHost,
-		DstPort:     uint32(c.DstPort),
-		UserId:      uint32(c.Entry.UserId),
-		ProcessId:   uint32(c.Process.ID),
-		ProcessPath: c.Process.Path,
-		ProcessArgs: c.Process.Args,
-		ProcessEnv:  c.Process.Env,
-		ProcessCwd:  c.Process.CWD,
+		Protocol:         c.Protocol,
+		SrcIp:            c.SrcIP.String(),
+		SrcPort:          uint32(c.SrcPort),
+		DstIp:            c.DstIP.String(),
+		DstHost:          c.DstHost,
+		DstPort:          uint32(c.DstPort),
+		UserId:           uint32(c.Entry.UserId),
+		ProcessId:        uint32(c.Process.ID),
+		ProcessPath:      c.Process.Path,
+		ProcessArgs:      c.Process.Args,
+		ProcessEnv:       c.Process.Env,
+		ProcessCwd:       c.Process.CWD,
+		ProcessChecksums: c.Process.Checksums,
+		ProcessTree:      tree,
 	}
 }
@@ -0,0 +1,171 @@
+// Package conntrack listens for the conntrack events of the kernel, to account
+// the bytes transferred and the duration of the connections allowed by the
+// rules, once they're closed.
+//
+// The counters of the connections are only reported if the accounting of
+// conntrack is enabled (net.netfilter.nf_conntrack_acct = 1), which the
+// Tracker does when started.
+package conntrack
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+)
+
+// ctnetlink messages, from linux/netfilter/nfnetlink_conntrack.h
+const (
+	ctMsgNew    = 0
+	ctMsgDelete = 2
+
+	nlaTypeMask = ^uint16(unix.NLA_F_NESTED | unix.NLA_F_NET_BYTEORDER)
+)
+
+// Flow is a connection tracked by conntrack, in the direction of the
+// first packet (the original one).
+type Flow struct {
+	ID       uint32
+	Protocol uint8
+	SrcIP    net.IP
+	SrcPort  uint16
+	DstIP    net.IP
+	DstPort  uint16
+	// sent by the source, and by the destination (the reply)
+	BytesOut   uint64
+	BytesIn    uint64
+	PacketsOut uint64
+	PacketsIn  uint64
+	// only set if the timestamps of conntrack are enabled
+	Start time.Time
+	Stop  time.Time
+}
+
+// Key identifies the flow by its protocol and addresses.
+func (f *Flow) Key() string {
+	return flowKey(f.Protocol, f.SrcIP, uint(f.SrcPort), f.DstIP, uint(f.DstPort))
+}
+
+func flowKey(proto uint8, srcIP net.IP, srcPort uint, dstIP net.IP, dstPort uint) string {
+	return fmt.Sprintf("%d %s:%d %s:%d", proto, srcIP, srcPort, dstIP, dstPort)
+}
+
+func parseAttrs(b []byte) (map[uint16][]byte, error) {
+	attrs, err := nl.ParseRouteAttr(b)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[uint16][]byte, len(attrs))
+	for _, a := range attrs {
+		m[a.Attr.Type&nlaTypeMask] = a.Value
+	}
+	return m, nil
+}
+
+func parseTuple(f *Flow, b []byte) error {
+	tuple, err := parseAttrs(b)
+	if err != nil {
+		return err
+	}
+	ip, err := parseAttrs(tuple[nl.CTA_TUPLE_IP])
+	if err != nil {
+		return err
+	}
+	if v, found := ip[nl.CTA_IP_V4_SRC]; found {
+		f.SrcIP, f.DstIP = net.IP(v), net.IP(ip[nl.CTA_IP_V4_DST])
+	} else {
+		f.SrcIP, f.DstIP = net.IP(ip[nl.CTA_IP_V6_SRC]), net.IP(ip[nl.CTA_IP_V6_DST])
+	}
+	if f.SrcIP == nil || f.DstIP == nil {
+		return fmt.Errorf("Tuple without addresses")
+	}
+
+	proto, err := parseAttrs(tuple[nl.CTA_TUPLE_PROTO])
+	if err != nil {
+		return err
+	}
+	if v := proto[nl.CTA_PROTO_NUM]; len(v) == 1 {
+		f.Protocol = v[0]
+	}
+	if v := proto[nl.CTA_PROTO_SRC_PORT]; len(v) == 2 {
+		f.SrcPort = binary.BigEndian.Uint16(v)
+	}
+	if v := proto[nl.CTA_PROTO_DST_PORT]; len(v) == 2 {
+		f.DstPort = binary.BigEndian.Uint16(v)
+	}
+	return nil
+}
+
+func parseCounters(b []byte) (packets, bytes uint64, err error) {
+	counters, err := parseAttrs(b)
+	if err != nil {
+		return 0, 0, err
+	}
+	if v := counters[nl.CTA_COUNTERS_PACKETS]; len(v) == 8 {
+		packets = binary.BigEndian.Uint64(v)
+	}
+	if v := counters[nl.CTA_COUNTERS_BYTES]; len(v) == 8 {
+		bytes = binary.BigEndian.Uint64(v)
+	}
+	return packets, bytes, nil
+}
+
+// parseFlow parses the attributes of a conntrack event, after the nfgenmsg header.
+func parseFlow(b []byte) (*Flow, error) {
+	attrs, err := parseAttrs(b)
+	if err != nil {
+		return nil, err
+	}
+	orig, found := attrs[nl.CTA_TUPLE_ORIG]
+	if !found {
+		return nil, fmt.Errorf("Conntrack event without the original tuple")
+	}
+
+	f := &Flow{}
+	if err := parseTuple(f, orig); err != nil {
+		return nil, err
+	}
+	if v := attrs[nl.CTA_ID]; len(v) == 4 {
+		f.ID = binary.BigEndian.Uint32(v)
+	}
+	if v, found := attrs[nl.CTA_COUNTERS_ORIG]; found {
+		if f.PacketsOut, f.BytesOut, err = parseCounters(v); err != nil {
+			return nil, err
+		}
+	}
+	if v, found := attrs[nl.CTA_COUNTERS_REPLY]; found {
+		if f.PacketsIn, f.BytesIn, err = parseCounters(v); err != nil {
+			return nil, err
+		}
+	}
+	if v, found := attrs[nl.CTA_TIMESTAMP]; found {
+		ts, err := parseAttrs(v)
+		if err != nil {
+			return nil, err
+		}
+		if v := ts[nl.CTA_TIMESTAMP_START]; len(v) == 8 {
+			f.Start = time.Unix(0, int64(binary.BigEndian.Uint64(v)))
+		}
+		if v := ts[nl.CTA_TIMESTAMP_STOP]; len(v) == 8 {
+			f.Stop = time.Unix(0, int64(binary.BigEndian.Uint64(v)))
+		}
+	}
+	return f, nil
+}
+
+// parseMessage returns the type of a conntrack event (ctMsgNew or
+// ctMsgDelete), and its flow.
+func parseMessage(m syscall.NetlinkMessage) (int, *Flow, error) {
+	if int(m.Header.Type>>8) != unix.NFNL_SUBSYS_CTNETLINK {
+		return 0, nil, fmt.Errorf("Not a conntrack message: %d", m.Header.Type)
+	}
+	if len(m.Data) < nl.SizeofNfgenmsg {
+		return 0, nil, fmt.Errorf("Conntrack message too short: %d", len(m.Data))
+	}
+	f, err := parseFlow(m.Data[nl.SizeofNfgenmsg:])
+	return int(m.Header.Type & 0xff), f, err
+}
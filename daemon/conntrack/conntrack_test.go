@@ -0,0 +1,206 @@
+package conntrack
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/evilsocket/opensnitch/daemon/conman"
+	"github.com/evilsocket/opensnitch/daemon/procmon"
+	"github.com/evilsocket/opensnitch/daemon/rule"
+	"github.com/evilsocket/opensnitch/daemon/statistics"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+)
+
+func be64(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
+
+func be16(v uint16) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, v)
+	return b
+}
+
+// newEvent builds a conntrack event of a TCP connection from
+// 10.0.0.1:40000 to 1.1.1.1:443, as sent by the kernel.
+func newEvent(msgType int, bytesOut, bytesIn uint64, start, stop time.Time) syscall.NetlinkMessage {
+	tuple := nl.NewRtAttr(nl.CTA_TUPLE_ORIG|nl.NLA_F_NESTED, nil)
+	ip := tuple.AddRtAttr(nl.CTA_TUPLE_IP|nl.NLA_F_NESTED, nil)
+	ip.AddRtAttr(nl.CTA_IP_V4_SRC, net.ParseIP("10.0.0.1").To4())
+	ip.AddRtAttr(nl.CTA_IP_V4_DST, net.ParseIP("1.1.1.1").To4())
+	proto := tuple.AddRtAttr(nl.CTA_TUPLE_PROTO|nl.NLA_F_NESTED, nil)
+	proto.AddRtAttr(nl.CTA_PROTO_NUM, []byte{unix.IPPROTO_TCP})
+	proto.AddRtAttr(nl.CTA_PROTO_SRC_PORT, be16(40000))
+	proto.AddRtAttr(nl.CTA_PROTO_DST_PORT, be16(443))
+
+	counters := func(attrType int, packets, bytes uint64) *nl.RtAttr {
+		c := nl.NewRtAttr(attrType|nl.NLA_F_NESTED, nil)
+		c.AddRtAttr(nl.CTA_COUNTERS_PACKETS, be64(packets))
+		c.AddRtAttr(nl.CTA_COUNTERS_BYTES, be64(bytes))
+		return c
+	}
+
+	data := (&nl.Nfgenmsg{NfgenFamily: unix.AF_INET, Version: nl.NFNETLINK_V0}).Serialize()
+	data = append(data, tuple.Serialize()...)
+	data = append(data, nl.NewRtAttr(nl.CTA_ID, []byte{0, 0, 0, 42}).Serialize()...)
+	data = append(data, counters(nl.CTA_COUNTERS_ORIG, 3, bytesOut).Serialize()...)
+	data = append(data, counters(nl.CTA_COUNTERS_REPLY, 5, bytesIn).Serialize()...)
+	if !start.IsZero() {
+		ts := nl.NewRtAttr(nl.CTA_TIMESTAMP|nl.NLA_F_NESTED, nil)
+		ts.AddRtAttr(nl.CTA_TIMESTAMP_START, be64(uint64(start.UnixNano())))
+		ts.AddRtAttr(nl.CTA_TIMESTAMP_STOP, be64(uint64(stop.UnixNano())))
+		data = append(data, ts.Serialize()...)
+	}
+
+	m := syscall.NetlinkMessage{Data: data}
+	m.Header.Type = uint16(unix.NFNL_SUBSYS_CTNETLINK<<8 | msgType)
+	return m
+}
+
+func newConnection() *conman.Connection {
+	return &conman.Connection{
+		Protocol: "tcp",
+		SrcIP:    net.ParseIP("10.0.0.1"),
+		SrcPort:  40000,
+		DstIP:    net.ParseIP("1.1.1.1"),
+		DstPort:  443,
+		Process:  procmon.NewProcess(1234, "/usr/bin/curl"),
+	}
+}
+
+func TestParseMessage(t *testing.T) {
+	start := time.Now()
+	msgType, f, err := parseMessage(newEvent(ctMsgDelete, 100, 2000, start, start.Add(3*time.Second)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msgType != ctMsgDelete {
+		t.Errorf("Unexpected message type: %d", msgType)
+	}
+	if f.ID != 42 || f.Protocol != unix.IPPROTO_TCP || f.SrcPort != 40000 || f.DstPort != 443 {
+		t.Errorf("Unexpected flow: %+v", f)
+	}
+	if !f.SrcIP.Equal(net.ParseIP("10.0.0.1")) || !f.DstIP.Equal(net.ParseIP("1.1.1.1")) {
+		t.Errorf("Unexpected addresses: %s -> %s", f.SrcIP, f.DstIP)
+	}
+	if f.BytesOut != 100 || f.BytesIn != 2000 || f.PacketsOut != 3 || f.PacketsIn != 5 {
+		t.Errorf("Unexpected counters: %+v", f)
+	}
+	if f.Stop.Sub(f.Start) != 3*time.Second {
+		t.Errorf("Unexpected timestamps: %s - %s", f.Start, f.Stop)
+	}
+
+	m := newEvent(ctMsgNew, 0, 0, time.Time{}, time.Time{})
+	m.Header.Type = unix.NFNL_SUBSYS_NFTABLES << 8
+	if _, _, err := parseMessage(m); err == nil {
+		t.Error("Message of another subsystem parsed")
+	}
+}
+
+func TestTracker(t *testing.T) {
+	rules, _ := rule.NewLoader(false)
+	stats := statistics.New(rules)
+	tracker := newTracker(stats)
+	allow := rule.Create("allow-curl", true, false, rule.Allow, rule.Always, &rule.Operator{})
+	deny := rule.Create("deny-curl", true, false, rule.Deny, rule.Always, &rule.Operator{})
+	start := time.Now()
+
+	// the verdict before the conntrack entry
+	tracker.OnConnectionEvent(newConnection(), allow, false)
+	_, f, _ := parseMessage(newEvent(ctMsgNew, 0, 0, time.Time{}, time.Time{}))
+	tracker.onNew(f)
+	_, f, _ = parseMessage(newEvent(ctMsgDelete, 100, 2000, start, start.Add(3*time.Second)))
+	tracker.onDestroy(f)
+
+	// the conntrack entry before the verdict
+	_, f, _ = parseMessage(newEvent(ctMsgNew, 0, 0, time.Time{}, time.Time{}))
+	tracker.onNew(f)
+	tracker.OnConnectionEvent(newConnection(), allow, false)
+	_, f, _ = parseMessage(newEvent(ctMsgDelete, 50, 1000, start, start.Add(time.Second)))
+	tracker.onDestroy(f)
+
+	// connections denied are not accounted
+	tracker.OnConnectionEvent(newConnection(), deny, false)
+	_, f, _ = parseMessage(newEvent(ctMsgDelete, 10, 10, start, start.Add(time.Second)))
+	tracker.onDestroy(f)
+
+	traffic := stats.TrafficByExecutable.Traffic()["/usr/bin/curl"]
+	if traffic.Connections != 2 || traffic.BytesOut != 150 || traffic.BytesIn != 3000 || traffic.Duration != 4*time.Second {
+		t.Errorf("Unexpected traffic of the process: %+v", traffic)
+	}
+	if traffic := stats.TrafficByRule.Traffic()["allow-curl"]; traffic.Connections != 2 {
+		t.Errorf("Unexpected traffic of the rule: %+v", traffic)
+	}
+	if _, found := stats.TrafficByRule.Traffic()["deny-curl"]; found {
+		t.Error("Connection denied accounted")
+	}
+	if len(tracker.conns) != 0 {
+		t.Errorf("Connections left: %d", len(tracker.conns))
+	}
+}
+
+func TestTrackerExpire(t *testing.T) {
+	rules, _ := rule.NewLoader(false)
+	tracker := newTracker(statistics.New(rules))
+	allow := rule.Create("allow-curl", true, false, rule.Allow, rule.Always, &rule.Operator{})
+
+	// allowed, but never confirmed by conntrack
+	tracker.OnConnectionEvent(newConnection(), allow, false)
+	tracker.expire(time.Now())
+	if len(tracker.conns) != 1 {
+		t.Fatalf("Connection expired too soon")
+	}
+	tracker.expire(time.Now().Add(2 * pendingTimeout))
+	if len(tracker.conns) != 0 {
+		t.Errorf("Connection not expired")
+	}
+
+	// confirmed and allowed, kept until it's destroyed
+	tracker.OnConnectionEvent(newConnection(), allow, false)
+	_, f, _ := parseMessage(newEvent(ctMsgNew, 0, 0, time.Time{}, time.Time{}))
+	tracker.onNew(f)
+	tracker.expire(time.Now().Add(2 * pendingTimeout))
+	if len(tracker.conns) != 1 {
+		t.Errorf("Connection being tracked expired")
+	}
+}
+
+func TestAccounting(t *testing.T) {
+	f, err := ioutil.TempFile("", "nf_conntrack_acct")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.Close()
+	defer func(orig string) { acctSysctl = orig }(acctSysctl)
+	acctSysctl = f.Name()
+
+	read := func() string {
+		raw, err := ioutil.ReadFile(acctSysctl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(raw)
+	}
+
+	ioutil.WriteFile(acctSysctl, []byte("0\n"), 0644)
+	if !enableAccounting() || read() != "1" {
+		t.Fatal("Accounting not enabled:", read())
+	}
+	// enabled by someone else, so it's left enabled
+	if enableAccounting() {
+		t.Error("Accounting already enabled reported as changed")
+	}
+	disableAccounting()
+	if read() != "0" {
+		t.Error("Accounting not restored:", read())
+	}
+}
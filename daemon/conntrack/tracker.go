@@ -0,0 +1,257 @@
+package conntrack
+
+import (
+	"io/ioutil"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/evilsocket/opensnitch/daemon/conman"
+	"github.com/evilsocket/opensnitch/daemon/log"
+	"github.com/evilsocket/opensnitch/daemon/rule"
+	"github.com/evilsocket/opensnitch/daemon/statistics"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// max number of connections tracked, connections beyond it are not
+	// accounted.
+	maxConns = 65536
+	// how long a connection allowed waits for its conntrack entry, or a
+	// conntrack entry for its verdict, before being forgotten.
+	pendingTimeout = time.Minute
+	// how often the connections pending are expired.
+	expireInterval = 10 * time.Second
+	// size of the receive buffer of the socket, so bursts of events are not lost.
+	recvBufferSize = 4 * 1024 * 1024
+)
+
+var acctSysctl = "/proc/sys/net/netfilter/nf_conntrack_acct"
+
+var protocols = map[string]uint8{
+	"tcp":     unix.IPPROTO_TCP,
+	"udp":     unix.IPPROTO_UDP,
+	"udplite": unix.IPPROTO_UDPLITE,
+}
+
+// tracked is a connection allowed, or a conntrack entry not yet matched
+// with its verdict (the entry is usually created before the verdict is
+// reported, as it's confirmed as soon as the packet is accepted).
+type tracked struct {
+	process string
+	rule    *rule.Rule
+	allowed time.Time
+	started time.Time
+}
+
+// Tracker matches the conntrack entries with the connections allowed, and
+// once they're destroyed, accounts the bytes they transferred and how long
+// they lasted to the process and the rule that allowed them.
+type Tracker struct {
+	sync.Mutex
+	stats *statistics.Statistics
+	sock  *nl.NetlinkSocket
+	conns map[string]*tracked
+	quit  chan bool
+	done  chan bool
+	// the accounting was enabled by the tracker, so it's disabled on Close
+	restoreAccounting bool
+}
+
+// NewTracker subscribes to the conntrack events, accounting the
+// connections to stats.
+func NewTracker(stats *statistics.Statistics) (*Tracker, error) {
+	enabled := enableAccounting()
+
+	sock, err := nl.Subscribe(unix.NETLINK_NETFILTER, unix.NFNLGRP_CONNTRACK_NEW, unix.NFNLGRP_CONNTRACK_DESTROY)
+	if err != nil {
+		if enabled {
+			disableAccounting()
+		}
+		return nil, err
+	}
+	// wake up periodically, to check if the tracker has been closed
+	if err := sock.SetReceiveTimeout(&unix.Timeval{Sec: 1}); err != nil {
+		sock.Close()
+		if enabled {
+			disableAccounting()
+		}
+		return nil, err
+	}
+	if err := unix.SetsockoptInt(sock.GetFd(), unix.SOL_SOCKET, unix.SO_RCVBUFFORCE, recvBufferSize); err != nil {
+		log.Debug("Unable to set the conntrack socket buffer size: %s", err)
+	}
+
+	t := newTracker(stats)
+	t.sock = sock
+	t.restoreAccounting = enabled
+	go t.listen()
+	return t, nil
+}
+
+func newTracker(stats *statistics.Statistics) *Tracker {
+	return &Tracker{
+		stats: stats,
+		conns: make(map[string]*tracked),
+		quit:  make(chan bool),
+		done:  make(chan bool),
+	}
+}
+
+// enableAccounting enables the counters of the conntrack entries, without
+// them the connections are accounted without bytes. It returns true if they
+// were disabled, to disable them again once the tracker is closed.
+func enableAccounting() bool {
+	if raw, err := ioutil.ReadFile(acctSysctl); err == nil && strings.TrimSpace(string(raw)) == "1" {
+		return false
+	}
+	if err := ioutil.WriteFile(acctSysctl, []byte("1"), 0644); err != nil {
+		log.Warning("Unable to enable the conntrack accounting, the bytes of the connections won't be counted: %s", err)
+		return false
+	}
+	log.Info("Conntrack accounting enabled, until the daemon exits")
+	return true
+}
+
+// disableAccounting restores the counters of the conntrack entries disabled.
+func disableAccounting() {
+	if err := ioutil.WriteFile(acctSysctl, []byte("0"), 0644); err != nil {
+		log.Warning("Unable to disable the conntrack accounting again: %s", err)
+	}
+}
+
+// Close stops listening for the conntrack events, and disables the
+// accounting if it was enabled by the tracker.
+func (t *Tracker) Close() {
+	close(t.quit)
+	<-t.done
+	t.sock.Close()
+	if t.restoreAccounting {
+		disableAccounting()
+	}
+}
+
+// OnConnectionEvent starts tracking a connection allowed by a rule.
+func (t *Tracker) OnConnectionEvent(con *conman.Connection, match *rule.Rule, wasMissed bool) {
+	if wasMissed || match.Enabled == false || (match.Action != rule.Allow && match.Action != rule.Mark) {
+		return
+	}
+	proto, found := protocols[strings.TrimSuffix(con.Protocol, "6")]
+	if !found {
+		return
+	}
+	key := flowKey(proto, con.SrcIP, con.SrcPort, con.DstIP, con.DstPort)
+
+	t.Lock()
+	defer t.Unlock()
+	if c, found := t.conns[key]; found {
+		c.process = con.Process.Path
+		c.rule = match
+		c.allowed = time.Now()
+		return
+	}
+	if len(t.conns) >= maxConns {
+		return
+	}
+	t.conns[key] = &tracked{
+		process: con.Process.Path,
+		rule:    match,
+		allowed: time.Now(),
+	}
+}
+
+func (t *Tracker) listen() {
+	defer close(t.done)
+	lastExpire := time.Now()
+
+	for {
+		select {
+		case <-t.quit:
+			return
+		default:
+		}
+
+		msgs, _, err := t.sock.Receive()
+		if err == syscall.ENOBUFS {
+			log.Warning("Conntrack events lost, too many connections")
+		} else if err != nil && err != syscall.EAGAIN && err != syscall.EINTR {
+			log.Error("Error receiving conntrack events: %s", err)
+			return
+		}
+		for _, m := range msgs {
+			msgType, f, err := parseMessage(m)
+			if err != nil {
+				log.Debug("Invalid conntrack event: %s", err)
+				continue
+			}
+			switch msgType {
+			case ctMsgNew:
+				t.onNew(f)
+			case ctMsgDelete:
+				t.onDestroy(f)
+			}
+		}
+
+		if now := time.Now(); now.Sub(lastExpire) >= expireInterval {
+			t.expire(now)
+			lastExpire = now
+		}
+	}
+}
+
+func (t *Tracker) onNew(f *Flow) {
+	key := f.Key()
+	t.Lock()
+	defer t.Unlock()
+	if c, found := t.conns[key]; found {
+		c.started = time.Now()
+		return
+	}
+	if len(t.conns) >= maxConns {
+		return
+	}
+	t.conns[key] = &tracked{started: time.Now()}
+}
+
+func (t *Tracker) onDestroy(f *Flow) {
+	key := f.Key()
+	t.Lock()
+	c, found := t.conns[key]
+	if found {
+		delete(t.conns, key)
+	}
+	now := time.Now()
+	t.Unlock()
+	if !found || c.process == "" {
+		return
+	}
+
+	var duration time.Duration
+	if !f.Start.IsZero() && !f.Stop.IsZero() {
+		duration = f.Stop.Sub(f.Start)
+	} else if !c.started.IsZero() {
+		duration = now.Sub(c.started)
+	} else {
+		duration = now.Sub(c.allowed)
+	}
+	t.stats.OnConnectionClosed(c.process, c.rule.Name, f.BytesIn, f.BytesOut, duration)
+	// counted by the bytes quotas of the rules
+	c.rule.AddTraffic(c.process, f.BytesIn+f.BytesOut)
+}
+
+// expire forgets the connections allowed without a conntrack entry (dropped
+// later by the firewall), and the entries of the connections not allowed by
+// us (the incoming ones, or the ones not queued).
+func (t *Tracker) expire(now time.Time) {
+	t.Lock()
+	defer t.Unlock()
+	for key, c := range t.conns {
+		if c.process == "" && now.Sub(c.started) > pendingTimeout {
+			delete(t.conns, key)
+		} else if c.started.IsZero() && now.Sub(c.allowed) > pendingTimeout {
+			delete(t.conns, key)
+		}
+	}
+}
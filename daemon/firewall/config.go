@@ -10,13 +10,13 @@ import (
 	"github.com/fsnotify/fsnotify"
 )
 
-var (
-	configFile    = "/etc/opensnitchd/system-fw.json"
-	configWatcher *fsnotify.Watcher
-	fwConfig      config
-)
+var configFile = "/etc/opensnitchd/system-fw.json"
+
+// table of the system rules without Table.
+const defaultSystemTable = "filter"
 
-type fwRule struct {
+// FwRule holds the fields of a system rule.
+type FwRule struct {
 	Description      string
 	Table            string
 	Chain            string
@@ -26,7 +26,7 @@ type fwRule struct {
 }
 
 type rulesList struct {
-	Rule *fwRule
+	Rule *FwRule
 }
 
 type config struct {
@@ -34,78 +34,81 @@ type config struct {
 	SystemRules []*rulesList
 }
 
-func loadDiskConfiguration(reload bool) {
-	raw, err := ioutil.ReadFile(configFile)
+func (m *Manager) loadDiskConfiguration(reload bool) {
+	raw, err := ioutil.ReadFile(m.configFile)
 	if err != nil {
-		fmt.Errorf("Error loading disk firewall configuration %s: %s", configFile, err)
+		log.Error("Error loading disk firewall configuration %s: %s", m.configFile, err)
 	}
 
-	if ok := loadConfiguration(raw); ok {
-		configWatcher.Remove(configFile)
-		if err := configWatcher.Add(configFile); err != nil {
+	if ok := m.loadConfiguration(raw); ok && m.configWatcher != nil {
+		m.configWatcher.Remove(m.configFile)
+		if err := m.configWatcher.Add(m.configFile); err != nil {
 			log.Error("Could not watch firewall configuration: %s", err)
 			return
 		}
 	}
 
-	if reload {
+	if reload || m.configWatcher == nil {
 		return
 	}
 
-	go monitorConfigWorker()
+	go m.monitorConfigWorker()
 }
 
 // loadConfigutation reads the system firewall rules from disk.
 // Then the rules are added based on the configuration defined.
-func loadConfiguration(rawConfig []byte) bool {
-	fwConfig.Lock()
-	defer fwConfig.Unlock()
+func (m *Manager) loadConfiguration(rawConfig []byte) bool {
+	m.config.Lock()
+	defer m.config.Unlock()
 
-	// delete old system rules, that may be different from the new ones
-	DeleteSystemRules(false, log.GetLogLevel() == log.DEBUG)
-	if err := json.Unmarshal(rawConfig, &fwConfig); err != nil {
-		log.Error("Error parsing firewall configuration %s: %s", configFile, err)
+	var newConfig config
+	if err := json.Unmarshal(rawConfig, &newConfig); err != nil {
+		log.Error("Error parsing firewall configuration %s: %s", m.configFile, err)
 		return false
 	}
-
-	DeleteSystemRules(true, log.GetLogLevel() == log.DEBUG)
-	for _, r := range fwConfig.SystemRules {
-		if r.Rule.Chain == "" {
-			continue
+	// the backends expect the table of every rule, the chains are per table
+	for _, r := range newConfig.SystemRules {
+		if r.Rule != nil && r.Rule.Table == "" {
+			r.Rule.Table = defaultSystemTable
 		}
-		CreateSystemRule(r.Rule, true)
-		AddSystemRule(ADD, r.Rule, true)
 	}
 
+	// delete old system rules, that may be different from the new ones
+	m.DeleteSystemRules(false, log.GetLogLevel() == log.DEBUG)
+	m.config.SystemRules = newConfig.SystemRules
+
+	m.DeleteSystemRules(true, log.GetLogLevel() == log.DEBUG)
+	m.AddSystemRules()
+
 	return true
 }
 
-func saveConfiguration(rawConfig string) error {
+func (m *Manager) saveConfiguration(rawConfig string) error {
 	conf, err := json.Marshal([]byte(rawConfig))
 	if err != nil {
-		log.Error("saving json firewall configuration: ", err, conf)
+		log.Error("saving json firewall configuration: %s, %s", err, conf)
 		return err
 	}
 
-	if loadConfiguration([]byte(rawConfig)) != true {
+	if m.loadConfiguration([]byte(rawConfig)) != true {
 		return fmt.Errorf("Error parsing firewall configuration %s: %s", rawConfig, err)
 	}
 
-	if err = ioutil.WriteFile(configFile, []byte(rawConfig), 0644); err != nil {
-		log.Error("writing firewall configuration to disk: ", err)
+	if err = ioutil.WriteFile(m.configFile, []byte(rawConfig), 0644); err != nil {
+		log.Error("writing firewall configuration to disk: %s", err)
 		return err
 	}
 	return nil
 }
 
-func monitorConfigWorker() {
+func (m *Manager) monitorConfigWorker() {
 	for {
 		select {
-		case <-rulesCheckerChan:
+		case <-m.rulesCheckerChan:
 			return
-		case event := <-configWatcher.Events:
+		case event := <-m.configWatcher.Events:
 			if (event.Op&fsnotify.Write == fsnotify.Write) || (event.Op&fsnotify.Remove == fsnotify.Remove) {
-				loadDiskConfiguration(true)
+				m.loadDiskConfiguration(true)
 			}
 		}
 	}
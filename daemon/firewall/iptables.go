@@ -0,0 +1,281 @@
+package firewall
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/evilsocket/opensnitch/daemon/core"
+	"github.com/evilsocket/opensnitch/daemon/log"
+)
+
+// Action is the modifier we apply to a rule.
+type Action string
+
+// Actions we apply to the firewall.
+const (
+	ADD      = Action("-A")
+	INSERT   = Action("-I")
+	DELETE   = Action("-D")
+	FLUSH    = Action("-F")
+	NEWCHAIN = Action("-N")
+	DELCHAIN = Action("-X")
+
+	systemRulePrefix = "opensnitch-filter"
+)
+
+var (
+	regexRulesQuery, _       = regexp.Compile(`NFQUEUE.*ctstate NEW,RELATED.*NFQUEUE num.*bypass`)
+	regexDropQuery, _        = regexp.Compile(`DROP.*mark match 0x18ba5`)
+	regexRejectQuery, _      = regexp.Compile(`REJECT.*mark match 0x18ba6`)
+	regexSystemRulesQuery, _ = regexp.Compile(systemRulePrefix + ".*")
+)
+
+// iptables manages the firewall rules by executing the iptables and ip6tables
+// binaries.
+type iptables struct {
+	// make sure we don't mess with multiple rules
+	// at the same time
+	sync.Mutex
+
+	systemChains map[string]*FwRule
+}
+
+func newIptables() *iptables {
+	return &iptables{
+		systemChains: make(map[string]*FwRule),
+	}
+}
+
+// Name returns the name of this firewall implementation.
+func (ipt *iptables) Name() string {
+	return IPTABLES
+}
+
+// IsAvailable checks if the iptables binary is installed.
+func (ipt *iptables) IsAvailable() bool {
+	_, err := exec.LookPath("iptables")
+	return err == nil
+}
+
+// RunRule inserts or deletes a firewall rule.
+func (ipt *iptables) RunRule(action Action, enable bool, logError bool, rule []string) (err4, err6 error) {
+	if enable == false {
+		action = "-D"
+	}
+
+	rule = append([]string{string(action)}, rule...)
+
+	ipt.Lock()
+	defer ipt.Unlock()
+
+	if _, err4 = core.Exec("iptables", rule); err4 != nil {
+		if logError {
+			log.Error("Error while running firewall rule, ipv4 err: %s", err4)
+			log.Error("rule: %s", rule)
+		}
+	}
+
+	if core.IPv6Enabled {
+		if _, err6 = core.Exec("ip6tables", rule); err6 != nil {
+			if logError {
+				log.Error("Error while running firewall rule, ipv6 err: %s", err6)
+				log.Error("rule: %s", rule)
+			}
+		}
+	}
+
+	return
+}
+
+// QueueDNSResponses redirects DNS responses to us, in order to keep a cache
+// of resolved domains.
+// INPUT --protocol udp --sport 53 -j NFQUEUE --queue-num 0 --queue-bypass
+func (ipt *iptables) QueueDNSResponses(enable bool, logError bool, qNum int) (err4, err6 error) {
+	return ipt.RunRule(INSERT, enable, logError, []string{
+		"INPUT",
+		"--protocol", "udp",
+		"--sport", "53",
+		"-j", "NFQUEUE",
+		"--queue-num", fmt.Sprintf("%d", qNum),
+		"--queue-bypass",
+	})
+}
+
+// QueueConnections inserts the firewall rule which redirects connections to us.
+// They are queued until the user denies/accept them, or reaches a timeout.
+// OUTPUT -t mangle -m conntrack --ctstate NEW,RELATED -j NFQUEUE --queue-num 0 --queue-bypass
+func (ipt *iptables) QueueConnections(enable bool, logError bool, qNum int) (err4, err6 error) {
+	return ipt.RunRule(INSERT, enable, logError, []string{
+		"OUTPUT",
+		"-t", "mangle",
+		"-m", "conntrack",
+		"--ctstate", "NEW,RELATED",
+		"-j", "NFQUEUE",
+		"--queue-num", fmt.Sprintf("%d", qNum),
+		"--queue-bypass",
+	})
+}
+
+// DropMarked rejects packets marked by OpenSnitch.
+// OUTPUT -m mark --mark 101285 -j DROP
+func (ipt *iptables) DropMarked(enable bool, logError bool) (err4, err6 error) {
+	return ipt.RunRule(ADD, enable, logError, []string{
+		"OUTPUT",
+		"-m", "mark",
+		"--mark", fmt.Sprintf("%d", DropMark),
+		"-j", "DROP",
+	})
+}
+
+// RejectMarked rejects packets marked by OpenSnitch, with a TCP RST for TCP
+// connections and an ICMP port unreachable for the rest.
+// OUTPUT -m mark --mark 101286 -p tcp -j REJECT --reject-with tcp-reset
+// OUTPUT -m mark --mark 101286 -j REJECT
+func (ipt *iptables) RejectMarked(enable bool, logError bool) (err4, err6 error) {
+	if err4, err6 = ipt.RunRule(ADD, enable, logError, []string{
+		"OUTPUT",
+		"-m", "mark",
+		"--mark", fmt.Sprintf("%d", RejectMark),
+		"-p", "tcp",
+		"-j", "REJECT",
+		"--reject-with", "tcp-reset",
+	}); err4 != nil || err6 != nil {
+		return
+	}
+	return ipt.RunRule(ADD, enable, logError, []string{
+		"OUTPUT",
+		"-m", "mark",
+		"--mark", fmt.Sprintf("%d", RejectMark),
+		"-j", "REJECT",
+	})
+}
+
+// InsertRules adds the rules needed to intercept connections.
+func (ipt *iptables) InsertRules(qNum int) error {
+	if err4, err6 := ipt.QueueDNSResponses(true, true, qNum); err4 != nil || err6 != nil {
+		log.Error("Error while running DNS firewall rule: %s, %s", err4, err6)
+	} else if err4, err6 = ipt.QueueConnections(true, true, qNum); err4 != nil || err6 != nil {
+		return fmt.Errorf("Error while running conntrack firewall rule: %v, %v", err4, err6)
+	} else if err4, err6 = ipt.DropMarked(true, true); err4 != nil || err6 != nil {
+		return fmt.Errorf("Error while running drop firewall rule: %v, %v", err4, err6)
+	} else if err4, err6 = ipt.RejectMarked(true, true); err4 != nil || err6 != nil {
+		return fmt.Errorf("Error while running reject firewall rule: %v, %v", err4, err6)
+	}
+	return nil
+}
+
+// CleanRules deletes the interception rules.
+func (ipt *iptables) CleanRules(qNum int, logErrors bool) {
+	ipt.QueueDNSResponses(false, logErrors, qNum)
+	ipt.QueueConnections(false, logErrors, qNum)
+	ipt.DropMarked(false, logErrors)
+	ipt.RejectMarked(false, logErrors)
+}
+
+// CreateSystemRule create the custom firewall chains and adds them to system.
+func (ipt *iptables) CreateSystemRule(rule *FwRule, logErrors bool) {
+	chainName := systemRulePrefix + "-" + rule.Chain
+	if _, ok := ipt.systemChains[rule.Table+"-"+chainName]; ok {
+		return
+	}
+	ipt.RunRule(NEWCHAIN, true, logErrors, []string{chainName, "-t", rule.Table})
+
+	// Insert the rule at the top of the chain
+	if err4, err6 := ipt.RunRule(INSERT, true, logErrors, []string{rule.Chain, "-t", rule.Table, "-j", chainName}); err4 == nil && err6 == nil {
+		ipt.systemChains[rule.Table+"-"+chainName] = rule
+	}
+}
+
+// DeleteSystemRule deletes the chain of a system rule.
+// If force is false and the chain has not been previously added,
+// it won't try to delete it. Otherwise it'll try to delete it.
+func (ipt *iptables) DeleteSystemRule(rule *FwRule, force, logErrors bool) {
+	chain := systemRulePrefix + "-" + rule.Chain
+	if _, ok := ipt.systemChains[rule.Table+"-"+chain]; !ok && !force {
+		return
+	}
+	ipt.RunRule(FLUSH, true, logErrors, []string{chain, "-t", rule.Table})
+	ipt.RunRule(DELETE, false, logErrors, []string{rule.Chain, "-t", rule.Table, "-j", chain})
+	ipt.RunRule(DELCHAIN, true, logErrors, []string{chain, "-t", rule.Table})
+	delete(ipt.systemChains, rule.Table+"-"+chain)
+}
+
+// AddSystemRule inserts a new rule.
+func (ipt *iptables) AddSystemRule(rule *FwRule, enable bool) (err4, err6 error) {
+	chain := systemRulePrefix + "-" + rule.Chain
+	r := []string{chain, "-t", rule.Table}
+	if rule.Parameters != "" {
+		r = append(r, strings.Split(rule.Parameters, " ")...)
+	}
+	r = append(r, []string{"-j", rule.Target}...)
+	if rule.TargetParameters != "" {
+		r = append(r, strings.Split(rule.TargetParameters, " ")...)
+	}
+
+	return ipt.RunRule(ADD, enable, true, r)
+}
+
+// AreRulesLoaded checks if the firewall rules are loaded.
+func (ipt *iptables) AreRulesLoaded() bool {
+	ipt.Lock()
+	defer ipt.Unlock()
+
+	var outDrop6 string
+	var outMangle6 string
+
+	outDrop, err := core.Exec("iptables", []string{"-n", "-L", "OUTPUT"})
+	if err != nil {
+		return false
+	}
+	outMangle, err := core.Exec("iptables", []string{"-n", "-L", "OUTPUT", "-t", "mangle"})
+	if err != nil {
+		return false
+	}
+
+	if core.IPv6Enabled {
+		outDrop6, err = core.Exec("ip6tables", []string{"-n", "-L", "OUTPUT"})
+		if err != nil {
+			return false
+		}
+		outMangle6, err = core.Exec("ip6tables", []string{"-n", "-L", "OUTPUT", "-t", "mangle"})
+		if err != nil {
+			return false
+		}
+	}
+
+	systemRulesLoaded := true
+	if len(ipt.systemChains) > 0 {
+		for _, rule := range ipt.systemChains {
+			if chainOut4, err4 := core.Exec("iptables", []string{"-n", "-L", rule.Chain, "-t", rule.Table}); err4 == nil {
+				if regexSystemRulesQuery.FindString(chainOut4) == "" {
+					systemRulesLoaded = false
+					break
+				}
+			}
+			if core.IPv6Enabled {
+				if chainOut6, err6 := core.Exec("ip6tables", []string{"-n", "-L", rule.Chain, "-t", rule.Table}); err6 == nil {
+					if regexSystemRulesQuery.FindString(chainOut6) == "" {
+						systemRulesLoaded = false
+						break
+					}
+				}
+			}
+		}
+	}
+
+	result := regexDropQuery.FindString(outDrop) != "" &&
+		regexRejectQuery.FindString(outDrop) != "" &&
+		regexRulesQuery.FindString(outMangle) != "" &&
+		systemRulesLoaded
+
+	if core.IPv6Enabled {
+		result = result && regexDropQuery.FindString(outDrop6) != "" &&
+			regexRejectQuery.FindString(outDrop6) != "" &&
+			regexRulesQuery.FindString(outMangle6) != ""
+	}
+
+	return result
+}
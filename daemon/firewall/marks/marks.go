@@ -0,0 +1,18 @@
+// Package marks defines the firewall marks used by the daemon, so they can
+// be checked without depending on the firewall package.
+package marks
+
+// Drop is the mark we place on a connection when we deny it.
+// The connection is dropped later on OUTPUT chain.
+const Drop = 0x18BA5
+
+// Reject is the mark we place on a connection when we reject it.
+// The connection is accepted by the queue and rejected later on OUTPUT chain,
+// with a TCP RST or an ICMP port unreachable, so applications fail fast.
+const Reject = 0x18BA6
+
+// IsReserved returns true if the mark is used by the daemon, so it can't be
+// set by the rules.
+func IsReserved(mark uint32) bool {
+	return mark == Drop || mark == Reject
+}
@@ -0,0 +1,536 @@
+package firewall
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/evilsocket/opensnitch/daemon/log"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	nftTable = "opensnitch"
+
+	nftChainMangleOutput = "mangle-output"
+	nftChainFilterInput  = "filter-input"
+	nftChainFilterOutput = "filter-output"
+)
+
+// iptables tables translated to nftables chain types and priorities.
+var nftTablePriorities = map[string]int32{
+	"raw":      -300,
+	"mangle":   -150,
+	"filter":   0,
+	"security": 50,
+}
+
+// iptables chains translated to netfilter hooks.
+var nftChainHooks = map[string]uint32{
+	"PREROUTING":  nfInetPreRouting,
+	"INPUT":       nfInetLocalIn,
+	"FORWARD":     nfInetForward,
+	"OUTPUT":      nfInetLocalOut,
+	"POSTROUTING": nfInetPostRouting,
+}
+
+var nftProtocols = map[string]byte{
+	"icmp":      unix.IPPROTO_ICMP,
+	"tcp":       unix.IPPROTO_TCP,
+	"udp":       unix.IPPROTO_UDP,
+	"ipv6-icmp": unix.IPPROTO_ICMPV6,
+	"icmpv6":    unix.IPPROTO_ICMPV6,
+	"sctp":      unix.IPPROTO_SCTP,
+	"udplite":   unix.IPPROTO_UDPLITE,
+}
+
+var nftCtStates = map[string]uint32{
+	"INVALID":     1 << 0,
+	"ESTABLISHED": ctStateBitEstablished,
+	"RELATED":     ctStateBitRelated,
+	"NEW":         ctStateBitNew,
+	"UNTRACKED":   1 << 6,
+}
+
+// nftables manages the firewall rules talking directly to the kernel
+// via netlink, without depending on any external binary.
+// All the rules are added to the inet table "opensnitch", so they apply
+// both to IPv4 and IPv6, and can be deleted at once.
+type nftables struct {
+	sync.Mutex
+
+	// base chains created, attached to a netfilter hook.
+	baseChains map[string]bool
+	// system chains created, to which the system rules are added.
+	systemChains map[string]*FwRule
+	// number of rules we've added to each chain.
+	chainRules map[string]int
+}
+
+func newNftables() *nftables {
+	n := &nftables{}
+	n.reset()
+	return n
+}
+
+func (n *nftables) reset() {
+	n.baseChains = make(map[string]bool)
+	n.systemChains = make(map[string]*FwRule)
+	n.chainRules = make(map[string]int)
+}
+
+// Name returns the name of this firewall implementation.
+func (n *nftables) Name() string {
+	return NFTABLES
+}
+
+// IsAvailable checks if the kernel supports nftables.
+func (n *nftables) IsAvailable() bool {
+	_, err := nftListTables(unix.NFPROTO_INET)
+	return err == nil
+}
+
+// baseChainName returns the name of the chain attached to the hook of an
+// iptables table and chain, like mangle-output for -t mangle OUTPUT.
+func baseChainName(table, chain string) string {
+	return strings.ToLower(table + "-" + chain)
+}
+
+func systemChainName(table, chain string) string {
+	return "system-" + baseChainName(table, chain)
+}
+
+// addBaseChain adds to the batch the chain attached to the hook of an iptables
+// table and chain, if it doesn't exist yet.
+func (n *nftables) addBaseChain(b *nftBatch, table, chain string) (string, error) {
+	name := baseChainName(table, chain)
+	if n.baseChains[name] {
+		return name, nil
+	}
+	priority, found := nftTablePriorities[table]
+	if !found {
+		return "", fmt.Errorf("Table not supported: %s", table)
+	}
+	hook, found := nftChainHooks[chain]
+	if !found {
+		return "", fmt.Errorf("Chain not supported: %s", chain)
+	}
+	chainType := "filter"
+	// Rerouting after changing the mark of a packet, like iptables does
+	// on the mangle table.
+	if table == "mangle" && hook == nfInetLocalOut {
+		chainType = "route"
+	}
+	b.AddBaseChain(nftTable, name, chainType, hook, priority)
+
+	return name, nil
+}
+
+// InsertRules adds the rules needed to intercept connections.
+// Any previous table is deleted in the same transaction, so a residual
+// table from a previous run doesn't duplicate rules.
+func (n *nftables) InsertRules(qNum int) error {
+	n.Lock()
+	defer n.Unlock()
+
+	n.reset()
+	b := newNftBatch(unix.NFPROTO_INET)
+	b.AddTable(nftTable)
+	b.DelTable(nftTable)
+	b.AddTable(nftTable)
+
+	mangleOutput, _ := n.addBaseChain(b, "mangle", "OUTPUT")
+	filterInput, _ := n.addBaseChain(b, "filter", "INPUT")
+	filterOutput, _ := n.addBaseChain(b, "filter", "OUTPUT")
+
+	// udp sport 53 queue num 0 bypass
+	b.AddRule(nftTable, filterInput, false,
+		exprMeta(nftMetaL4proto),
+		exprCmp(nftCmpEq, []byte{unix.IPPROTO_UDP}),
+		exprPayload(nftPayloadTransportHeader, 0, 2),
+		exprCmp(nftCmpEq, be16(53)),
+		exprQueue(uint16(qNum), nftQueueFlagBypass),
+	)
+	// ct state new,related queue num 0 bypass
+	b.AddRule(nftTable, mangleOutput, false,
+		exprCt(nftCtState),
+		exprBitwise(native32(ctStateBitNew|ctStateBitRelated), native32(0)),
+		exprCmp(nftCmpNeq, native32(0)),
+		exprQueue(uint16(qNum), nftQueueFlagBypass),
+	)
+	// meta mark 0x18ba5 drop
+	b.AddRule(nftTable, filterOutput, false,
+		exprMeta(nftMetaMark),
+		exprCmp(nftCmpEq, native32(DropMark)),
+		exprVerdict(nfDrop, ""),
+	)
+	// meta mark 0x18ba6 meta l4proto tcp reject with tcp reset
+	b.AddRule(nftTable, filterOutput, false,
+		exprMeta(nftMetaMark),
+		exprCmp(nftCmpEq, native32(RejectMark)),
+		exprMeta(nftMetaL4proto),
+		exprCmp(nftCmpEq, []byte{unix.IPPROTO_TCP}),
+		exprReject(nftRejectTCPRst, 0),
+	)
+	// meta mark 0x18ba6 reject with icmpx port-unreachable
+	b.AddRule(nftTable, filterOutput, false,
+		exprMeta(nftMetaMark),
+		exprCmp(nftCmpEq, native32(RejectMark)),
+		exprReject(nftRejectIcmpxUnreach, nftRejectIcmpxPortUnreach),
+	)
+
+	if err := b.Send(); err != nil {
+		n.reset()
+		return fmt.Errorf("Error while adding nftables rules: %s", err)
+	}
+	for _, chain := range []string{mangleOutput, filterInput, filterOutput} {
+		n.baseChains[chain] = true
+	}
+	n.chainRules[mangleOutput] = 1
+	n.chainRules[filterInput] = 1
+	n.chainRules[filterOutput] = 3
+
+	return nil
+}
+
+// CleanRules deletes the opensnitch table, and with it all the rules we added.
+func (n *nftables) CleanRules(qNum int, logErrors bool) {
+	n.Lock()
+	defer n.Unlock()
+
+	b := newNftBatch(unix.NFPROTO_INET)
+	b.AddTable(nftTable)
+	b.DelTable(nftTable)
+	if err := b.Send(); err != nil && logErrors {
+		log.Error("Error deleting nftables table %s: %s", nftTable, err)
+	}
+	n.reset()
+}
+
+// CreateSystemRule creates the chain of the system rules, and makes the
+// base chain jump to it before any of our rules.
+func (n *nftables) CreateSystemRule(rule *FwRule, logErrors bool) {
+	n.Lock()
+	defer n.Unlock()
+
+	chain := systemChainName(rule.Table, rule.Chain)
+	if _, ok := n.systemChains[chain]; ok {
+		return
+	}
+
+	b := newNftBatch(unix.NFPROTO_INET)
+	b.AddTable(nftTable)
+	baseChain, err := n.addBaseChain(b, rule.Table, rule.Chain)
+	if err != nil {
+		if logErrors {
+			log.Error("Error creating nftables system chain %s: %s", chain, err)
+		}
+		return
+	}
+	b.AddChain(nftTable, chain)
+	b.AddRule(nftTable, baseChain, true, exprVerdict(nftJump, chain))
+	if err := b.Send(); err != nil {
+		if logErrors {
+			log.Error("Error creating nftables system chain %s: %s", chain, err)
+		}
+		return
+	}
+
+	n.baseChains[baseChain] = true
+	n.systemChains[chain] = rule
+	n.chainRules[baseChain]++
+}
+
+// DeleteSystemRule deletes the rules of a system chain. The chain itself
+// is kept, and deleted along with the table.
+func (n *nftables) DeleteSystemRule(rule *FwRule, force, logErrors bool) {
+	n.Lock()
+	defer n.Unlock()
+
+	chain := systemChainName(rule.Table, rule.Chain)
+	if _, ok := n.systemChains[chain]; !ok {
+		return
+	}
+	b := newNftBatch(unix.NFPROTO_INET)
+	b.FlushChain(nftTable, chain)
+	if err := b.Send(); err != nil && logErrors {
+		log.Error("Error flushing nftables system chain %s: %s", chain, err)
+	}
+	n.chainRules[chain] = 0
+}
+
+// AddSystemRule translates a system rule, written in iptables syntax,
+// to nftables, and adds it to its system chain, or deletes it if enable
+// is false.
+func (n *nftables) AddSystemRule(rule *FwRule, enable bool) (err4, err6 error) {
+	if !enable {
+		return n.delSystemRule(rule), nil
+	}
+	exprs, err := nftSystemRuleExprs(rule)
+	if err != nil {
+		log.Error("Error translating system rule \"%s\" to nftables: %s", rule.Description, err)
+		return err, nil
+	}
+
+	n.Lock()
+	defer n.Unlock()
+
+	chain := systemChainName(rule.Table, rule.Chain)
+	b := newNftBatch(unix.NFPROTO_INET)
+	b.AddRuleWithComment(nftTable, chain, nftSystemRuleComment(rule), false, exprs...)
+	if err = b.Send(); err != nil {
+		log.Error("Error adding nftables system rule \"%s\": %s", rule.Description, err)
+		return err, nil
+	}
+	n.chainRules[chain]++
+
+	return nil, nil
+}
+
+// delSystemRule deletes a system rule added by AddSystemRule, looking up
+// its handle by the comment it was tagged with.
+func (n *nftables) delSystemRule(rule *FwRule) error {
+	n.Lock()
+	defer n.Unlock()
+
+	chain := systemChainName(rule.Table, rule.Chain)
+	if _, ok := n.systemChains[chain]; !ok {
+		return nil
+	}
+	rules, err := nftListRules(unix.NFPROTO_INET, nftTable)
+	if err != nil {
+		log.Error("Error listing nftables rules: %s", err)
+		return err
+	}
+	// compared as stored, long comments are truncated
+	comment := nftUdataComment(nftCommentUdata(nftSystemRuleComment(rule)))
+	for _, r := range rules {
+		if r.chain != chain || r.comment != comment {
+			continue
+		}
+		b := newNftBatch(unix.NFPROTO_INET)
+		b.DelRule(nftTable, chain, r.handle)
+		if err := b.Send(); err != nil {
+			log.Error("Error deleting nftables system rule \"%s\": %s", rule.Description, err)
+			return err
+		}
+		if n.chainRules[chain] > 0 {
+			n.chainRules[chain]--
+		}
+		return nil
+	}
+
+	return nil
+}
+
+// nftSystemRuleComment identifies a system rule in its chain.
+func nftSystemRuleComment(rule *FwRule) string {
+	return strings.TrimSpace(fmt.Sprintf("%s -j %s %s", rule.Parameters, rule.Target, rule.TargetParameters))
+}
+
+// AreRulesLoaded checks that every rule we added is still in place.
+func (n *nftables) AreRulesLoaded() bool {
+	n.Lock()
+	defer n.Unlock()
+
+	list, err := nftListRules(unix.NFPROTO_INET, nftTable)
+	if err != nil {
+		return false
+	}
+	rules := make(map[string]int)
+	for _, r := range list {
+		rules[r.chain]++
+	}
+	for chain, num := range n.chainRules {
+		if rules[chain] < num {
+			return false
+		}
+	}
+
+	return len(n.chainRules) > 0
+}
+
+// nftSystemRuleExprs translates the most common iptables options of a
+// system rule to nftables expressions.
+func nftSystemRuleExprs(rule *FwRule) ([]nftExpr, error) {
+	var exprs []nftExpr
+	params := strings.Fields(rule.Parameters)
+	proto := byte(0)
+
+	for i := 0; i < len(params); i++ {
+		opt := params[i]
+		if i+1 >= len(params) {
+			return nil, fmt.Errorf("Missing value of option %s", opt)
+		}
+		i++
+		value := params[i]
+
+		switch opt {
+		case "-m", "--match":
+			// matches are implicit in nftables
+		case "-p", "--protocol":
+			p, found := nftProtocols[strings.ToLower(value)]
+			if !found {
+				num, err := strconv.ParseUint(value, 10, 8)
+				if err != nil {
+					return nil, fmt.Errorf("Protocol not supported: %s", value)
+				}
+				p = byte(num)
+			}
+			proto = p
+			exprs = append(exprs, exprMeta(nftMetaL4proto), exprCmp(nftCmpEq, []byte{p}))
+		case "--sport", "--source-port", "--dport", "--destination-port":
+			if proto != unix.IPPROTO_TCP && proto != unix.IPPROTO_UDP &&
+				proto != unix.IPPROTO_UDPLITE && proto != unix.IPPROTO_SCTP {
+				return nil, fmt.Errorf("Option %s needs a tcp, udp, udplite or sctp protocol", opt)
+			}
+			port, err := strconv.ParseUint(value, 10, 16)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid port %s", value)
+			}
+			offset := uint32(0)
+			if opt == "--dport" || opt == "--destination-port" {
+				offset = 2
+			}
+			exprs = append(exprs,
+				exprPayload(nftPayloadTransportHeader, offset, 2),
+				exprCmp(nftCmpEq, be16(uint16(port))))
+		case "-s", "--source", "-d", "--destination":
+			e, err := nftAddressExprs(value, opt == "-s" || opt == "--source")
+			if err != nil {
+				return nil, err
+			}
+			exprs = append(exprs, e...)
+		case "-i", "--in-interface", "-o", "--out-interface":
+			key := uint32(nftMetaOifname)
+			if opt == "-i" || opt == "--in-interface" {
+				key = nftMetaIifname
+			}
+			exprs = append(exprs, exprMeta(key), exprCmp(nftCmpEq, nftIfname(value)))
+		case "--ctstate", "--state":
+			states := uint32(0)
+			for _, s := range strings.Split(value, ",") {
+				st, found := nftCtStates[strings.ToUpper(s)]
+				if !found {
+					return nil, fmt.Errorf("Invalid conntrack state %s", s)
+				}
+				states |= st
+			}
+			exprs = append(exprs,
+				exprCt(nftCtState),
+				exprBitwise(native32(states), native32(0)),
+				exprCmp(nftCmpNeq, native32(0)))
+		case "--mark":
+			mark, mask, err := nftParseMark(value)
+			if err != nil {
+				return nil, err
+			}
+			exprs = append(exprs, exprMeta(nftMetaMark))
+			if mask != 0xffffffff {
+				exprs = append(exprs, exprBitwise(native32(mask), native32(0)))
+			}
+			exprs = append(exprs, exprCmp(nftCmpEq, native32(mark&mask)))
+		default:
+			return nil, fmt.Errorf("Option not supported: %s", opt)
+		}
+	}
+
+	switch strings.ToUpper(rule.Target) {
+	case "ACCEPT":
+		exprs = append(exprs, exprVerdict(nfAccept, ""))
+	case "DROP":
+		exprs = append(exprs, exprVerdict(nfDrop, ""))
+	case "RETURN":
+		exprs = append(exprs, exprVerdict(nftReturn, ""))
+	case "MARK":
+		targetParams := strings.Fields(rule.TargetParameters)
+		if len(targetParams) != 2 || (targetParams[0] != "--set-mark" && targetParams[0] != "--set-xmark") {
+			return nil, fmt.Errorf("Invalid MARK target parameters: %s", rule.TargetParameters)
+		}
+		mark, mask, err := nftParseMark(targetParams[1])
+		if err != nil {
+			return nil, err
+		}
+		if mask != 0xffffffff {
+			return nil, fmt.Errorf("Masked marks not supported: %s", targetParams[1])
+		}
+		exprs = append(exprs, exprImmediate(native32(mark)), exprMetaSet(nftMetaMark))
+	default:
+		return nil, fmt.Errorf("Target not supported: %s", rule.Target)
+	}
+
+	return exprs, nil
+}
+
+// nftAddressExprs matches the source or destination address of a packet
+// against an IP or a network (1.2.3.4, 10.0.0.0/8, ::1, ...).
+func nftAddressExprs(value string, source bool) ([]nftExpr, error) {
+	var ip net.IP
+	var mask net.IPMask
+	if _, ipNet, err := net.ParseCIDR(value); err == nil {
+		ip, mask = ipNet.IP, ipNet.Mask
+	} else if ip = net.ParseIP(value); ip == nil {
+		return nil, fmt.Errorf("Invalid address %s", value)
+	}
+
+	family := byte(unix.NFPROTO_IPV4)
+	offset := uint32(16)
+	if source {
+		offset = 12
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	} else {
+		family = unix.NFPROTO_IPV6
+		offset = 24
+		if source {
+			offset = 8
+		}
+	}
+	if mask == nil {
+		mask = net.CIDRMask(len(ip)*8, len(ip)*8)
+	}
+
+	exprs := []nftExpr{
+		exprMeta(nftMetaNfproto),
+		exprCmp(nftCmpEq, []byte{family}),
+		exprPayload(nftPayloadNetworkHeader, offset, uint32(len(ip))),
+	}
+	if ones, bits := mask.Size(); ones != bits {
+		exprs = append(exprs, exprBitwise(mask, make([]byte, len(mask))))
+	}
+	exprs = append(exprs, exprCmp(nftCmpEq, ip.Mask(mask)))
+
+	return exprs, nil
+}
+
+// nftIfname returns the interface name as compared by nftables. Names ending
+// with + (eth+) match any interface starting with the given prefix.
+func nftIfname(name string) []byte {
+	if strings.HasSuffix(name, "+") {
+		return []byte(strings.TrimSuffix(name, "+"))
+	}
+	ifname := make([]byte, ifNameSize)
+	copy(ifname, name)
+	return ifname
+}
+
+// nftParseMark parses a mark in the iptables format: value[/mask]
+func nftParseMark(value string) (mark, mask uint32, err error) {
+	mask = 0xffffffff
+	parts := strings.SplitN(value, "/", 2)
+	m, err := strconv.ParseUint(parts[0], 0, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("Invalid mark %s", value)
+	}
+	if len(parts) == 2 {
+		msk, err := strconv.ParseUint(parts[1], 0, 32)
+		if err != nil {
+			return 0, 0, fmt.Errorf("Invalid mark mask %s", value)
+		}
+		mask = uint32(msk)
+	}
+	return uint32(m), mask, nil
+}
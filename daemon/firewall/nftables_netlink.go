@@ -0,0 +1,545 @@
+package firewall
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"syscall"
+
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+)
+
+// nf_tables netlink API, from linux/netfilter/nf_tables.h
+const (
+	nfnlMsgBatchBegin = unix.NLMSG_MIN_TYPE
+	nfnlMsgBatchEnd   = unix.NLMSG_MIN_TYPE + 1
+
+	nftMsgNewTable = 0
+	nftMsgGetTable = 1
+	nftMsgDelTable = 2
+	nftMsgNewChain = 3
+	nftMsgNewRule  = 6
+	nftMsgGetRule  = 7
+	nftMsgDelRule  = 8
+
+	nftaTableName = 1
+
+	nftaChainTable  = 1
+	nftaChainName   = 3
+	nftaChainHook   = 4
+	nftaChainPolicy = 5
+	nftaChainType   = 7
+
+	nftaHookHooknum  = 1
+	nftaHookPriority = 2
+
+	nftaRuleTable       = 1
+	nftaRuleChain       = 2
+	nftaRuleHandle      = 3
+	nftaRuleExpressions = 4
+	nftaRuleUserdata    = 7
+
+	// the comment of a rule is stored in its userdata, as a
+	// type-length-value entry (libnftnl's NFTNL_UDATA_RULE_COMMENT).
+	nftUdataRuleComment = 0
+
+	nftaListElem = 1
+	nftaExprName = 1
+	nftaExprData = 2
+
+	nftaDataValue   = 1
+	nftaDataVerdict = 2
+
+	nftaVerdictCode  = 1
+	nftaVerdictChain = 2
+
+	nftRegVerdict = 0
+	nftReg1       = 1
+
+	nftaMetaDreg = 1
+	nftaMetaKey  = 2
+	nftaMetaSreg = 3
+
+	nftMetaMark    = 3
+	nftMetaIifname = 6
+	nftMetaOifname = 7
+	nftMetaNfproto = 15
+	nftMetaL4proto = 16
+
+	nftaCmpSreg = 1
+	nftaCmpOp   = 2
+	nftaCmpData = 3
+
+	nftCmpEq  = 0
+	nftCmpNeq = 1
+
+	nftaCtDreg = 1
+	nftaCtKey  = 2
+
+	nftCtState = 0
+
+	nftaBitwiseSreg = 1
+	nftaBitwiseDreg = 2
+	nftaBitwiseLen  = 3
+	nftaBitwiseMask = 4
+	nftaBitwiseXor  = 5
+
+	nftaPayloadDreg   = 1
+	nftaPayloadBase   = 2
+	nftaPayloadOffset = 3
+	nftaPayloadLen    = 4
+
+	nftPayloadNetworkHeader   = 1
+	nftPayloadTransportHeader = 2
+
+	nftaQueueNum   = 1
+	nftaQueueTotal = 2
+	nftaQueueFlags = 3
+
+	nftQueueFlagBypass = 0x01
+
+	nftaImmediateDreg = 1
+	nftaImmediateData = 2
+
+	nftaRejectType     = 1
+	nftaRejectIcmpCode = 2
+
+	nftRejectTCPRst       = 1
+	nftRejectIcmpxUnreach = 2
+
+	nftRejectIcmpxPortUnreach = 1
+
+	// verdicts
+	nfDrop      = 0
+	nfAccept    = 1
+	nftContinue = -1
+	nftJump     = -3
+	nftReturn   = -5
+
+	// netfilter hooks, from linux/netfilter.h
+	nfInetPreRouting  = 0
+	nfInetLocalIn     = 1
+	nfInetForward     = 2
+	nfInetLocalOut    = 3
+	nfInetPostRouting = 4
+
+	// conntrack states, as stored by the ct expression.
+	ctStateBitEstablished = 1 << 1
+	ctStateBitRelated     = 1 << 2
+	ctStateBitNew         = 1 << 3
+
+	ifNameSize = unix.IFNAMSIZ
+)
+
+// nftExpr is an expression of a nftables rule.
+type nftExpr struct {
+	name  string
+	attrs []*nl.RtAttr
+}
+
+func be16(v uint16) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, v)
+	return b
+}
+
+func be64(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
+
+func be32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	return b
+}
+
+func native32(v uint32) []byte {
+	b := make([]byte, 4)
+	nl.NativeEndian().PutUint32(b, v)
+	return b
+}
+
+func nested(attrType int, children ...*nl.RtAttr) *nl.RtAttr {
+	attr := nl.NewRtAttr(attrType|nl.NLA_F_NESTED, nil)
+	for _, c := range children {
+		attr.AddChild(c)
+	}
+	return attr
+}
+
+func dataValue(attrType int, value []byte) *nl.RtAttr {
+	return nested(attrType, nl.NewRtAttr(nftaDataValue, value))
+}
+
+// exprMeta loads a meta key (mark, l4proto, ...) into the register 1.
+func exprMeta(key uint32) nftExpr {
+	return nftExpr{"meta", []*nl.RtAttr{
+		nl.NewRtAttr(nftaMetaDreg, be32(nftReg1)),
+		nl.NewRtAttr(nftaMetaKey, be32(key)),
+	}}
+}
+
+// exprMetaSet sets a meta key (mark, ...) from the register 1.
+func exprMetaSet(key uint32) nftExpr {
+	return nftExpr{"meta", []*nl.RtAttr{
+		nl.NewRtAttr(nftaMetaKey, be32(key)),
+		nl.NewRtAttr(nftaMetaSreg, be32(nftReg1)),
+	}}
+}
+
+// exprCt loads a conntrack key into the register 1.
+func exprCt(key uint32) nftExpr {
+	return nftExpr{"ct", []*nl.RtAttr{
+		nl.NewRtAttr(nftaCtDreg, be32(nftReg1)),
+		nl.NewRtAttr(nftaCtKey, be32(key)),
+	}}
+}
+
+// exprPayload loads len bytes of the packet at the given offset into the register 1.
+func exprPayload(base, offset, len uint32) nftExpr {
+	return nftExpr{"payload", []*nl.RtAttr{
+		nl.NewRtAttr(nftaPayloadDreg, be32(nftReg1)),
+		nl.NewRtAttr(nftaPayloadBase, be32(base)),
+		nl.NewRtAttr(nftaPayloadOffset, be32(offset)),
+		nl.NewRtAttr(nftaPayloadLen, be32(len)),
+	}}
+}
+
+// exprBitwise applies (reg1 & mask) ^ xor to the register 1.
+func exprBitwise(mask, xor []byte) nftExpr {
+	return nftExpr{"bitwise", []*nl.RtAttr{
+		nl.NewRtAttr(nftaBitwiseSreg, be32(nftReg1)),
+		nl.NewRtAttr(nftaBitwiseDreg, be32(nftReg1)),
+		nl.NewRtAttr(nftaBitwiseLen, be32(uint32(len(mask)))),
+		dataValue(nftaBitwiseMask, mask),
+		dataValue(nftaBitwiseXor, xor),
+	}}
+}
+
+// exprCmp compares the register 1 against the given data.
+func exprCmp(op uint32, data []byte) nftExpr {
+	return nftExpr{"cmp", []*nl.RtAttr{
+		nl.NewRtAttr(nftaCmpSreg, be32(nftReg1)),
+		nl.NewRtAttr(nftaCmpOp, be32(op)),
+		dataValue(nftaCmpData, data),
+	}}
+}
+
+// exprImmediate loads the given data into the register 1.
+func exprImmediate(data []byte) nftExpr {
+	return nftExpr{"immediate", []*nl.RtAttr{
+		nl.NewRtAttr(nftaImmediateDreg, be32(nftReg1)),
+		dataValue(nftaImmediateData, data),
+	}}
+}
+
+// exprQueue sends the packet to the given netfilter queue.
+func exprQueue(qNum uint16, flags uint16) nftExpr {
+	return nftExpr{"queue", []*nl.RtAttr{
+		nl.NewRtAttr(nftaQueueNum, be16(qNum)),
+		nl.NewRtAttr(nftaQueueTotal, be16(1)),
+		nl.NewRtAttr(nftaQueueFlags, be16(flags)),
+	}}
+}
+
+// exprReject rejects the packet, replying with a TCP RST or an ICMP error.
+// code is only used by the ICMP reject types.
+func exprReject(rejectType uint32, code uint8) nftExpr {
+	return nftExpr{"reject", []*nl.RtAttr{
+		nl.NewRtAttr(nftaRejectType, be32(rejectType)),
+		nl.NewRtAttr(nftaRejectIcmpCode, []byte{code}),
+	}}
+}
+
+// exprVerdict ends the rule with a verdict. chain is only used
+// by jump and goto verdicts.
+func exprVerdict(code int32, chain string) nftExpr {
+	verdict := nested(nftaDataVerdict, nl.NewRtAttr(nftaVerdictCode, be32(uint32(code))))
+	if chain != "" {
+		verdict.AddRtAttr(nftaVerdictChain, nl.ZeroTerminated(chain))
+	}
+	return nftExpr{"immediate", []*nl.RtAttr{
+		nl.NewRtAttr(nftaImmediateDreg, be32(nftRegVerdict)),
+		nested(nftaImmediateData, verdict),
+	}}
+}
+
+func exprsAttr(exprs []nftExpr) *nl.RtAttr {
+	list := nested(nftaRuleExpressions)
+	for _, e := range exprs {
+		elem := nested(nftaListElem, nl.NewRtAttr(nftaExprName, nl.ZeroTerminated(e.name)))
+		elem.AddChild(nested(nftaExprData, e.attrs...))
+		list.AddChild(elem)
+	}
+	return list
+}
+
+func nftMsgType(msg int) int {
+	return unix.NFNL_SUBSYS_NFTABLES<<8 | msg
+}
+
+func newNftRequest(msg, flags int, family uint8, attrs ...*nl.RtAttr) *nl.NetlinkRequest {
+	req := nl.NewNetlinkRequest(nftMsgType(msg), flags)
+	req.AddData(&nl.Nfgenmsg{
+		NfgenFamily: family,
+		Version:     nl.NFNETLINK_V0,
+	})
+	for _, a := range attrs {
+		req.AddData(a)
+	}
+	return req
+}
+
+// nftBatch groups several nftables operations, which are applied
+// atomically by the kernel: either all of them are applied or none.
+type nftBatch struct {
+	family uint8
+	reqs   []*nl.NetlinkRequest
+	ops    []string
+}
+
+func newNftBatch(family uint8) *nftBatch {
+	return &nftBatch{family: family}
+}
+
+func (b *nftBatch) add(op string, msg, flags int, attrs ...*nl.RtAttr) {
+	b.reqs = append(b.reqs, newNftRequest(msg, flags|unix.NLM_F_ACK, b.family, attrs...))
+	b.ops = append(b.ops, op)
+}
+
+// AddTable creates the table, if it doesn't exist.
+func (b *nftBatch) AddTable(table string) {
+	b.add("add table "+table, nftMsgNewTable, unix.NLM_F_CREATE,
+		nl.NewRtAttr(nftaTableName, nl.ZeroTerminated(table)))
+}
+
+// DelTable deletes the table, with all its chains and rules.
+func (b *nftBatch) DelTable(table string) {
+	b.add("delete table "+table, nftMsgDelTable, 0,
+		nl.NewRtAttr(nftaTableName, nl.ZeroTerminated(table)))
+}
+
+// AddBaseChain creates a chain attached to a netfilter hook.
+func (b *nftBatch) AddBaseChain(table, chain, chainType string, hook uint32, priority int32) {
+	b.add("add chain "+chain, nftMsgNewChain, unix.NLM_F_CREATE,
+		nl.NewRtAttr(nftaChainTable, nl.ZeroTerminated(table)),
+		nl.NewRtAttr(nftaChainName, nl.ZeroTerminated(chain)),
+		nested(nftaChainHook,
+			nl.NewRtAttr(nftaHookHooknum, be32(hook)),
+			nl.NewRtAttr(nftaHookPriority, be32(uint32(priority))),
+		),
+		nl.NewRtAttr(nftaChainPolicy, be32(nfAccept)),
+		nl.NewRtAttr(nftaChainType, nl.ZeroTerminated(chainType)),
+	)
+}
+
+// AddChain creates a regular chain, only reachable by jumping to it.
+func (b *nftBatch) AddChain(table, chain string) {
+	b.add("add chain "+chain, nftMsgNewChain, unix.NLM_F_CREATE,
+		nl.NewRtAttr(nftaChainTable, nl.ZeroTerminated(table)),
+		nl.NewRtAttr(nftaChainName, nl.ZeroTerminated(chain)),
+	)
+}
+
+// AddRule appends a rule to the chain, or inserts it at the top of the chain
+// if insert is true.
+func (b *nftBatch) AddRule(table, chain string, insert bool, exprs ...nftExpr) {
+	b.AddRuleWithComment(table, chain, "", insert, exprs...)
+}
+
+// AddRuleWithComment adds a rule like AddRule, tagged with a comment
+// which identifies it when listing the rules (see nftListRules).
+func (b *nftBatch) AddRuleWithComment(table, chain, comment string, insert bool, exprs ...nftExpr) {
+	flags := unix.NLM_F_CREATE
+	if !insert {
+		flags |= unix.NLM_F_APPEND
+	}
+	attrs := []*nl.RtAttr{
+		nl.NewRtAttr(nftaRuleTable, nl.ZeroTerminated(table)),
+		nl.NewRtAttr(nftaRuleChain, nl.ZeroTerminated(chain)),
+		exprsAttr(exprs),
+	}
+	if comment != "" {
+		attrs = append(attrs, nl.NewRtAttr(nftaRuleUserdata, nftCommentUdata(comment)))
+	}
+	b.add("add rule to "+chain, nftMsgNewRule, flags, attrs...)
+}
+
+// DelRule deletes a rule of the chain, given its handle.
+func (b *nftBatch) DelRule(table, chain string, handle uint64) {
+	b.add("delete rule of "+chain, nftMsgDelRule, 0,
+		nl.NewRtAttr(nftaRuleTable, nl.ZeroTerminated(table)),
+		nl.NewRtAttr(nftaRuleChain, nl.ZeroTerminated(chain)),
+		nl.NewRtAttr(nftaRuleHandle, be64(handle)),
+	)
+}
+
+// FlushChain deletes all the rules of a chain.
+func (b *nftBatch) FlushChain(table, chain string) {
+	b.add("flush chain "+chain, nftMsgDelRule, 0,
+		nl.NewRtAttr(nftaRuleTable, nl.ZeroTerminated(table)),
+		nl.NewRtAttr(nftaRuleChain, nl.ZeroTerminated(chain)),
+	)
+}
+
+// Len returns the number of operations of the batch.
+func (b *nftBatch) Len() int {
+	return len(b.reqs)
+}
+
+// Send commits the batch, and waits for the kernel to acknowledge every operation.
+func (b *nftBatch) Send() error {
+	if len(b.reqs) == 0 {
+		return nil
+	}
+	sock, err := nl.Subscribe(unix.NETLINK_NETFILTER)
+	if err != nil {
+		return err
+	}
+	defer sock.Close()
+
+	batchMsg := func(msgType int) []byte {
+		req := nl.NewNetlinkRequest(msgType, 0)
+		req.AddData(&nl.Nfgenmsg{
+			NfgenFamily: unix.AF_UNSPEC,
+			Version:     nl.NFNETLINK_V0,
+			ResId:       nl.Swap16(unix.NFNL_SUBSYS_NFTABLES),
+		})
+		return req.Serialize()
+	}
+
+	pending := make(map[uint32]int, len(b.reqs))
+	buf := batchMsg(nfnlMsgBatchBegin)
+	for i, req := range b.reqs {
+		buf = append(buf, req.Serialize()...)
+		pending[req.Seq] = i
+	}
+	buf = append(buf, batchMsg(nfnlMsgBatchEnd)...)
+
+	if err := unix.Sendto(sock.GetFd(), buf, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return err
+	}
+
+	for len(pending) > 0 {
+		msgs, _, err := sock.Receive()
+		if err != nil {
+			return err
+		}
+		for _, m := range msgs {
+			idx, found := pending[m.Header.Seq]
+			if !found || m.Header.Type != unix.NLMSG_ERROR || len(m.Data) < 4 {
+				continue
+			}
+			delete(pending, m.Header.Seq)
+			if errno := int32(nl.NativeEndian().Uint32(m.Data[0:4])); errno != 0 {
+				return fmt.Errorf("%s: %s", b.ops[idx], syscall.Errno(-errno))
+			}
+		}
+	}
+
+	return nil
+}
+
+// nftCommentUdata encodes a rule comment as userdata, the way the nft
+// command does, so it's also shown by "nft list ruleset".
+func nftCommentUdata(comment string) []byte {
+	value := nl.ZeroTerminated(comment)
+	if len(value) > 255 {
+		value = append(value[:254], 0)
+	}
+	return append([]byte{nftUdataRuleComment, byte(len(value))}, value...)
+}
+
+// nftUdataComment returns the comment of a rule from its userdata.
+func nftUdataComment(udata []byte) string {
+	for len(udata) >= 2 {
+		udType, udLen := udata[0], int(udata[1])
+		if len(udata) < 2+udLen {
+			break
+		}
+		if udType == nftUdataRuleComment {
+			return strings.TrimRight(string(udata[2:2+udLen]), "\x00")
+		}
+		udata = udata[2+udLen:]
+	}
+	return ""
+}
+
+// nftRule is a rule listed from the kernel.
+type nftRule struct {
+	chain   string
+	handle  uint64
+	comment string
+}
+
+// nftListRules returns the rules of the given table.
+func nftListRules(family uint8, table string) ([]nftRule, error) {
+	req := newNftRequest(nftMsgGetRule, unix.NLM_F_DUMP, family,
+		nl.NewRtAttr(nftaRuleTable, nl.ZeroTerminated(table)))
+	msgs, err := req.Execute(unix.NETLINK_NETFILTER, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]nftRule, 0, len(msgs))
+	for _, m := range msgs {
+		if len(m) < nl.SizeofNfgenmsg {
+			continue
+		}
+		attrs, err := nl.ParseRouteAttr(m[nl.SizeofNfgenmsg:])
+		if err != nil {
+			return nil, err
+		}
+		ruleTable := ""
+		r := nftRule{}
+		for _, a := range attrs {
+			switch a.Attr.Type & ^uint16(nl.NLA_F_NESTED) {
+			case nftaRuleTable:
+				ruleTable = nl.BytesToString(a.Value)
+			case nftaRuleChain:
+				r.chain = nl.BytesToString(a.Value)
+			case nftaRuleHandle:
+				if len(a.Value) == 8 {
+					r.handle = binary.BigEndian.Uint64(a.Value)
+				}
+			case nftaRuleUserdata:
+				r.comment = nftUdataComment(a.Value)
+			}
+		}
+		if ruleTable == table {
+			rules = append(rules, r)
+		}
+	}
+
+	return rules, nil
+}
+
+// nftListTables returns the names of the tables of the given family.
+func nftListTables(family uint8) ([]string, error) {
+	req := newNftRequest(nftMsgGetTable, unix.NLM_F_DUMP, family)
+	msgs, err := req.Execute(unix.NETLINK_NETFILTER, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	tables := make([]string, 0, len(msgs))
+	for _, m := range msgs {
+		if len(m) < nl.SizeofNfgenmsg {
+			continue
+		}
+		attrs, err := nl.ParseRouteAttr(m[nl.SizeofNfgenmsg:])
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range attrs {
+			if a.Attr.Type == nftaTableName {
+				tables = append(tables, nl.BytesToString(a.Value))
+			}
+		}
+	}
+
+	return tables, nil
+}
@@ -0,0 +1,126 @@
+package firewall
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func exprNames(exprs []nftExpr) []string {
+	names := make([]string, 0, len(exprs))
+	for _, e := range exprs {
+		names = append(names, e.name)
+	}
+	return names
+}
+
+func TestNftSystemRuleExprs(t *testing.T) {
+	rule := &FwRule{Parameters: "-p udp --dport 53 -o eth0", Target: "ACCEPT"}
+	exprs, err := nftSystemRuleExprs(rule)
+	if err != nil {
+		t.Fatal("Error translating rule:", err)
+	}
+	expected := []nftExpr{
+		exprMeta(nftMetaL4proto), exprCmp(nftCmpEq, []byte{unix.IPPROTO_UDP}),
+		exprPayload(nftPayloadTransportHeader, 2, 2), exprCmp(nftCmpEq, be16(53)),
+		exprMeta(nftMetaOifname), exprCmp(nftCmpEq, nftIfname("eth0")),
+		exprVerdict(nfAccept, ""),
+	}
+	if !reflect.DeepEqual(exprs, expected) {
+		t.Errorf("Unexpected expressions: %v", exprNames(exprs))
+	}
+
+	rule = &FwRule{Parameters: "-m conntrack --ctstate ESTABLISHED,RELATED --mark 0x10/0xff", Target: "MARK", TargetParameters: "--set-mark 0x20"}
+	exprs, err = nftSystemRuleExprs(rule)
+	if err != nil {
+		t.Fatal("Error translating rule:", err)
+	}
+	expected = []nftExpr{
+		exprCt(nftCtState),
+		exprBitwise(native32(ctStateBitEstablished|ctStateBitRelated), native32(0)),
+		exprCmp(nftCmpNeq, native32(0)),
+		exprMeta(nftMetaMark), exprBitwise(native32(0xff), native32(0)), exprCmp(nftCmpEq, native32(0x10)),
+		exprImmediate(native32(0x20)), exprMetaSet(nftMetaMark),
+	}
+	if !reflect.DeepEqual(exprs, expected) {
+		t.Errorf("Unexpected expressions: %v", exprNames(exprs))
+	}
+
+	for _, r := range []*FwRule{
+		{Parameters: "--dport 53", Target: "ACCEPT"},
+		{Parameters: "-p icmp --dport 53", Target: "ACCEPT"},
+		{Parameters: "-p udp --dport", Target: "ACCEPT"},
+		{Parameters: "-p foo", Target: "ACCEPT"},
+		{Parameters: "--ctstate BOGUS", Target: "ACCEPT"},
+		{Parameters: "--limit 1/s", Target: "ACCEPT"},
+		{Parameters: "-p tcp", Target: "LOG"},
+		{Parameters: "-p tcp", Target: "MARK", TargetParameters: "--set-mark 0x1/0xf"},
+	} {
+		if _, err := nftSystemRuleExprs(r); err == nil {
+			t.Errorf("Rule \"%s -j %s %s\" translated", r.Parameters, r.Target, r.TargetParameters)
+		}
+	}
+}
+
+func TestNftAddressExprs(t *testing.T) {
+	tests := []struct {
+		value  string
+		source bool
+		family byte
+		offset uint32
+		mask   net.IPMask
+		ip     net.IP
+	}{
+		{"1.2.3.4", true, unix.NFPROTO_IPV4, 12, nil, net.IP{1, 2, 3, 4}},
+		{"10.1.2.3/8", false, unix.NFPROTO_IPV4, 16, net.CIDRMask(8, 32), net.IP{10, 0, 0, 0}},
+		{"::1", true, unix.NFPROTO_IPV6, 8, nil, net.ParseIP("::1")},
+		{"fd00::/64", false, unix.NFPROTO_IPV6, 24, net.CIDRMask(64, 128), net.ParseIP("fd00::")},
+	}
+	for _, test := range tests {
+		exprs, err := nftAddressExprs(test.value, test.source)
+		if err != nil {
+			t.Errorf("Error translating %s: %s", test.value, err)
+			continue
+		}
+		expected := []nftExpr{
+			exprMeta(nftMetaNfproto),
+			exprCmp(nftCmpEq, []byte{test.family}),
+			exprPayload(nftPayloadNetworkHeader, test.offset, uint32(len(test.ip))),
+		}
+		if test.mask != nil {
+			expected = append(expected, exprBitwise(test.mask, make([]byte, len(test.mask))))
+		}
+		expected = append(expected, exprCmp(nftCmpEq, test.ip))
+		if !reflect.DeepEqual(exprs, expected) {
+			t.Errorf("Unexpected expressions of %s: %v", test.value, exprNames(exprs))
+		}
+	}
+
+	if _, err := nftAddressExprs("1.2.3", true); err == nil {
+		t.Error("Invalid address translated")
+	}
+}
+
+func TestNftRuleComment(t *testing.T) {
+	rule := &FwRule{Parameters: "-p udp --dport 53", Target: "ACCEPT"}
+	comment := nftSystemRuleComment(rule)
+	if comment != "-p udp --dport 53 -j ACCEPT" {
+		t.Errorf("Unexpected comment: %s", comment)
+	}
+	if c := nftUdataComment(nftCommentUdata(comment)); c != comment {
+		t.Errorf("Comment not decoded: %s", c)
+	}
+
+	long := make([]byte, 300)
+	for i := range long {
+		long[i] = 'a'
+	}
+	if c := nftUdataComment(nftCommentUdata(string(long))); len(c) != 254 {
+		t.Errorf("Long comment not truncated: %d", len(c))
+	}
+	if c := nftUdataComment([]byte{nftUdataRuleComment, 10, 'a'}); c != "" {
+		t.Errorf("Truncated userdata decoded: %s", c)
+	}
+}
@@ -0,0 +1,161 @@
+package firewall
+
+import (
+	"fmt"
+	"sync"
+)
+
+// RECORDER is the name of the in-memory Backend.
+const RECORDER = "recorder"
+
+// Recorder is an in-memory Backend which doesn't modify the system firewall.
+// It keeps the state of the rules, and records every call made to it,
+// so the Firewall logic can be tested without root privileges.
+type Recorder struct {
+	sync.Mutex
+
+	// Calls holds the operations performed, in order.
+	Calls []string
+	// Loaded is true while the interception rules are inserted.
+	Loaded bool
+	// SystemChains holds the chains created for the system rules.
+	SystemChains map[string]*FwRule
+	// SystemRules holds the system rules added.
+	SystemRules []*FwRule
+	// InsertErr, if set, is returned by InsertRules.
+	InsertErr error
+}
+
+// NewRecorder returns a new in-memory Backend.
+func NewRecorder() *Recorder {
+	return &Recorder{
+		SystemChains: make(map[string]*FwRule),
+	}
+}
+
+func (r *Recorder) record(format string, args ...interface{}) {
+	r.Calls = append(r.Calls, fmt.Sprintf(format, args...))
+}
+
+// Name returns the name of this firewall implementation.
+func (r *Recorder) Name() string {
+	return RECORDER
+}
+
+// IsAvailable always returns true.
+func (r *Recorder) IsAvailable() bool {
+	return true
+}
+
+// InsertRules marks the interception rules as loaded.
+func (r *Recorder) InsertRules(qNum int) error {
+	r.Lock()
+	defer r.Unlock()
+
+	r.record("InsertRules %d", qNum)
+	if r.InsertErr != nil {
+		return r.InsertErr
+	}
+	r.Loaded = true
+	return nil
+}
+
+// CleanRules marks the interception rules as not loaded.
+func (r *Recorder) CleanRules(qNum int, logErrors bool) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.record("CleanRules %d", qNum)
+	r.Loaded = false
+}
+
+// CreateSystemRule creates the chain of a system rule, if it doesn't exist.
+func (r *Recorder) CreateSystemRule(rule *FwRule, logErrors bool) {
+	r.Lock()
+	defer r.Unlock()
+
+	key := rule.Table + "-" + rule.Chain
+	if _, ok := r.SystemChains[key]; ok {
+		return
+	}
+	r.record("CreateSystemRule %s", key)
+	r.SystemChains[key] = rule
+}
+
+// DeleteSystemRule deletes the chain of a system rule, and its rules.
+func (r *Recorder) DeleteSystemRule(rule *FwRule, force, logErrors bool) {
+	r.Lock()
+	defer r.Unlock()
+
+	key := rule.Table + "-" + rule.Chain
+	if _, ok := r.SystemChains[key]; !ok && !force {
+		return
+	}
+	r.record("DeleteSystemRule %s", key)
+	delete(r.SystemChains, key)
+
+	rules := r.SystemRules[:0]
+	for _, sr := range r.SystemRules {
+		if sr.Table+"-"+sr.Chain != key {
+			rules = append(rules, sr)
+		}
+	}
+	r.SystemRules = rules
+}
+
+// AddSystemRule adds a system rule, or deletes it if enable is false.
+func (r *Recorder) AddSystemRule(rule *FwRule, enable bool) (err4, err6 error) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.record("AddSystemRule %s-%s %s -j %s %v", rule.Table, rule.Chain, rule.Parameters, rule.Target, enable)
+	if enable {
+		r.SystemRules = append(r.SystemRules, rule)
+		return nil, nil
+	}
+	for i, sr := range r.SystemRules {
+		if sr == rule {
+			r.SystemRules = append(r.SystemRules[:i], r.SystemRules[i+1:]...)
+			break
+		}
+	}
+	return nil, nil
+}
+
+// AreRulesLoaded returns if the interception rules are loaded.
+func (r *Recorder) AreRulesLoaded() bool {
+	r.Lock()
+	defer r.Unlock()
+	return r.Loaded
+}
+
+// Flush deletes every rule, like an external tool flushing the firewall would do.
+func (r *Recorder) Flush() {
+	r.Lock()
+	defer r.Unlock()
+
+	r.Loaded = false
+	r.SystemChains = make(map[string]*FwRule)
+	r.SystemRules = nil
+}
+
+// Count returns how many times an operation has been performed.
+func (r *Recorder) Count(op string) int {
+	r.Lock()
+	defer r.Unlock()
+
+	n := 0
+	for _, c := range r.Calls {
+		if len(c) >= len(op) && c[:len(op)] == op {
+			n++
+		}
+	}
+	return n
+}
+
+// NumSystemRules returns the number of system rules added.
+func (r *Recorder) NumSystemRules() int {
+	r.Lock()
+	defer r.Unlock()
+	return len(r.SystemRules)
+}
@@ -1,244 +1,185 @@
 package firewall
 
 import (
-	"fmt"
-	"regexp"
-	"strings"
 	"sync"
 	"time"
 
-	"github.com/evilsocket/opensnitch/daemon/core"
+	"github.com/evilsocket/opensnitch/daemon/firewall/marks"
 	"github.com/evilsocket/opensnitch/daemon/log"
 	"github.com/fsnotify/fsnotify"
 )
 
 // DropMark is the mark we place on a connection when we deny it.
-// The connection is dropped later on OUTPUT chain.
-const DropMark = 0x18BA5
+const DropMark = marks.Drop
 
-// Action is the modifier we apply to a rule.
-type Action string
+// RejectMark is the mark we place on a connection when we reject it.
+const RejectMark = marks.Reject
 
-// Actions we apply to the firewall.
+// Supported firewall implementations.
 const (
-	ADD      = Action("-A")
-	INSERT   = Action("-I")
-	DELETE   = Action("-D")
-	FLUSH    = Action("-F")
-	NEWCHAIN = Action("-N")
-	DELCHAIN = Action("-X")
-
-	systemRulePrefix = "opensnitch-filter"
+	IPTABLES = "iptables"
+	NFTABLES = "nftables"
 )
 
-// make sure we don't mess with multiple rules
-// at the same time
-var (
-	lock = sync.Mutex{}
-
-	queueNum = 0
-	running  = false
-	// check that rules are loaded every 30s
-	rulesChecker             *time.Ticker
-	rulesCheckerChan         = make(chan bool)
-	regexRulesQuery, _       = regexp.Compile(`NFQUEUE.*ctstate NEW,RELATED.*NFQUEUE num.*bypass`)
-	regexDropQuery, _        = regexp.Compile(`DROP.*mark match 0x18ba5`)
-	regexSystemRulesQuery, _ = regexp.Compile(systemRulePrefix + ".*")
-
-	systemChains = make(map[string]*fwRule)
-)
-
-// RunRule inserts or deletes a firewall rule.
-func RunRule(action Action, enable bool, logError bool, rule []string) (err4, err6 error) {
-	if enable == false {
-		action = "-D"
-	}
-
-	rule = append([]string{string(action)}, rule...)
+// Firewall manages the rules needed to intercept connections, and the
+// system rules defined in the system-fw.json file.
+type Firewall interface {
+	// Name returns the name of the implementation in use.
+	Name() string
+	// SetType sets the implementation to use the next time it's started.
+	SetType(name string)
+	Init(qNum *int)
+	Stop()
+	IsRunning() bool
+	AreRulesLoaded() bool
+	CleanRules(logErrors bool)
+	AddSystemRules()
+	DeleteSystemRules(force, logErrors bool)
+}
 
-	lock.Lock()
-	defer lock.Unlock()
+// Backend is the interface every firewall implementation must satisfy.
+// It only modifies the system rules, the Manager takes care of the rest.
+type Backend interface {
+	Name() string
+	IsAvailable() bool
+	InsertRules(qNum int) error
+	CleanRules(qNum int, logErrors bool)
+	CreateSystemRule(rule *FwRule, logErrors bool)
+	DeleteSystemRule(rule *FwRule, force, logErrors bool)
+	AddSystemRule(rule *FwRule, enable bool) (err4, err6 error)
+	AreRulesLoaded() bool
+}
 
-	if _, err4 = core.Exec("iptables", rule); err4 != nil {
-		if logError {
-			log.Error("Error while running firewall rule, ipv4 err: %s", err4)
-			log.Error("rule: %s", rule)
-		}
-	}
+// Manager implements the Firewall interface on top of a Backend.
+// It inserts the rules, checks periodically that they're still loaded,
+// and applies the system rules defined in the configuration file.
+type Manager struct {
+	// make sure we don't start or stop the firewall
+	// at the same time
+	sync.RWMutex
+
+	backend Backend
+	// firewall implementation configured by the user,
+	// used the next time the firewall is started.
+	fwType string
+	// if true, the backend has been set by the caller and it's never replaced.
+	fixedBackend bool
+
+	queueNum int
+	running  bool
+	// check that rules are loaded every checkInterval
+	checkInterval    time.Duration
+	rulesChecker     *time.Ticker
+	rulesCheckerChan chan bool
+
+	configFile    string
+	configWatcher *fsnotify.Watcher
+	config        config
+}
 
-	if core.IPv6Enabled {
-		if _, err6 = core.Exec("ip6tables", rule); err6 != nil {
-			if logError {
-				log.Error("Error while running firewall rule, ipv6 err: %s", err6)
-				log.Error("rule: %s", rule)
-			}
-		}
+// New returns a Firewall which uses iptables by default.
+func New() *Manager {
+	return &Manager{
+		backend:          newIptables(),
+		fwType:           IPTABLES,
+		checkInterval:    30 * time.Second,
+		rulesCheckerChan: make(chan bool),
+		configFile:       configFile,
 	}
-
-	return
 }
 
-// QueueDNSResponses redirects DNS responses to us, in order to keep a cache
-// of resolved domains.
-// INPUT --protocol udp --sport 53 -j NFQUEUE --queue-num 0 --queue-bypass
-func QueueDNSResponses(enable bool, logError bool, qNum int) (err4, err6 error) {
-	return RunRule(INSERT, enable, logError, []string{
-		"INPUT",
-		"--protocol", "udp",
-		"--sport", "53",
-		"-j", "NFQUEUE",
-		"--queue-num", fmt.Sprintf("%d", qNum),
-		"--queue-bypass",
-	})
+// NewWithBackend returns a Firewall which always uses the given Backend.
+func NewWithBackend(backend Backend) *Manager {
+	m := New()
+	m.backend = backend
+	m.fwType = backend.Name()
+	m.fixedBackend = true
+	return m
 }
 
-// QueueConnections inserts the firewall rule which redirects connections to us.
-// They are queued until the user denies/accept them, or reaches a timeout.
-// OUTPUT -t mangle -m conntrack --ctstate NEW,RELATED -j NFQUEUE --queue-num 0 --queue-bypass
-func QueueConnections(enable bool, logError bool, qNum int) (err4, err6 error) {
-	return RunRule(INSERT, enable, logError, []string{
-		"OUTPUT",
-		"-t", "mangle",
-		"-m", "conntrack",
-		"--ctstate", "NEW,RELATED",
-		"-j", "NFQUEUE",
-		"--queue-num", fmt.Sprintf("%d", qNum),
-		"--queue-bypass",
-	})
+// Name returns the name of the firewall implementation in use.
+func (m *Manager) Name() string {
+	m.RLock()
+	defer m.RUnlock()
+	return m.backend.Name()
 }
 
-// DropMarked rejects packets marked by OpenSnitch.
-// OUTPUT -m mark --mark 101285 -j DROP
-func DropMarked(enable bool, logError bool) (err4, err6 error) {
-	return RunRule(ADD, enable, logError, []string{
-		"OUTPUT",
-		"-m", "mark",
-		"--mark", fmt.Sprintf("%d", DropMark),
-		"-j", "DROP",
-	})
-}
+// SetType sets the firewall implementation to use: iptables or nftables.
+// It takes effect the next time the firewall is started.
+func (m *Manager) SetType(name string) {
+	m.Lock()
+	defer m.Unlock()
 
-// CreateSystemRule create the custom firewall chains and adds them to system.
-func CreateSystemRule(rule *fwRule, logErrors bool) {
-	chainName := systemRulePrefix + "-" + rule.Chain
-	if _, ok := systemChains[rule.Table+"-"+chainName]; ok {
+	if name != IPTABLES && name != NFTABLES {
+		log.Warning("Unknown firewall %s, using %s", name, m.fwType)
 		return
 	}
-	RunRule(NEWCHAIN, true, logErrors, []string{chainName, "-t", rule.Table})
-
-	// Insert the rule at the top of the chain
-	if err4, err6 := RunRule(INSERT, true, logErrors, []string{rule.Chain, "-t", rule.Table, "-j", chainName}); err4 == nil && err6 == nil {
-		systemChains[rule.Table+"-"+chainName] = rule
-	}
-}
-
-// DeleteSystemRules deletes the system rules.
-// If force is false and the rule has not been previously added,
-// it won't try to delete the rules. Otherwise it'll try to delete them.
-func DeleteSystemRules(force, logErrors bool) {
-	for _, r := range fwConfig.SystemRules {
-		chain := systemRulePrefix + "-" + r.Rule.Chain
-		if _, ok := systemChains[r.Rule.Table+"-"+chain]; !ok && !force {
-			continue
-		}
-		RunRule(FLUSH, true, logErrors, []string{chain, "-t", r.Rule.Table})
-		RunRule(DELETE, false, logErrors, []string{r.Rule.Chain, "-t", r.Rule.Table, "-j", chain})
-		RunRule(DELCHAIN, true, logErrors, []string{chain, "-t", r.Rule.Table})
-		delete(systemChains, r.Rule.Table+"-"+chain)
+	if m.fixedBackend {
+		return
 	}
+	m.fwType = name
 }
 
-// AddSystemRule inserts a new rule.
-func AddSystemRule(action Action, rule *fwRule, enable bool) (err4, err6 error) {
-	chain := systemRulePrefix + "-" + rule.Chain
-	if rule.Table == "" {
-		rule.Table = "filter"
+// selectBackend returns the firewall implementation configured,
+// falling back to nftables if iptables is not installed.
+func (m *Manager) selectBackend() Backend {
+	if m.fixedBackend {
+		return m.backend
 	}
-	r := []string{chain, "-t", rule.Table}
-	if rule.Parameters != "" {
-		r = append(r, strings.Split(rule.Parameters, " ")...)
+	if m.fwType == NFTABLES {
+		return newNftables()
 	}
-	r = append(r, []string{"-j", rule.Target}...)
-	if rule.TargetParameters != "" {
-		r = append(r, strings.Split(rule.TargetParameters, " ")...)
+	ipt := newIptables()
+	if !ipt.IsAvailable() {
+		if nft := newNftables(); nft.IsAvailable() {
+			log.Important("iptables not found, using nftables")
+			return nft
+		}
 	}
-
-	return RunRule(action, enable, true, r)
+	return ipt
 }
 
-// AreRulesLoaded checks if the firewall rules are loaded.
-func AreRulesLoaded() bool {
-	lock.Lock()
-	defer lock.Unlock()
-
-	var outDrop6 string
-	var outMangle6 string
-
-	outDrop, err := core.Exec("iptables", []string{"-n", "-L", "OUTPUT"})
-	if err != nil {
-		return false
-	}
-	outMangle, err := core.Exec("iptables", []string{"-n", "-L", "OUTPUT", "-t", "mangle"})
-	if err != nil {
-		return false
-	}
-
-	if core.IPv6Enabled {
-		outDrop6, err = core.Exec("ip6tables", []string{"-n", "-L", "OUTPUT"})
-		if err != nil {
-			return false
-		}
-		outMangle6, err = core.Exec("ip6tables", []string{"-n", "-L", "OUTPUT", "-t", "mangle"})
-		if err != nil {
-			return false
+// AddSystemRules creates the system rules defined in the configuration.
+func (m *Manager) AddSystemRules() {
+	for _, r := range m.config.SystemRules {
+		if r.Rule == nil || r.Rule.Chain == "" {
+			continue
 		}
+		m.backend.CreateSystemRule(r.Rule, true)
+		m.backend.AddSystemRule(r.Rule, true)
 	}
+}
 
-	systemRulesLoaded := true
-	if len(systemChains) > 0 {
-		for _, rule := range systemChains {
-			if chainOut4, err4 := core.Exec("iptables", []string{"-n", "-L", rule.Chain, "-t", rule.Table}); err4 == nil {
-				if regexSystemRulesQuery.FindString(chainOut4) == "" {
-					systemRulesLoaded = false
-					break
-				}
-			}
-			if core.IPv6Enabled {
-				if chainOut6, err6 := core.Exec("ip6tables", []string{"-n", "-L", rule.Chain, "-t", rule.Table}); err6 == nil {
-					if regexSystemRulesQuery.FindString(chainOut6) == "" {
-						systemRulesLoaded = false
-						break
-					}
-				}
-			}
+// DeleteSystemRules deletes the system rules.
+// If force is false and the rule has not been previously added,
+// it won't try to delete the rules. Otherwise it'll try to delete them.
+func (m *Manager) DeleteSystemRules(force, logErrors bool) {
+	for _, r := range m.config.SystemRules {
+		if r.Rule == nil {
+			continue
 		}
+		m.backend.DeleteSystemRule(r.Rule, force, logErrors)
 	}
+}
 
-	result := regexDropQuery.FindString(outDrop) != "" &&
-		regexRulesQuery.FindString(outMangle) != "" &&
-		systemRulesLoaded
-
-	if core.IPv6Enabled {
-		result = result && regexDropQuery.FindString(outDrop6) != "" &&
-			regexRulesQuery.FindString(outMangle6) != ""
-	}
-
-	return result
+// AreRulesLoaded checks if the firewall rules are loaded.
+func (m *Manager) AreRulesLoaded() bool {
+	m.RLock()
+	defer m.RUnlock()
+	return m.backend.AreRulesLoaded()
 }
 
 // StartCheckingRules checks periodically if the rules are loaded.
 // If they're not, we insert them again.
-func StartCheckingRules() {
+func (m *Manager) StartCheckingRules() {
 	for {
 		select {
-		case <-rulesCheckerChan:
+		case <-m.rulesCheckerChan:
 			goto Exit
-		case <-rulesChecker.C:
-			if rules := AreRulesLoaded(); rules == false {
+		case <-m.rulesChecker.C:
+			if rules := m.AreRulesLoaded(); rules == false {
 				log.Important("firewall rules changed, reloading")
-				CleanRules(log.GetLogLevel() == log.DEBUG)
-				insertRules()
-				loadDiskConfiguration(true)
+				m.reloadRules()
 			}
 		}
 	}
@@ -247,77 +188,97 @@ Exit:
 	log.Info("exit checking fw rules")
 }
 
+func (m *Manager) reloadRules() {
+	m.config.Lock()
+	defer m.config.Unlock()
+
+	m.CleanRules(log.GetLogLevel() == log.DEBUG)
+	m.insertRules()
+	m.AddSystemRules()
+}
+
 // StopCheckingRules stops checking if the firewall rules are loaded.
-func StopCheckingRules() {
-	if rulesChecker != nil {
-		rulesChecker.Stop()
+func (m *Manager) StopCheckingRules() {
+	if m.rulesChecker != nil {
+		m.rulesChecker.Stop()
 	}
-	rulesCheckerChan <- true
-	if configWatcher != nil {
-		rulesCheckerChan <- true
+	m.rulesCheckerChan <- true
+	if m.configWatcher != nil {
+		m.rulesCheckerChan <- true
 	}
 }
 
 // IsRunning returns if the firewall rules are loaded or not.
-func IsRunning() bool {
-	return running
+func (m *Manager) IsRunning() bool {
+	m.RLock()
+	defer m.RUnlock()
+	return m.running
 }
 
 // CleanRules deletes the rules we added.
-func CleanRules(logErrors bool) {
-	QueueDNSResponses(false, logErrors, queueNum)
-	QueueConnections(false, logErrors, queueNum)
-	DropMarked(false, logErrors)
-	DeleteSystemRules(true, logErrors)
+func (m *Manager) CleanRules(logErrors bool) {
+	m.backend.CleanRules(m.queueNum, logErrors)
+	m.DeleteSystemRules(true, logErrors)
 }
 
-func insertRules() {
-	if err4, err6 := QueueDNSResponses(true, true, queueNum); err4 != nil || err6 != nil {
-		log.Error("Error while running DNS firewall rule: %s", err4, err6)
-	} else if err4, err6 = QueueConnections(true, true, queueNum); err4 != nil || err6 != nil {
-		log.Fatal("Error while running conntrack firewall rule: %s", err4, err6)
-	} else if err4, err6 = DropMarked(true, true); err4 != nil || err6 != nil {
-		log.Fatal("Error while running drop firewall rule: %s", err4, err6)
+func (m *Manager) insertRules() {
+	if err := m.backend.InsertRules(m.queueNum); err != nil {
+		log.Fatal("%s", err)
 	}
 }
 
 // Stop deletes the firewall rules, allowing network traffic.
-func Stop(qNum *int) {
-	if running == false {
+func (m *Manager) Stop() {
+	m.Lock()
+	if m.running == false {
+		m.Unlock()
 		return
 	}
-	if qNum != nil {
-		queueNum = *qNum
-	}
-
-	if configWatcher != nil {
-		configWatcher.Remove(configFile)
-		configWatcher.Close()
+	if m.configWatcher != nil {
+		m.configWatcher.Remove(m.configFile)
+		m.configWatcher.Close()
 	}
-	StopCheckingRules()
-	CleanRules(log.GetLogLevel() == log.DEBUG)
-
-	running = false
+	m.running = false
+	m.Unlock()
+
+	// the rules checker takes the lock to check the rules, so it's
+	// stopped without holding it.
+	m.StopCheckingRules()
+
+	m.Lock()
+	defer m.Unlock()
+	m.config.Lock()
+	m.CleanRules(log.GetLogLevel() == log.DEBUG)
+	m.config.Unlock()
 }
 
 // Init inserts the firewall rules.
-func Init(qNum *int) {
-	if running {
+func (m *Manager) Init(qNum *int) {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.running {
 		return
 	}
 	if qNum != nil {
-		queueNum = *qNum
+		m.queueNum = *qNum
+	}
+	if m.backend.Name() != m.fwType {
+		// delete residual rules of the previous implementation
+		m.backend.CleanRules(m.queueNum, false)
 	}
-	insertRules()
+	m.backend = m.selectBackend()
+	log.Info("Using %s firewall", m.backend.Name())
+	m.insertRules()
 
 	var err error
-	if configWatcher, err = fsnotify.NewWatcher(); err != nil {
-		log.Warning("Error creating firewall config watcher:", err)
+	if m.configWatcher, err = fsnotify.NewWatcher(); err != nil {
+		log.Warning("Error creating firewall config watcher: %s", err)
 	}
-	loadDiskConfiguration(false)
+	m.loadDiskConfiguration(false)
 
-	rulesChecker = time.NewTicker(time.Second * 30)
-	go StartCheckingRules()
+	m.rulesChecker = time.NewTicker(m.checkInterval)
+	go m.StartCheckingRules()
 
-	running = true
+	m.running = true
 }
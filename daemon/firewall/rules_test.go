@@ -0,0 +1,155 @@
+package firewall
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var (
+	oneSystemRule = `{"SystemRules": [
+		{"Rule": {"Description": "Allow icmp", "Table": "mangle", "Chain": "OUTPUT", "Parameters": "-p icmp", "Target": "ACCEPT"}}
+	]}`
+	twoSystemRules = `{"SystemRules": [
+		{"Rule": {"Description": "Allow icmp", "Table": "mangle", "Chain": "OUTPUT", "Parameters": "-p icmp", "Target": "ACCEPT"}},
+		{"Rule": {"Description": "Allow dns", "Table": "filter", "Chain": "OUTPUT", "Parameters": "-p udp --dport 53", "Target": "ACCEPT"}}
+	]}`
+)
+
+func newTestManager(t *testing.T, conf string) (*Manager, *Recorder) {
+	tmpDir, err := ioutil.TempDir("", "opensnitch-fw-test")
+	if err != nil {
+		t.Fatal("Error creating temp dir:", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+
+	rec := NewRecorder()
+	m := NewWithBackend(rec)
+	m.checkInterval = 10 * time.Millisecond
+	m.configFile = filepath.Join(tmpDir, "system-fw.json")
+	if err := ioutil.WriteFile(m.configFile, []byte(conf), 0644); err != nil {
+		t.Fatal("Error writing config file:", err)
+	}
+
+	return m, rec
+}
+
+// waitFor checks cond until it's true or the timeout expires.
+func waitFor(cond func() bool) bool {
+	for i := 0; i < 200; i++ {
+		if cond() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func TestInitStop(t *testing.T) {
+	m, rec := newTestManager(t, oneSystemRule)
+	qNum := 3
+	m.Init(&qNum)
+
+	if !m.IsRunning() {
+		t.Error("Firewall should be running after Init()")
+	}
+	if m.Name() != RECORDER {
+		t.Error("Firewall name should be recorder:", m.Name())
+	}
+	if !rec.AreRulesLoaded() || rec.Count("InsertRules 3") != 1 {
+		t.Error("Rules not inserted on queue 3:", rec.Calls)
+	}
+	if rec.NumSystemRules() != 1 {
+		t.Error("System rules not added:", rec.Calls)
+	}
+
+	m.Stop()
+	if m.IsRunning() {
+		t.Error("Firewall should not be running after Stop()")
+	}
+	if rec.AreRulesLoaded() || rec.NumSystemRules() != 0 {
+		t.Error("Rules not deleted after Stop():", rec.Calls)
+	}
+}
+
+func TestStartCheckingRules(t *testing.T) {
+	m, rec := newTestManager(t, oneSystemRule)
+	m.Init(nil)
+	defer m.Stop()
+
+	t.Run("Rules are not reinserted if loaded", func(t *testing.T) {
+		time.Sleep(5 * m.checkInterval)
+		if n := rec.Count("InsertRules"); n != 1 {
+			t.Error("Rules inserted more than once:", n)
+		}
+	})
+
+	t.Run("Rules are reinserted if flushed", func(t *testing.T) {
+		rec.Flush()
+		if !waitFor(rec.AreRulesLoaded) {
+			t.Fatal("Rules not reinserted:", rec.Calls)
+		}
+		if n := rec.Count("InsertRules"); n != 2 {
+			t.Error("Rules should have been inserted twice:", n)
+		}
+	})
+
+	t.Run("System rules are reinserted once", func(t *testing.T) {
+		if !waitFor(func() bool { return rec.NumSystemRules() == 1 }) {
+			t.Error("System rules not reinserted:", rec.NumSystemRules(), rec.Calls)
+		}
+	})
+}
+
+func TestSystemRulesReload(t *testing.T) {
+	m, rec := newTestManager(t, oneSystemRule)
+	m.Init(nil)
+	defer m.Stop()
+
+	if rec.NumSystemRules() != 1 {
+		t.Fatal("System rules not added:", rec.Calls)
+	}
+
+	t.Run("Rules are added on config change", func(t *testing.T) {
+		if err := ioutil.WriteFile(m.configFile, []byte(twoSystemRules), 0644); err != nil {
+			t.Fatal("Error writing config file:", err)
+		}
+		if !waitFor(func() bool { return rec.NumSystemRules() == 2 }) {
+			t.Error("System rules not reloaded:", rec.NumSystemRules(), rec.Calls)
+		}
+	})
+
+	t.Run("Old rules are deleted on config change", func(t *testing.T) {
+		if err := ioutil.WriteFile(m.configFile, []byte(oneSystemRule), 0644); err != nil {
+			t.Fatal("Error writing config file:", err)
+		}
+		if !waitFor(func() bool { return rec.NumSystemRules() == 1 && rec.Count("DeleteSystemRule filter-OUTPUT") > 0 }) {
+			t.Error("System rules not reloaded:", rec.NumSystemRules(), rec.Calls)
+		}
+	})
+
+	t.Run("Invalid config keeps the rules", func(t *testing.T) {
+		if err := ioutil.WriteFile(m.configFile, []byte("{invalid"), 0644); err != nil {
+			t.Fatal("Error writing config file:", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+		if rec.NumSystemRules() != 1 {
+			t.Error("System rules changed:", rec.NumSystemRules(), rec.Calls)
+		}
+	})
+}
+
+func TestSystemRulesDefaultTable(t *testing.T) {
+	m, rec := newTestManager(t, `{"SystemRules": [
+		{"Rule": {"Description": "Allow dns", "Chain": "OUTPUT", "Parameters": "-p udp --dport 53", "Target": "ACCEPT"}}
+	]}`)
+	m.Init(nil)
+	defer m.Stop()
+
+	// the chain is created in the default table the first time it's loaded
+	if rec.Count("CreateSystemRule filter-OUTPUT") != 1 || rec.NumSystemRules() != 1 {
+		t.Error("System rule without table not added to the default table:", rec.Calls)
+	}
+}
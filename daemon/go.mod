@@ -10,9 +10,10 @@ require (
 	github.com/google/gopacket v1.1.14
 	github.com/vishvananda/netlink v1.1.0
 	github.com/vishvananda/netns v0.0.0-20191106174202-0a2b9b5464df // indirect
+	go.etcd.io/bbolt v1.3.7
 	golang.org/x/net v0.0.0-20180417003750-8d16fa6dc9a8
 	golang.org/x/sync v0.0.0-20200625203802-6e8e738ad208 // indirect
-	golang.org/x/sys v0.0.0-20190606203320-7fc4e5ec1444 // indirect
+	golang.org/x/sys v0.10.0
 	golang.org/x/text v0.3.0 // indirect
 	google.golang.org/genproto v0.0.0-20180413175816-7fd901a49ba6 // indirect
 	google.golang.org/grpc v1.11.3
@@ -0,0 +1,279 @@
+// Package history saves the connections and their verdicts to a local
+// database, so they can be queried after the daemon has been restarted.
+package history
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/evilsocket/opensnitch/daemon/conman"
+	"github.com/evilsocket/opensnitch/daemon/log"
+	"github.com/evilsocket/opensnitch/daemon/rule"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// max number of records waiting to be written, connections beyond it
+	// are not recorded.
+	maxPending = 4096
+	// max number of records written in the same transaction.
+	maxBatch = 256
+	// how often the records older than the retention are deleted.
+	expireInterval = time.Hour
+)
+
+var (
+	bucketConnections = []byte("connections")
+	bucketByProcess   = []byte("by_process")
+	bucketByHost      = []byte("by_host")
+	bucketByRule      = []byte("by_rule")
+)
+
+// DB is an append-only log of connections. Records are never modified, only
+// deleted once they're older than the retention.
+//
+// The records are saved by time, and indexed by process path, destination
+// (host and IP) and rule name, so they can be queried by any of them without
+// reading the whole history.
+type DB struct {
+	db        *bolt.DB
+	retention time.Duration
+	pending   chan *Record
+	dropped   uint64
+	quit      chan bool
+	done      chan bool
+}
+
+// Open opens the history saved at path, creating it if it doesn't exist.
+// Records older than retention are deleted, 0 to keep them forever.
+func Open(path string, retention time.Duration) (*DB, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("Error opening connections history %s: %s", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, b := range [][]byte{bucketConnections, bucketByProcess, bucketByHost, bucketByRule} {
+			if _, err := tx.CreateBucketIfNotExists(b); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("Error initializing connections history %s: %s", path, err)
+	}
+
+	h := &DB{
+		db:        db,
+		retention: retention,
+		pending:   make(chan *Record, maxPending),
+		quit:      make(chan bool),
+		done:      make(chan bool),
+	}
+	go h.worker()
+	return h, nil
+}
+
+// Close writes the records pending and closes the database.
+func (h *DB) Close() {
+	close(h.quit)
+	<-h.done
+	h.db.Close()
+}
+
+// OnConnectionEvent records a connection, the rule matched (nil if none) and
+// the action applied to it. It never blocks: if the database can't keep up,
+// the connection is not recorded.
+func (h *DB) OnConnectionEvent(con *conman.Connection, match *rule.Rule, action rule.Action) {
+	select {
+	case h.pending <- NewRecord(con, match, action):
+	default:
+		atomic.AddUint64(&h.dropped, 1)
+	}
+}
+
+func (h *DB) worker() {
+	expire := time.NewTicker(expireInterval)
+	defer expire.Stop()
+	h.expire()
+
+	for {
+		select {
+		case <-h.quit:
+			// write what's left before exiting
+			for len(h.pending) > 0 {
+				h.writeBatch(<-h.pending)
+			}
+			close(h.done)
+			return
+		case <-expire.C:
+			h.expire()
+		case r := <-h.pending:
+			h.writeBatch(r)
+		}
+	}
+}
+
+// writeBatch writes the record, and the ones queued after it, in a single
+// transaction.
+func (h *DB) writeBatch(first *Record) {
+	batch := []*Record{first}
+	for len(batch) < maxBatch && len(h.pending) > 0 {
+		batch = append(batch, <-h.pending)
+	}
+
+	if err := h.write(batch...); err != nil {
+		log.Error("Error saving %d connections to the history: %s", len(batch), err)
+	}
+	if dropped := atomic.SwapUint64(&h.dropped, 0); dropped > 0 {
+		log.Warning("%d connections not saved to the history, too many connections", dropped)
+	}
+}
+
+func (h *DB) write(records ...*Record) error {
+	return h.db.Update(func(tx *bolt.Tx) error {
+		cons := tx.Bucket(bucketConnections)
+		for _, r := range records {
+			seq, err := cons.NextSequence()
+			if err != nil {
+				return err
+			}
+			raw, err := json.Marshal(r)
+			if err != nil {
+				return err
+			}
+			key := timeKey(r.Time.UnixNano(), seq)
+			if err := cons.Put(key, raw); err != nil {
+				return err
+			}
+			if err := putIndexes(tx, r, key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// expire deletes the records older than the retention, a few at a time, so
+// the database isn't locked for too long.
+func (h *DB) expire() {
+	if h.retention <= 0 {
+		return
+	}
+	cutoff := timeKey(time.Now().Add(-h.retention).UnixNano(), 0)
+	total := 0
+	for {
+		deleted := 0
+		err := h.db.Update(func(tx *bolt.Tx) error {
+			c := tx.Bucket(bucketConnections).Cursor()
+			for k, v := c.First(); k != nil && bytes.Compare(k, cutoff) < 0 && deleted < maxBatch; k, v = c.Next() {
+				var r Record
+				if err := json.Unmarshal(v, &r); err == nil {
+					deleteIndexes(tx, &r, k)
+				}
+				if err := c.Delete(); err != nil {
+					return err
+				}
+				deleted++
+			}
+			return nil
+		})
+		total += deleted
+		if err != nil {
+			log.Error("Error deleting old connections from the history: %s", err)
+			break
+		}
+		if deleted < maxBatch {
+			break
+		}
+	}
+	if total > 0 {
+		log.Debug("%d connections older than %s deleted from the history", total, h.retention)
+	}
+}
+
+// timeKey returns the key of a record: its time and a sequence number, so
+// records created at the same time don't collide, and are kept in order.
+func timeKey(nsec int64, seq uint64) []byte {
+	if nsec < 0 {
+		nsec = 0
+	}
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint64(key[:8], uint64(nsec))
+	binary.BigEndian.PutUint64(key[8:], seq)
+	return key
+}
+
+// indexKey returns the key of a record in an index: the value indexed,
+// followed by the key of the record.
+func indexKey(value string, key []byte) []byte {
+	return append(indexPrefix(value), key...)
+}
+
+func indexPrefix(value string) []byte {
+	return append([]byte(value), 0)
+}
+
+func putIndexes(tx *bolt.Tx, r *Record, key []byte) error {
+	if err := tx.Bucket(bucketByProcess).Put(indexKey(r.Process, key), nil); err != nil {
+		return err
+	}
+	for _, host := range r.hosts() {
+		if err := tx.Bucket(bucketByHost).Put(indexKey(host, key), nil); err != nil {
+			return err
+		}
+	}
+	if r.Rule != "" {
+		return tx.Bucket(bucketByRule).Put(indexKey(r.Rule, key), nil)
+	}
+	return nil
+}
+
+func deleteIndexes(tx *bolt.Tx, r *Record, key []byte) {
+	tx.Bucket(bucketByProcess).Delete(indexKey(r.Process, key))
+	for _, host := range r.hosts() {
+		tx.Bucket(bucketByHost).Delete(indexKey(host, key))
+	}
+	if r.Rule != "" {
+		tx.Bucket(bucketByRule).Delete(indexKey(r.Rule, key))
+	}
+}
+
+// prevKey returns the key just before the given one, nil if there's none.
+func prevKey(key []byte) []byte {
+	prev := append([]byte{}, key...)
+	for i := len(prev) - 1; i >= 0; i-- {
+		if prev[i] > 0 {
+			prev[i]--
+			return prev
+		}
+		prev[i] = 0xff
+	}
+	return nil
+}
+
+// scanBackwards calls fn with the keys of the records between start and end,
+// both included, newest first, until it returns false. With a prefix, the
+// keys are read from an index.
+func scanBackwards(c *bolt.Cursor, prefix, start, end []byte, fn func(key []byte) bool) {
+	start = append(append([]byte{}, prefix...), start...)
+	end = append(append([]byte{}, prefix...), end...)
+
+	k, _ := c.Seek(end)
+	if k == nil {
+		k, _ = c.Last()
+	}
+	for ; k != nil && bytes.Compare(k, end) > 0; k, _ = c.Prev() {
+	}
+	for ; k != nil && bytes.Compare(k, start) >= 0; k, _ = c.Prev() {
+		if fn(k[len(prefix):]) == false {
+			return
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package history
+
+import (
+	"io/ioutil"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/evilsocket/opensnitch/daemon/conman"
+	"github.com/evilsocket/opensnitch/daemon/rule"
+)
+
+func openTestDB(t *testing.T, retention time.Duration) (*DB, func()) {
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := Open(filepath.Join(dir, "history.db"), retention)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return h, func() {
+		h.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func testRecords(now time.Time) []*Record {
+	return []*Record{
+		{Time: now.Add(-72 * time.Hour), Action: "allow", Rule: "allow-curl", Process: "/usr/bin/curl", DstIP: "1.1.1.1", DstHost: "one.one.one.one"},
+		{Time: now.Add(-48 * time.Hour), Action: "deny", Rule: "deny-wget", Process: "/usr/bin/wget", DstIP: "8.8.8.8"},
+		{Time: now.Add(-24 * time.Hour), Action: "allow", Rule: "allow-curl", Process: "/usr/bin/curl", DstIP: "8.8.8.8", DstHost: "dns.google"},
+		{Time: now.Add(-1 * time.Hour), Action: "allow", Process: "/usr/bin/curl", DstIP: "9.9.9.9"},
+	}
+}
+
+func TestHistoryFind(t *testing.T) {
+	h, cleanup := openTestDB(t, 0)
+	defer cleanup()
+
+	now := time.Now()
+	if err := h.write(testRecords(now)...); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		query Query
+		hosts []string
+	}{
+		{"all", Query{}, []string{"9.9.9.9", "8.8.8.8", "8.8.8.8", "1.1.1.1"}},
+		{"process", Query{Process: "/usr/bin/curl"}, []string{"9.9.9.9", "8.8.8.8", "1.1.1.1"}},
+		{"host name", Query{Host: "dns.google"}, []string{"8.8.8.8"}},
+		{"host ip", Query{Host: "8.8.8.8"}, []string{"8.8.8.8", "8.8.8.8"}},
+		{"rule", Query{Rule: "allow-curl"}, []string{"8.8.8.8", "1.1.1.1"}},
+		{"process and host", Query{Process: "/usr/bin/wget", Host: "8.8.8.8"}, []string{"8.8.8.8"}},
+		{"from", Query{From: now.Add(-36 * time.Hour)}, []string{"9.9.9.9", "8.8.8.8"}},
+		{"time range", Query{Process: "/usr/bin/curl", From: now.Add(-96 * time.Hour), To: now.Add(-12 * time.Hour)}, []string{"8.8.8.8", "1.1.1.1"}},
+		{"limit", Query{Limit: 2}, []string{"9.9.9.9", "8.8.8.8"}},
+		{"no match", Query{Process: "/usr/bin/nc"}, []string{}},
+	}
+	for _, test := range tests {
+		records, err := h.Find(test.query)
+		if err != nil {
+			t.Fatalf("%s: %s", test.name, err)
+		}
+		if len(records) != len(test.hosts) {
+			t.Errorf("%s: expected %d records, got %d", test.name, len(test.hosts), len(records))
+			continue
+		}
+		for i, r := range records {
+			if r.DstIP != test.hosts[i] {
+				t.Errorf("%s: record %d expected to %s, got %s", test.name, i, test.hosts[i], r.DstIP)
+			}
+		}
+	}
+}
+
+func TestHistoryExpire(t *testing.T) {
+	h, cleanup := openTestDB(t, 30*time.Hour)
+	defer cleanup()
+
+	if err := h.write(testRecords(time.Now())...); err != nil {
+		t.Fatal(err)
+	}
+	h.expire()
+
+	records, err := h.Find(Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records after expiring, got %d", len(records))
+	}
+	// the indexes of the expired records must be deleted too
+	if records, _ := h.Find(Query{Rule: "deny-wget"}); len(records) != 0 {
+		t.Errorf("expected no records of an expired rule, got %d", len(records))
+	}
+}
+
+func TestHistoryAsyncWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "history.db")
+
+	h, err := Open(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range testRecords(time.Now()) {
+		h.pending <- r
+	}
+	// the records still pending are written on Close
+	h.Close()
+
+	if h, err = Open(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	records, err := h.Find(Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 4 {
+		t.Errorf("expected 4 records written, got %d", len(records))
+	}
+}
+
+func TestHistoryPages(t *testing.T) {
+	h, cleanup := openTestDB(t, 0)
+	defer cleanup()
+
+	now := time.Now()
+	records := make([]*Record, 0, 2*pageSize+10)
+	for i := 0; i < cap(records); i++ {
+		// some records at the same time, so a page can end between them
+		records = append(records, &Record{Time: now.Add(time.Duration(i/2) * time.Second), Action: "allow", Process: "/usr/bin/curl", DstIP: "1.1.1.1"})
+	}
+	if err := h.write(records...); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query    Query
+		expected int
+	}{
+		// a record older than the rest is written while reading the first one
+		{Query{}, len(records) + 1},
+		{Query{Process: "/usr/bin/curl"}, len(records)},
+		{Query{Limit: pageSize + 1}, pageSize + 1},
+	}
+	for i, test := range tests {
+		var prev *Record
+		found := 0
+		err := h.Each(test.query, func(r *Record) bool {
+			if prev != nil && r.Time.After(prev.Time) {
+				t.Errorf("%+v: records not sorted, %s after %s", test.query, r.Time, prev.Time)
+			}
+			prev = r
+			found++
+			// the database can be written while the records are sent
+			if i == 0 && found == 1 {
+				if err := h.write(&Record{Time: now.Add(-time.Hour), Action: "deny", Process: "/usr/bin/wget", DstIP: "8.8.8.8"}); err != nil {
+					t.Errorf("%+v: error writing while reading: %s", test.query, err)
+				}
+			}
+			return true
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found != test.expected {
+			t.Errorf("%+v: expected %d records, got %d", test.query, test.expected, found)
+		}
+	}
+
+	// a page reads pageSize records at most, even if none of them match
+	from, to := (&Query{}).timeRange()
+	page, last, err := h.readPage(Query{Process: "/usr/bin/curl", Host: "9.9.9.9"}, timeKey(from, 0), timeKey(to, math.MaxUint64), pageSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 0 || last == nil {
+		t.Errorf("Unexpected page of a query without matches: %d records, last %v", len(page), last)
+	}
+	if err := h.write(&Record{Time: now.Add(-2 * time.Hour), Action: "allow", Process: "/usr/bin/curl", DstIP: "9.9.9.9"}); err != nil {
+		t.Fatal(err)
+	}
+	if found, err := h.Find(Query{Process: "/usr/bin/curl", Host: "9.9.9.9"}); err != nil || len(found) != 1 {
+		t.Errorf("Record not found after pages without matches: %d, %v", len(found), err)
+	}
+}
+
+func TestNewRecordAction(t *testing.T) {
+	con := &conman.Connection{Protocol: "tcp", SrcIP: net.ParseIP("127.0.0.1"), DstIP: net.ParseIP("1.1.1.1")}
+	r := NewRecord(con, nil, rule.Deny)
+	if r.Action != string(rule.Deny) || r.Rule != "" {
+		t.Error("Default action not recorded:", r.Action, r.Rule)
+	}
+	if ev := r.Serialize(); ev.Rule.Action != string(rule.Deny) {
+		t.Error("Default action not serialized:", ev.Rule)
+	}
+	// the uid and pid are unknown
+	if ev := r.Serialize(); ev.Connection.UserId != 0 || ev.Connection.ProcessId != 0 {
+		t.Error("Unknown uid and pid not serialized as 0:", ev.Connection.UserId, ev.Connection.ProcessId)
+	}
+}
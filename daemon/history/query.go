@@ -0,0 +1,160 @@
+package history
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// max number of records read in the same transaction, matching the query
+// or not.
+const pageSize = 256
+
+// Query selects the records of the history. Empty fields match any record.
+type Query struct {
+	// path of the process
+	Process string
+	// host name or IP of the destination
+	Host string
+	// name of the rule applied
+	Rule string
+	// time range of the connections
+	From time.Time
+	To   time.Time
+	// max number of records returned, 0 for no limit
+	Limit int
+}
+
+func (q *Query) match(r *Record) bool {
+	if q.Process != "" && r.Process != q.Process {
+		return false
+	}
+	if q.Host != "" && r.DstHost != q.Host && r.DstIP != q.Host {
+		return false
+	}
+	if q.Rule != "" && r.Rule != q.Rule {
+		return false
+	}
+	return true
+}
+
+// index returns the index to read the records from, and the value to look
+// for in it. The process is the most selective, then the rule, then the host.
+func (q *Query) index() ([]byte, string) {
+	switch {
+	case q.Process != "":
+		return bucketByProcess, q.Process
+	case q.Rule != "":
+		return bucketByRule, q.Rule
+	case q.Host != "":
+		return bucketByHost, q.Host
+	}
+	return nil, ""
+}
+
+func (q *Query) timeRange() (from, to int64) {
+	from, to = 0, math.MaxInt64
+	if !q.From.IsZero() {
+		from = q.From.UnixNano()
+	}
+	if !q.To.IsZero() {
+		to = q.To.UnixNano()
+	}
+	return from, to
+}
+
+// Find returns the records matching the query, newest first.
+func (h *DB) Find(q Query) ([]*Record, error) {
+	records := make([]*Record, 0)
+	err := h.Each(q, func(r *Record) bool {
+		records = append(records, r)
+		return true
+	})
+	return records, err
+}
+
+// Each calls fn with the records matching the query, newest first, until
+// it returns false.
+//
+// The records are read a page at a time, and fn is called once the
+// transaction of the page is closed, so a slow fn (sending the records to a
+// client) doesn't keep the database from being written meanwhile.
+func (h *DB) Each(q Query, fn func(r *Record) bool) error {
+	from, to := q.timeRange()
+	start, end := timeKey(from, 0), timeKey(to, math.MaxUint64)
+
+	found := 0
+	for end != nil {
+		max := pageSize
+		if q.Limit > 0 && q.Limit-found < max {
+			max = q.Limit - found
+		}
+		page, last, err := h.readPage(q, start, end, max)
+		if err != nil {
+			return err
+		}
+		for _, r := range page {
+			found++
+			if !fn(r) || (q.Limit > 0 && found >= q.Limit) {
+				return nil
+			}
+		}
+		if last == nil {
+			return nil
+		}
+		end = prevKey(last)
+	}
+	return nil
+}
+
+// readPage returns up to max records matching the query between the keys
+// start and end, newest first, and the key of the last one read if there may
+// be more records left. At most pageSize keys are read, so a query matching
+// few records doesn't read the whole history in the same transaction.
+func (h *DB) readPage(q Query, start, end []byte, max int) (page []*Record, last []byte, err error) {
+	index, value := q.index()
+
+	err = h.db.View(func(tx *bolt.Tx) error {
+		cons := tx.Bucket(bucketConnections)
+		var c *bolt.Cursor
+		var prefix []byte
+		if index != nil {
+			c = tx.Bucket(index).Cursor()
+			prefix = indexPrefix(value)
+		} else {
+			c = cons.Cursor()
+		}
+
+		var err error
+		scanned := 0
+		scanBackwards(c, prefix, start, end, func(key []byte) bool {
+			scanned++
+			if scanned > pageSize {
+				return false
+			}
+			// the keys are only valid during the transaction
+			last = append(last[:0], key...)
+
+			raw := cons.Get(key)
+			if raw == nil {
+				return true
+			}
+			r := &Record{}
+			if err = json.Unmarshal(raw, r); err != nil {
+				return false
+			}
+			if q.match(r) {
+				page = append(page, r)
+			}
+			return len(page) < max
+		})
+		// all the records left have been read
+		if scanned <= pageSize && len(page) < max {
+			last = nil
+		}
+		return err
+	})
+	return page, last, err
+}
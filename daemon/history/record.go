@@ -0,0 +1,103 @@
+package history
+
+import (
+	"time"
+
+	"github.com/evilsocket/opensnitch/daemon/conman"
+	"github.com/evilsocket/opensnitch/daemon/rule"
+	"github.com/evilsocket/opensnitch/daemon/ui/protocol"
+)
+
+// Record is a connection saved to the history, with the verdict applied.
+// Rule is empty if no rule matched (the GUI didn't reply), and Action is then
+// the default action. If the rule matched is disabled, Action is also the
+// default action.
+type Record struct {
+	Time      time.Time         `json:"time"`
+	Action    string            `json:"action"`
+	Rule      string            `json:"rule,omitempty"`
+	Protocol  string            `json:"protocol"`
+	SrcIP     string            `json:"src_ip"`
+	SrcPort   uint              `json:"src_port"`
+	DstIP     string            `json:"dst_ip"`
+	DstHost   string            `json:"dst_host,omitempty"`
+	DstPort   uint              `json:"dst_port"`
+	UserID    int               `json:"user_id"`
+	PID       int               `json:"pid"`
+	Process   string            `json:"process"`
+	Args      []string          `json:"args,omitempty"`
+	Checksums map[string]string `json:"checksums,omitempty"`
+}
+
+// NewRecord creates the record of a connection, the rule matched, if any, and
+// the action applied to it.
+func NewRecord(con *conman.Connection, match *rule.Rule, action rule.Action) *Record {
+	r := &Record{
+		Time:     time.Now(),
+		Action:   string(action),
+		Protocol: con.Protocol,
+		SrcIP:    con.SrcIP.String(),
+		SrcPort:  con.SrcPort,
+		DstIP:    con.DstIP.String(),
+		DstHost:  con.DstHost,
+		DstPort:  con.DstPort,
+		UserID:   -1,
+		PID:      -1,
+	}
+	if con.Entry != nil {
+		r.UserID = con.Entry.UserId
+	}
+	if con.Process != nil {
+		r.PID = con.Process.ID
+		r.Process = con.Process.Path
+		r.Args = con.Process.Args
+		r.Checksums = con.Process.Checksums
+	}
+	if match != nil {
+		r.Rule = match.Name
+	}
+	return r
+}
+
+// hosts returns the destinations the record is indexed by: the host name and
+// the IP, so it can be found by any of them.
+func (r *Record) hosts() []string {
+	if r.DstHost == "" || r.DstHost == r.DstIP {
+		return []string{r.DstIP}
+	}
+	return []string{r.DstHost, r.DstIP}
+}
+
+// protoID returns the uid or pid of a record as sent to the clients: 0 if it's
+// unknown (-1), as the connections of unknown processes.
+func protoID(id int) uint32 {
+	if id < 0 {
+		return 0
+	}
+	return uint32(id)
+}
+
+// Serialize returns the record as a connection event.
+func (r *Record) Serialize() *protocol.Event {
+	return &protocol.Event{
+		Time: r.Time.Format("2006-01-02 15:04:05"),
+		Connection: &protocol.Connection{
+			Protocol:         r.Protocol,
+			SrcIp:            r.SrcIP,
+			SrcPort:          uint32(r.SrcPort),
+			DstIp:            r.DstIP,
+			DstHost:          r.DstHost,
+			DstPort:          uint32(r.DstPort),
+			UserId:           protoID(r.UserID),
+			ProcessId:        protoID(r.PID),
+			ProcessPath:      r.Process,
+			ProcessArgs:      r.Args,
+			ProcessChecksums: r.Checksums,
+		},
+		Rule: &protocol.Rule{
+			Name:   r.Rule,
+			Action: r.Action,
+		},
+		Unixnano: r.Time.UnixNano(),
+	}
+}
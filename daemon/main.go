@@ -12,15 +12,20 @@ import (
 	"runtime/pprof"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/evilsocket/opensnitch/daemon/conman"
+	"github.com/evilsocket/opensnitch/daemon/conntrack"
 	"github.com/evilsocket/opensnitch/daemon/core"
 	"github.com/evilsocket/opensnitch/daemon/dns"
 	"github.com/evilsocket/opensnitch/daemon/firewall"
+	"github.com/evilsocket/opensnitch/daemon/history"
 	"github.com/evilsocket/opensnitch/daemon/log"
+	"github.com/evilsocket/opensnitch/daemon/metrics"
 	"github.com/evilsocket/opensnitch/daemon/netfilter"
 	"github.com/evilsocket/opensnitch/daemon/procmon"
 	"github.com/evilsocket/opensnitch/daemon/rule"
+	"github.com/evilsocket/opensnitch/daemon/sink"
 	"github.com/evilsocket/opensnitch/daemon/statistics"
 	"github.com/evilsocket/opensnitch/daemon/ui"
 )
@@ -28,6 +33,7 @@ import (
 var (
 	lock          sync.RWMutex
 	procmonMethod = ""
+	fwType        = ""
 	logFile       = ""
 	rulesPath     = "rules"
 	noLiveReload  = false
@@ -40,6 +46,27 @@ var (
 
 	uiSocket = ""
 	uiClient = (*ui.Client)(nil)
+	fw       = (firewall.Firewall)(nil)
+
+	usersRulesPath = ""
+	usersSocket    = "unix:///run/user/%d/opensnitch/osui.sock"
+	users          = (*ui.Users)(nil)
+
+	serviceSocket = ""
+	serviceCACert = ""
+	serviceCert   = ""
+	serviceKey    = ""
+	service       = (*ui.Service)(nil)
+
+	historyPath      = ""
+	historyRetention = 30 * 24 * time.Hour
+	hist             = (*history.DB)(nil)
+
+	metricsAddress = ""
+	metricsServer  = (*metrics.Server)(nil)
+
+	trafficAccounting = false
+	tracker           = (*conntrack.Tracker)(nil)
 
 	cpuProfile = ""
 	memProfile = ""
@@ -58,8 +85,19 @@ var (
 
 func init() {
 	flag.StringVar(&procmonMethod, "process-monitor-method", procmonMethod, "How to search for processes path. Options: ftrace, audit (experimental), proc (default)")
+	flag.StringVar(&fwType, "firewall", fwType, "Firewall to use: iptables (default), nftables")
 	flag.StringVar(&uiSocket, "ui-socket", uiSocket, "Path the UI gRPC service listener (https://github.com/grpc/grpc/blob/master/doc/naming.md).")
 	flag.StringVar(&rulesPath, "rules-path", rulesPath, "Path to load JSON rules from.")
+	flag.StringVar(&usersRulesPath, "users-rules-path", usersRulesPath, "Path to save the rules of each user to, in a directory named after their uid. If set, every user can have their own rules and GUI.")
+	flag.StringVar(&usersSocket, "users-ui-socket", usersSocket, "Path the GUI of each user listens on, %d is replaced by the uid of the user. Only unix sockets are allowed.")
+	flag.StringVar(&serviceSocket, "service-socket", serviceSocket, "Address the daemon gRPC service listens on, to manage it without a GUI (unix:///path/to/socket, or host:port with -service-ca, -service-cert and -service-key). Disabled by default.")
+	flag.StringVar(&serviceCACert, "service-ca", serviceCACert, "CA the certificates of the clients of a TCP -service-socket must be signed by.")
+	flag.StringVar(&serviceCert, "service-cert", serviceCert, "Certificate of a TCP -service-socket.")
+	flag.StringVar(&serviceKey, "service-key", serviceKey, "Key of the certificate of a TCP -service-socket.")
+	flag.StringVar(&historyPath, "history-path", historyPath, "File to save the connections history to, to query it with the daemon service. Disabled by default.")
+	flag.DurationVar(&historyRetention, "history-retention", historyRetention, "How long the connections are kept in the history, 0 to keep them forever.")
+	flag.StringVar(&metricsAddress, "metrics-address", metricsAddress, "Address to serve the Prometheus metrics on /metrics (127.0.0.1:9101, or unix:///path/to/socket). Disabled by default.")
+	flag.BoolVar(&trafficAccounting, "traffic-accounting", trafficAccounting, "Account the bytes and duration of the connections allowed, by process and rule, with the conntrack events.")
 	flag.IntVar(&queueNum, "queue-num", queueNum, "Netfilter queue number.")
 	flag.IntVar(&workers, "workers", workers, "Number of concurrent workers.")
 	flag.BoolVar(&noLiveReload, "no-live-reload", debug, "Disable rules live reloading.")
@@ -95,7 +133,7 @@ func setupLogging() {
 	if logFile != "" {
 		log.Close()
 		if err := log.OpenFile(logFile); err != nil {
-			log.Error("Error opening user defined log: ", logFile, err)
+			log.Error("Error opening user defined log: %s %s", logFile, err)
 		}
 	}
 }
@@ -125,10 +163,12 @@ func worker(id int) {
 		default:
 			pkt, ok := <-wrkChan
 			if !ok {
-				log.Debug("worker channel closed", id)
+				log.Debug("worker channel closed %d", id)
 				goto Exit
 			}
+			done := metrics.WorkerBusy()
 			onPacket(pkt)
+			done()
 		}
 	}
 Exit:
@@ -139,6 +179,7 @@ func setupWorkers() {
 	log.Debug("Starting %d workers ...", workers)
 	// setup the workers
 	wrkChan = make(chan netfilter.Packet)
+	metrics.SetWorkers(workers)
 	for i := 0; i < workers; i++ {
 		go worker(i)
 	}
@@ -146,9 +187,25 @@ func setupWorkers() {
 
 func doCleanup(queue *netfilter.Queue) {
 	log.Info("Cleaning up ...")
-	firewall.Stop(&queueNum)
+	fw.Stop()
 	procmon.End()
+	if service != nil {
+		service.Stop()
+	}
+	if metricsServer != nil {
+		metricsServer.Stop()
+	}
 	uiClient.Close()
+	if users != nil {
+		users.Close()
+	}
+	if hist != nil {
+		hist.Close()
+	}
+	if tracker != nil {
+		tracker.Close()
+	}
+	sink.Close()
 	queue.Close()
 
 	if cpuProfile != "" {
@@ -189,15 +246,32 @@ func onPacket(packet netfilter.Packet) {
 		return
 	}
 
+	var session *ui.UserSession
+	if users != nil {
+		session = users.Get(con.Entry.UserId)
+	}
+
 	// search a match in preloaded rules
-	r := acceptOrDeny(&packet, con)
+	r, action := acceptOrDeny(&packet, con, session)
 
 	stats.OnConnectionEvent(con, r, r == nil)
+	if hist != nil {
+		hist.OnConnectionEvent(con, r, action)
+	}
+	sink.OnConnectionEvent(con, r, action)
+	if tracker != nil {
+		tracker.OnConnectionEvent(con, r, r == nil)
+	}
+	if session != nil {
+		session.Stats.OnConnectionEvent(con, r, r == nil)
+	}
 }
 
 func applyDefaultAction(packet *netfilter.Packet) {
 	if uiClient.DefaultAction() == rule.Allow {
 		packet.SetVerdict(netfilter.NF_ACCEPT)
+	} else if uiClient.DefaultAction() == rule.Reject {
+		packet.SetVerdictAndMark(netfilter.NF_ACCEPT, firewall.RejectMark)
 	} else {
 		if uiClient.DefaultDuration() == rule.Always {
 			packet.SetVerdictAndMark(netfilter.NF_DROP, firewall.DropMark)
@@ -207,20 +281,49 @@ func applyDefaultAction(packet *netfilter.Packet) {
 	}
 }
 
-func acceptOrDeny(packet *netfilter.Packet, con *conman.Connection) *rule.Rule {
+// acceptOrDeny applies the verdict of the first rule that matches the connection.
+// The system rules are evaluated first, then the rules of the user of the
+// connection, if any. If there's no match, the GUI of the user is asked,
+// falling back to the GUI of the system if it's not running.
+// It returns the rule matched, nil if none, and the action applied, which is
+// the default action if there's no match, or the rule is disabled.
+func acceptOrDeny(packet *netfilter.Packet, con *conman.Connection, session *ui.UserSession) (*rule.Rule, rule.Action) {
 	lock.Lock()
 	defer lock.Unlock()
 
 	connected := false
-	r := rules.FindFirstMatch(con)
+	ruleSet := rules
+	r, exceeded := rules.FindMatchWithLimits(con)
+	if r == nil && session != nil {
+		if r, exceeded = session.Rules.FindMatchWithLimits(con); r != nil {
+			ruleSet = session.Rules
+		}
+	}
+	if exceeded != nil {
+		log.Warning("%s exceeded the limit of the rule %s: %s", con.Process.Path, exceeded.Name, exceeded.Limit)
+		if exceeded.Limit.Exceeded == rule.Ask {
+			r = nil
+		} else {
+			r = rule.Create(exceeded.Name, true, false, exceeded.Limit.Exceeded, rule.Once, &exceeded.Operator)
+		}
+	}
 	if r == nil {
 		// no rule matched, send a request to the
 		// UI client if connected and running
-		r, connected = uiClient.Ask(con)
+		client := uiClient
+		if session != nil && session.Client.Connected() {
+			client = session.Client
+			ruleSet = session.Rules
+		}
+		askStart := time.Now()
+		r, connected = client.Ask(con)
+		if connected {
+			metrics.ObservePrompt(time.Since(askStart))
+		}
 		if r == nil {
 			log.Error("Invalid rule received, applying default action")
 			applyDefaultAction(packet)
-			return nil
+			return nil, uiClient.DefaultAction()
 		}
 		if connected {
 			ok := false
@@ -236,7 +339,7 @@ func acceptOrDeny(packet *netfilter.Packet, con *conman.Connection) *rule.Rule {
 			if r.Duration == rule.Always {
 				pers = "Saved"
 				// add to the loaded rules and persist on disk
-				if err := rules.Add(r, true); err != nil {
+				if err := ruleSet.Add(r, true); err != nil {
 					log.Error("Error while saving rule: %s", err)
 				} else {
 					ok = true
@@ -244,7 +347,7 @@ func acceptOrDeny(packet *netfilter.Packet, con *conman.Connection) *rule.Rule {
 			} else {
 				pers = "Added"
 				// add to the rules but do not save to disk
-				if err := rules.Add(r, false); err != nil {
+				if err := ruleSet.Add(r, false); err != nil {
 					log.Error("Error while adding rule: %s", err)
 				} else {
 					ok = true
@@ -257,7 +360,9 @@ func acceptOrDeny(packet *netfilter.Packet, con *conman.Connection) *rule.Rule {
 		}
 	}
 
+	action := r.Action
 	if r.Enabled == false {
+		action = uiClient.DefaultAction()
 		applyDefaultAction(packet)
 		ruleName := log.Green(r.Name)
 		log.Info("DISABLED (%s) %s %s -> %s:%d (%s)", uiClient.DefaultAction(), log.Bold(log.Green("✔")), log.Bold(con.Process.Path), log.Bold(con.To()), con.DstPort, ruleName)
@@ -272,7 +377,35 @@ func acceptOrDeny(packet *netfilter.Packet, con *conman.Connection) *rule.Rule {
 			ruleName = log.Dim(r.Name)
 		}
 		log.Debug("%s %s -> %s:%d (%s)", log.Bold(log.Green("✔")), log.Bold(con.Process.Path), log.Bold(con.To()), con.DstPort, ruleName)
+	} else if r.Action == rule.Requeue && r.Redirect != nil {
+		if packet != nil {
+			if int(r.Redirect.Queue) == queueNum {
+				log.Warning("Rule %s requeues connections to our own queue %d, applying default action", r.Name, queueNum)
+				action = uiClient.DefaultAction()
+				applyDefaultAction(packet)
+			} else {
+				packet.SetRequeueVerdict(r.Redirect.Queue)
+			}
+		}
+
+		log.Debug("%s %s -> %s:%d (%s, queue %d)", log.Bold(log.Blue("↪")), log.Bold(con.Process.Path), log.Bold(con.To()), con.DstPort, r.Name, r.Redirect.Queue)
+	} else if r.Action == rule.Mark && r.Redirect != nil {
+		if packet != nil {
+			packet.SetVerdictAndMark(netfilter.NF_ACCEPT, r.Redirect.Mark)
+		}
+
+		log.Debug("%s %s -> %s:%d (%s, mark 0x%x)", log.Bold(log.Green("✔")), log.Bold(con.Process.Path), log.Bold(con.To()), con.DstPort, log.Green(r.Name), r.Redirect.Mark)
+	} else if r.Action == rule.Reject {
+		// accept the packet and let the firewall reject it, replying to the
+		// application with a TCP RST or an ICMP port unreachable.
+		if packet != nil {
+			packet.SetVerdictAndMark(netfilter.NF_ACCEPT, firewall.RejectMark)
+		}
+
+		log.Debug("%s %s -> %s:%d (%s)", log.Bold(log.Red("✘")), log.Bold(con.Process.Path), log.Bold(con.To()), con.DstPort, log.Red(r.Name))
 	} else {
+		// deny, or requeue and mark rules without their parameters
+		action = rule.Deny
 		if packet != nil {
 			packet.SetVerdictAndMark(netfilter.NF_DROP, firewall.DropMark)
 		}
@@ -280,7 +413,7 @@ func acceptOrDeny(packet *netfilter.Packet, con *conman.Connection) *rule.Rule {
 		log.Debug("%s %s -> %s:%d (%s)", log.Bold(log.Red("✘")), log.Bold(con.Process.Path), log.Bold(con.To()), con.DstPort, log.Red(r.Name))
 	}
 
-	return r
+	return r, action
 }
 
 func main() {
@@ -289,7 +422,8 @@ func main() {
 	flag.Parse()
 
 	// clean any possible residual firewall rule
-	firewall.CleanRules(false)
+	fw = firewall.New()
+	fw.CleanRules(false)
 
 	setupLogging()
 
@@ -327,7 +461,47 @@ func main() {
 	}
 	pktChan = queue.Packets()
 
-	uiClient = ui.NewClient(uiSocket, stats, rules)
+	uiClient = ui.NewClient(uiSocket, stats, rules, fw)
+	if usersRulesPath != "" {
+		if usersRulesPath, err = core.ExpandPath(usersRulesPath); err != nil {
+			log.Fatal("%s", err)
+		}
+		if users, err = ui.NewUsers(usersRulesPath, usersSocket, !noLiveReload, fw); err != nil {
+			log.Fatal("%s", err)
+		}
+	}
+	if historyPath != "" {
+		if historyPath, err = core.ExpandPath(historyPath); err != nil {
+			log.Fatal("%s", err)
+		}
+		log.Info("Saving the connections history to %s ...", historyPath)
+		if hist, err = history.Open(historyPath, historyRetention); err != nil {
+			log.Fatal("%s", err)
+		}
+	}
+	if trafficAccounting {
+		if tracker, err = conntrack.NewTracker(stats); err != nil {
+			log.Fatal("Error subscribing to the conntrack events: %s", err)
+		}
+	}
+	if serviceSocket != "" {
+		service = ui.NewService(serviceSocket, uiClient, hist)
+		if serviceCACert != "" || serviceCert != "" || serviceKey != "" {
+			service.SetTLS(serviceCACert, serviceCert, serviceKey)
+		}
+		// the connections are filtered anyway, the daemon can still be
+		// managed from the GUI.
+		if err = service.Serve(); err != nil {
+			log.Warning("%s", err)
+			service = nil
+		}
+	}
+	if metricsAddress != "" {
+		metricsServer = metrics.NewServer(metricsAddress, stats, rules, queueNum)
+		if err = metricsServer.Serve(); err != nil {
+			log.Fatal("%s", err)
+		}
+	}
 	if overwriteLogging() {
 		setupLogging()
 	}
@@ -338,8 +512,11 @@ func main() {
 	}
 	procmon.Init()
 
+	if fwType != "" {
+		fw.SetType(fwType)
+	}
 	// queue is ready, run firewall rules
-	firewall.Init(&queueNum)
+	fw.Init(&queueNum)
 
 	log.Info("Running on netfilter queue #%d ...", queueNum)
 	for {
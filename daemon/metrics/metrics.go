@@ -0,0 +1,164 @@
+// Package metrics exposes the statistics of the daemon as Prometheus metrics,
+// in the text exposition format, so the hosts can be monitored and alerted on
+// (a spike of dropped connections, the GUI taking too long to reply...).
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/evilsocket/opensnitch/daemon/statistics"
+)
+
+// upper bounds of the prompt latency buckets, in seconds.
+var promptBuckets = []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+var (
+	prompts     = newHistogram(promptBuckets)
+	workers     int64
+	workersBusy int64
+	// nanoseconds the workers have spent processing packets
+	workersBusyTime int64
+)
+
+// histogram counts observations in cumulative buckets, the same way the
+// Prometheus histograms do.
+type histogram struct {
+	sync.Mutex
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)),
+	}
+}
+
+func (h *histogram) observe(v float64) {
+	h.Lock()
+	defer h.Unlock()
+	for i, upper := range h.buckets {
+		if v <= upper {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+func (h *histogram) write(w io.Writer, name, help string) {
+	h.Lock()
+	defer h.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+	for i, upper := range h.buckets {
+		fmt.Fprintf(w, "%s_bucket{le=\"%g\"} %d\n", name, upper, h.counts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", name, h.count)
+	fmt.Fprintf(w, "%s_sum %g\n", name, h.sum)
+	fmt.Fprintf(w, "%s_count %d\n", name, h.count)
+}
+
+// ObservePrompt records how long the GUI took to reply to a connection.
+func ObservePrompt(d time.Duration) {
+	prompts.observe(d.Seconds())
+}
+
+// SetWorkers sets the number of workers processing packets.
+func SetWorkers(n int) {
+	atomic.StoreInt64(&workers, int64(n))
+}
+
+// WorkerBusy marks a worker as busy processing a packet, until the returned
+// function is called.
+func WorkerBusy() func() {
+	start := time.Now()
+	atomic.AddInt64(&workersBusy, 1)
+	return func() {
+		atomic.AddInt64(&workersBusy, -1)
+		atomic.AddInt64(&workersBusyTime, int64(time.Since(start)))
+	}
+}
+
+func writeMetric(w io.Writer, name, typ, help string, value interface{}) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, typ, name, value)
+}
+
+// labelEscape escapes the characters not allowed in label values.
+func labelEscape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// writeStats writes the counters of the statistics.
+func writeStats(w io.Writer, stats *statistics.Statistics, rules int) {
+	stats.RLock()
+	defer stats.RUnlock()
+
+	writeMetric(w, "opensnitch_uptime_seconds", "gauge", "Seconds since the daemon started.", int64(time.Since(stats.Started).Seconds()))
+	writeMetric(w, "opensnitch_rules", "gauge", "Number of rules loaded.", rules)
+	writeMetric(w, "opensnitch_connections_total", "counter", "Connections processed.", stats.Connections)
+	writeMetric(w, "opensnitch_accepted_total", "counter", "Connections accepted.", stats.Accepted)
+	writeMetric(w, "opensnitch_dropped_total", "counter", "Connections dropped.", stats.Dropped)
+	writeMetric(w, "opensnitch_requeued_total", "counter", "Connections handed over to another queue.", stats.Requeued)
+	writeMetric(w, "opensnitch_ignored_total", "counter", "Connections ignored.", stats.Ignored)
+	writeMetric(w, "opensnitch_rule_hits_total", "counter", "Connections matched by a rule.", stats.RuleHits)
+	writeMetric(w, "opensnitch_rule_misses_total", "counter", "Connections not matched by any rule.", stats.RuleMisses)
+	writeMetric(w, "opensnitch_dns_responses_total", "counter", "DNS responses tracked.", stats.DNSResponses)
+
+	byProto := stats.ConnectionsByProto
+	protos := make([]string, 0, len(byProto))
+	for proto := range byProto {
+		protos = append(protos, proto)
+	}
+	sort.Strings(protos)
+	fmt.Fprintf(w, "# HELP opensnitch_connections_by_protocol_total Connections processed by protocol.\n")
+	fmt.Fprintf(w, "# TYPE opensnitch_connections_by_protocol_total counter\n")
+	for _, proto := range protos {
+		fmt.Fprintf(w, "opensnitch_connections_by_protocol_total{protocol=\"%s\"} %d\n", labelEscape(proto), byProto[proto])
+	}
+
+	writeTraffic(w, "executable", stats.TrafficByExecutable.Traffic())
+	writeTraffic(w, "rule", stats.TrafficByRule.Traffic())
+}
+
+// writeTraffic writes the bytes and duration of the connections closed, by
+// executable or rule. Only the ones with the most traffic are kept by the
+// statistics, so the number of labels is bounded.
+func writeTraffic(w io.Writer, label string, traffic map[string]statistics.Traffic) {
+	keys := make([]string, 0, len(traffic))
+	for key := range traffic {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	name := fmt.Sprintf("opensnitch_%s_bytes_total", label)
+	fmt.Fprintf(w, "# HELP %s Bytes transferred by the connections closed, by %s.\n", name, label)
+	fmt.Fprintf(w, "# TYPE %s counter\n", name)
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s{%s=\"%s\",direction=\"in\"} %d\n", name, label, labelEscape(key), traffic[key].BytesIn)
+		fmt.Fprintf(w, "%s{%s=\"%s\",direction=\"out\"} %d\n", name, label, labelEscape(key), traffic[key].BytesOut)
+	}
+
+	name = fmt.Sprintf("opensnitch_%s_connection_seconds_total", label)
+	fmt.Fprintf(w, "# HELP %s Duration of the connections closed, by %s.\n", name, label)
+	fmt.Fprintf(w, "# TYPE %s counter\n", name)
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s{%s=\"%s\"} %g\n", name, label, labelEscape(key), traffic[key].Duration.Seconds())
+	}
+}
+
+// writeWorkers writes the utilisation of the workers.
+func writeWorkers(w io.Writer) {
+	writeMetric(w, "opensnitch_workers", "gauge", "Workers processing packets.", atomic.LoadInt64(&workers))
+	writeMetric(w, "opensnitch_workers_busy", "gauge", "Workers busy processing a packet.", atomic.LoadInt64(&workersBusy))
+	writeMetric(w, "opensnitch_workers_busy_seconds_total", "counter", "Seconds the workers have spent processing packets.",
+		fmt.Sprintf("%g", time.Duration(atomic.LoadInt64(&workersBusyTime)).Seconds()))
+}
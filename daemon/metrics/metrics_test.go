@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/evilsocket/opensnitch/daemon/rule"
+	"github.com/evilsocket/opensnitch/daemon/statistics"
+)
+
+func TestHistogram(t *testing.T) {
+	h := newHistogram([]float64{1, 5})
+	h.observe(0.5)
+	h.observe(3)
+	h.observe(10)
+
+	buf := &bytes.Buffer{}
+	h.write(buf, "test_seconds", "Test.")
+	out := buf.String()
+	for _, line := range []string{
+		`test_seconds_bucket{le="1"} 1`,
+		`test_seconds_bucket{le="5"} 2`,
+		`test_seconds_bucket{le="+Inf"} 3`,
+		`test_seconds_sum 13.5`,
+		`test_seconds_count 3`,
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("%s not found in:\n%s", line, out)
+		}
+	}
+}
+
+func TestParseQueueStats(t *testing.T) {
+	raw := "    0  12345     7 2 4096     3     1  1000  1\n" +
+		"    1  12346     0 2 4096     0     0     5  1\n"
+	queues, err := parseQueueStats(strings.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queues) != 2 {
+		t.Fatalf("Expected 2 queues, got %d", len(queues))
+	}
+	if q := queues[0]; q.num != 0 || q.backlog != 7 || q.dropped != 3 || q.userDropped != 1 {
+		t.Errorf("Unexpected stats of queue 0: %+v", q)
+	}
+	if _, err := parseQueueStats(strings.NewReader("0 1 x 2 4096 0 0 5 1\n")); err == nil {
+		t.Error("Invalid stats parsed")
+	}
+}
+
+func TestHandleMetrics(t *testing.T) {
+	dir, err := ioutil.TempDir("", "metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	nfqueueStatsFile = filepath.Join(dir, "nfnetlink_queue")
+	ioutil.WriteFile(nfqueueStatsFile, []byte("    0  12345     7 2 4096     3     1  1000  1\n"), 0600)
+
+	rules, _ := rule.NewLoader(false)
+	stats := statistics.New(rules)
+	stats.OnDNSResponse()
+	stats.OnIgnored()
+	stats.OnConnectionClosed("/usr/bin/curl", "allow-curl", 2048, 512, 3*time.Second)
+	SetWorkers(4)
+	done := WorkerBusy()
+	ObservePrompt(2 * time.Second)
+
+	s := NewServer("127.0.0.1:0", stats, rules, 0)
+	rec := httptest.NewRecorder()
+	s.handleMetrics(rec, httptest.NewRequest("GET", "/metrics", nil))
+	done()
+
+	out := rec.Body.String()
+	for _, line := range []string{
+		"opensnitch_accepted_total 2",
+		"opensnitch_ignored_total 1",
+		"opensnitch_dns_responses_total 1",
+		"opensnitch_rules 0",
+		"opensnitch_workers 4",
+		"opensnitch_workers_busy 1",
+		`opensnitch_prompt_duration_seconds_bucket{le="2.5"} 1`,
+		"opensnitch_nfqueue_backlog 7",
+		"opensnitch_nfqueue_dropped_total 3",
+		`opensnitch_executable_bytes_total{executable="/usr/bin/curl",direction="in"} 2048`,
+		`opensnitch_rule_bytes_total{rule="allow-curl",direction="out"} 512`,
+		`opensnitch_executable_connection_seconds_total{executable="/usr/bin/curl"} 3`,
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("%s not found in:\n%s", line, out)
+		}
+	}
+}
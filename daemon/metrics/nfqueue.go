@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var nfqueueStatsFile = "/proc/net/netfilter/nfnetlink_queue"
+
+// queueStats are the counters of a netfilter queue, from the kernel.
+type queueStats struct {
+	num         uint64
+	backlog     uint64
+	dropped     uint64
+	userDropped uint64
+}
+
+// parseQueueStats parses the stats of the netfilter queues. Every line is a
+// queue: queue_num peer_portid queue_total copy_mode copy_range queue_dropped
+// user_dropped id_sequence 1
+func parseQueueStats(r io.Reader) ([]queueStats, error) {
+	queues := make([]queueStats, 0)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 7 {
+			continue
+		}
+		var q queueStats
+		var err error
+		for _, f := range []struct {
+			idx int
+			dst *uint64
+		}{{0, &q.num}, {2, &q.backlog}, {5, &q.dropped}, {6, &q.userDropped}} {
+			if *f.dst, err = strconv.ParseUint(fields[f.idx], 10, 64); err != nil {
+				return nil, fmt.Errorf("Invalid queue stats line %q: %s", scanner.Text(), err)
+			}
+		}
+		queues = append(queues, q)
+	}
+	return queues, scanner.Err()
+}
+
+// writeQueue writes the backlog and drops of the netfilter queue of the
+// daemon. Nothing is written if the stats are not available.
+func writeQueue(w io.Writer, queueNum int) {
+	f, err := os.Open(nfqueueStatsFile)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	queues, err := parseQueueStats(f)
+	if err != nil {
+		return
+	}
+	for _, q := range queues {
+		if q.num != uint64(queueNum) {
+			continue
+		}
+		writeMetric(w, "opensnitch_nfqueue_backlog", "gauge", "Packets waiting in the netfilter queue to be processed.", q.backlog)
+		writeMetric(w, "opensnitch_nfqueue_dropped_total", "counter", "Packets dropped by the kernel because the netfilter queue was full.", q.dropped)
+		writeMetric(w, "opensnitch_nfqueue_user_dropped_total", "counter", "Packets dropped because the daemon couldn't receive them.", q.userDropped)
+	}
+}
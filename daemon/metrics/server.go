@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/evilsocket/opensnitch/daemon/core"
+	"github.com/evilsocket/opensnitch/daemon/log"
+	"github.com/evilsocket/opensnitch/daemon/rule"
+	"github.com/evilsocket/opensnitch/daemon/statistics"
+)
+
+// Server serves the metrics on /metrics, on a TCP address (127.0.0.1:9101)
+// or a unix socket (unix:///path/to/socket).
+type Server struct {
+	address      string
+	socketPath   string
+	isUnixSocket bool
+	queueNum     int
+	stats        *statistics.Statistics
+	rules        *rule.Loader
+	server       *http.Server
+}
+
+// NewServer creates the metrics server, to be started with Serve().
+func NewServer(address string, stats *statistics.Statistics, rules *rule.Loader, queueNum int) *Server {
+	s := &Server{
+		address:    address,
+		socketPath: address,
+		queueNum:   queueNum,
+		stats:      stats,
+		rules:      rules,
+	}
+	if strings.HasPrefix(address, "unix://") == true {
+		s.isUnixSocket = true
+		s.socketPath = address[7:]
+	}
+	return s
+}
+
+// Serve starts listening for requests.
+func (s *Server) Serve() error {
+	var listener net.Listener
+	var err error
+	if s.isUnixSocket {
+		// remove the socket of a previous run
+		if core.Exists(s.socketPath) {
+			os.Remove(s.socketPath)
+		}
+		listener, err = net.Listen("unix", s.socketPath)
+	} else {
+		listener, err = net.Listen("tcp", s.socketPath)
+	}
+	if err != nil {
+		return fmt.Errorf("Error listening on %s: %s", s.address, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", s.handleMetrics)
+	s.server = &http.Server{Handler: mux}
+
+	log.Info("Metrics listening on %s", s.address)
+	go func() {
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Warning("Metrics server %s stopped: %s", s.address, err)
+		}
+	}()
+	return nil
+}
+
+// Stop closes the server.
+func (s *Server) Stop() {
+	if s.server == nil {
+		return
+	}
+	s.server.Close()
+	if s.isUnixSocket {
+		os.Remove(s.socketPath)
+	}
+}
+
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	buf := &bytes.Buffer{}
+	writeStats(buf, s.stats, s.rules.NumRules())
+	prompts.write(buf, "opensnitch_prompt_duration_seconds", "Time the GUI took to reply to the connections asked.")
+	writeWorkers(buf)
+	writeQueue(buf, s.queueNum)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write(buf.Bytes())
+}
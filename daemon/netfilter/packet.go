@@ -27,6 +27,9 @@ type Packet struct {
 	verdictChannel  chan VerdictContainer
 	UID             uint32
 	NetworkProtocol uint8
+	// index of the input and output interfaces of the packet, 0 if unknown.
+	IfaceInIdx  int
+	IfaceOutIdx int
 }
 
 // SetVerdict emits a veredict on a packet
@@ -186,7 +186,7 @@ func (q *Queue) Packets() <-chan Packet {
 // FYI: the export keyword is mandatory to specify that go_callback is defined elsewhere
 
 //export go_callback
-func go_callback(queueID C.int, data *C.uchar, length C.int, mark C.uint, idx uint32, vc *VerdictContainerC, uid uint32) {
+func go_callback(queueID C.int, data *C.uchar, length C.int, mark C.uint, idx uint32, vc *VerdictContainerC, uid, indev, outdev uint32) {
 	(*vc).verdict = C.uint(NF_ACCEPT)
 	(*vc).data = nil
 	(*vc).mark_set = 0
@@ -206,6 +206,8 @@ func go_callback(queueID C.int, data *C.uchar, length C.int, mark C.uint, idx ui
 		verdictChannel:  make(chan VerdictContainer),
 		Mark:            uint32(mark),
 		UID:             uid,
+		IfaceInIdx:      int(indev),
+		IfaceOutIdx:     int(outdev),
 		NetworkProtocol: xdata[0] >> 4, // first 4 bits is the version
 	}
 
@@ -24,7 +24,7 @@ typedef struct {
 
 static void *get_uid = NULL;
 
-extern void go_callback(int id, unsigned char* data, int len, uint mark, u_int32_t idx, verdictContainer *vc, uint32_t uid);
+extern void go_callback(int id, unsigned char* data, int len, uint mark, u_int32_t idx, verdictContainer *vc, uint32_t uid, uint32_t indev, uint32_t outdev);
 
 static uint8_t stop = 0;
 
@@ -60,19 +60,22 @@ static int nf_callback(struct nfq_q_handle *qh, struct nfgenmsg *nfmsg, struct n
     int size = 0;
     verdictContainer vc = {0};
     uint32_t uid = 0xffffffff;
+    uint32_t indev = 0, outdev = 0;
 
     mark = nfq_get_nfmark(nfa);
     ph   = nfq_get_msg_packet_hdr(nfa);
     id   = ntohl(ph->packet_id);
     size = nfq_get_payload(nfa, &buffer);
     idx  = (uint32_t)((uintptr_t)arg);
+    indev  = nfq_get_indev(nfa);
+    outdev = nfq_get_outdev(nfa);
 
 #ifdef NFQA_CFG_F_UID_GID
     if (get_uid)
         nfq_get_uid(nfa, &uid);
 #endif
 
-    go_callback(id, buffer, size, mark, idx, &vc, uid);
+    go_callback(id, buffer, size, mark, idx, &vc, uid, indev, outdev);
 
     if( vc.mark_set == 1 ) {
       return nfq_set_verdict2(qh, id, vc.verdict, vc.mark, vc.length, vc.data);
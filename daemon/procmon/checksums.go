@@ -0,0 +1,194 @@
+package procmon
+
+import (
+	"container/list"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"syscall"
+)
+
+// Checksum algorithms of the process executable.
+const (
+	HashMD5    = "md5"
+	HashSHA256 = "sha256"
+)
+
+// checksumKey identifies a version of a binary. The ctime is included because
+// the mtime can be restored after modifying a file, while the ctime can't.
+type checksumKey struct {
+	dev   uint64
+	inode uint64
+	size  int64
+	mtime int64
+	ctime int64
+}
+
+type cachedChecksums struct {
+	key  checksumKey
+	sums map[string]string
+}
+
+var (
+	// cache of checksums, to not hash the same binaries on every connection.
+	// The least recently used binaries are evicted first.
+	checksumsLock      sync.Mutex
+	checksumsCache     = make(map[checksumKey]*list.Element)
+	checksumsLRU       = list.New()
+	maxCachedChecksums = 128
+
+	// checksums computed of the new processes, only the ones used by the rules:
+	// the algorithms used by every owner (a loader of rules), and their union.
+	checksumsByOwner = make(map[interface{}][]string)
+	checksumsEnabled []string
+)
+
+// EnableChecksums sets the checksums of the new processes used by the owner,
+// usually a loader of rules. Hashing the executables is expensive, so only the
+// algorithms used by the rules should be enabled: the checksums computed are
+// the ones used by any owner. Without algorithms, the owner uses none.
+func EnableChecksums(owner interface{}, algos ...string) {
+	checksumsLock.Lock()
+	defer checksumsLock.Unlock()
+
+	if len(algos) == 0 {
+		delete(checksumsByOwner, owner)
+	} else {
+		checksumsByOwner[owner] = algos
+	}
+
+	union := make(map[string]bool)
+	for _, used := range checksumsByOwner {
+		for _, algo := range used {
+			union[algo] = true
+		}
+	}
+	// the slice returned by enabledChecksums is never modified, so it's replaced
+	enabled := make([]string, 0, len(union))
+	for algo := range union {
+		enabled = append(enabled, algo)
+	}
+	sort.Strings(enabled)
+	checksumsEnabled = enabled
+}
+
+// EnabledChecksums returns the checksums computed of the new processes.
+func EnabledChecksums() []string {
+	checksumsLock.Lock()
+	defer checksumsLock.Unlock()
+	return checksumsEnabled
+}
+
+// getCachedChecksums returns the checksums of a binary, marking it as recently used.
+func getCachedChecksums(key checksumKey) map[string]string {
+	checksumsLock.Lock()
+	defer checksumsLock.Unlock()
+
+	e, found := checksumsCache[key]
+	if !found {
+		return nil
+	}
+	checksumsLRU.MoveToFront(e)
+	return e.Value.(*cachedChecksums).sums
+}
+
+// cacheChecksums adds the checksums of a binary to the ones cached,
+// evicting the least recently used binary if the cache is full.
+func cacheChecksums(key checksumKey, sums map[string]string) {
+	checksumsLock.Lock()
+	defer checksumsLock.Unlock()
+
+	if e, found := checksumsCache[key]; found {
+		e.Value.(*cachedChecksums).sums = sums
+		checksumsLRU.MoveToFront(e)
+		return
+	}
+	for checksumsLRU.Len() >= maxCachedChecksums {
+		oldest := checksumsLRU.Back()
+		checksumsLRU.Remove(oldest)
+		delete(checksumsCache, oldest.Value.(*cachedChecksums).key)
+	}
+	checksumsCache[key] = checksumsLRU.PushFront(&cachedChecksums{key, sums})
+}
+
+// ReadChecksums computes the checksums of the executable of the process with the
+// given algorithms, or the ones enabled if none is given. The checksums are
+// cached by binary, so every version of a binary is hashed only once.
+// The executable is read from /proc/<pid>/exe, so the checksums are of the binary
+// the process is running, even if the file has been replaced on disk.
+func (p *Process) ReadChecksums(algos ...string) error {
+	if len(algos) == 0 {
+		if algos = EnabledChecksums(); len(algos) == 0 {
+			return nil
+		}
+	}
+
+	f, err := os.Open(fmt.Sprint("/proc/", p.ID, "/exe"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fmt.Errorf("Unable to get the inode of %s", p.Path)
+	}
+	key := checksumKey{
+		dev:   uint64(st.Dev),
+		inode: st.Ino,
+		size:  info.Size(),
+		mtime: info.ModTime().UnixNano(),
+		ctime: st.Ctim.Nano(),
+	}
+
+	cached := getCachedChecksums(key)
+	hashes := make(map[string]hash.Hash)
+	writers := []io.Writer{}
+	for _, algo := range algos {
+		if _, found := cached[algo]; found {
+			continue
+		}
+		var h hash.Hash
+		switch algo {
+		case HashMD5:
+			h = md5.New()
+		case HashSHA256:
+			h = sha256.New()
+		default:
+			return fmt.Errorf("Unknown checksum algorithm %s", algo)
+		}
+		hashes[algo] = h
+		writers = append(writers, h)
+	}
+
+	checksums := cached
+	if len(hashes) > 0 {
+		if _, err := io.Copy(io.MultiWriter(writers...), f); err != nil {
+			return err
+		}
+		// the cached maps are shared by the processes, so they're never modified
+		checksums = make(map[string]string, len(cached)+len(hashes))
+		for algo, sum := range cached {
+			checksums[algo] = sum
+		}
+		for algo, h := range hashes {
+			checksums[algo] = hex.EncodeToString(h.Sum(nil))
+		}
+		cacheChecksums(key, checksums)
+	}
+
+	for _, algo := range algos {
+		p.Checksums[algo] = checksums[algo]
+	}
+	return nil
+}
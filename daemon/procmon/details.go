@@ -16,6 +16,9 @@ import (
 
 var socketsRegex, _ = regexp.Compile(`socket:\[([0-9]+)\]`)
 
+// maximum number of ancestors of a process, in case of a loop.
+var maxTreeDepth = 64
+
 // GetInfo collects information of a process.
 func (p *Process) GetInfo() error {
 	if err := p.readPath(); err != nil {
@@ -25,9 +28,12 @@ func (p *Process) GetInfo() error {
 	p.readCmdline()
 	p.readEnv()
 	p.readDescriptors()
-	p.readIOStats()
+	p.ReadIOStats()
 	p.readStatus()
 	p.cleanPath()
+	// the details of the process show all the checksums
+	p.ReadChecksums(HashMD5, HashSHA256)
+	p.readParents(0)
 
 	return nil
 }
@@ -129,7 +135,8 @@ func (p *Process) readDescriptors() {
 	}
 }
 
-func (p *Process) readIOStats() {
+// ReadIOStats reads the I/O statistics of the process from /proc/<pid>/io.
+func (p *Process) ReadIOStats() {
 	f, err := os.Open(fmt.Sprint("/proc/", p.ID, "/io"))
 	if err != nil {
 		return
@@ -177,6 +184,68 @@ func (p *Process) readStatus() {
 	}
 }
 
+// readParents reads the parent PID of the process, and the path of its
+// ancestors. If ppid is not known (0), it's read from the system.
+func (p *Process) readParents(ppid int) {
+	if ppid <= 0 {
+		ppid = getParentPid(p.ID)
+	}
+	p.PPID = ppid
+	p.Tree = nil
+	for pid := ppid; pid > 0 && len(p.Tree) < maxTreeDepth; pid = getParentPid(pid) {
+		p.Tree = append(p.Tree, ProcParent{ID: pid, Path: getProcessPath(pid)})
+	}
+}
+
+// getParentPid returns the parent PID of a process, from the fork events
+// if the ftrace monitor method is enabled, or from /proc/<pid>/stat.
+func getParentPid(pid int) int {
+	if methodIsFtrace() {
+		lock.RLock()
+		d, found := index[pid]
+		lock.RUnlock()
+		if found && d.ppid > 0 {
+			return d.ppid
+		}
+	}
+	return readParentPid(pid)
+}
+
+func readParentPid(pid int) int {
+	data, err := ioutil.ReadFile(fmt.Sprint("/proc/", pid, "/stat"))
+	if err != nil {
+		return -1
+	}
+	// pid (comm) state ppid ..., where comm may contain spaces and parenthesis
+	stat := string(data)
+	pos := strings.LastIndexByte(stat, ')')
+	if pos == -1 {
+		return -1
+	}
+	var state string
+	var ppid int
+	if _, err := fmt.Sscanf(stat[pos+1:], "%s %d", &state, &ppid); err != nil {
+		return -1
+	}
+	return ppid
+}
+
+// getProcessPath returns the path of the executable of a process.
+// Kernel threads don't have one.
+func getProcessPath(pid int) string {
+	if link, err := os.Readlink(fmt.Sprint("/proc/", pid, "/exe")); err == nil {
+		return strings.TrimSuffix(link, " (deleted)")
+	}
+	if methodIsFtrace() {
+		lock.RLock()
+		defer lock.RUnlock()
+		if d, found := index[pid]; found {
+			return d.path
+		}
+	}
+	return ""
+}
+
 func (p *Process) cleanPath() {
 	pathLen := len(p.Path)
 	if pathLen >= 10 && p.Path[pathLen-10:] == " (deleted)" {
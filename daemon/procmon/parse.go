@@ -95,6 +95,7 @@ func FindProcess(pid int, interceptUnknown bool) *Process {
 			proc := NewProcess(pid, aevent.ProcPath)
 			proc.readCmdline()
 			proc.setCwd(aevent.ProcDir)
+			ppid := aevent.PPid
 			audit.Lock.RUnlock()
 			// if the proc dir contains non alhpa-numeric chars the field is empty
 			if proc.CWD == "" {
@@ -102,6 +103,8 @@ func FindProcess(pid int, interceptUnknown bool) *Process {
 			}
 			proc.readEnv()
 			proc.cleanPath()
+			proc.ReadChecksums()
+			proc.readParents(ppid)
 
 			return proc
 		}
@@ -119,6 +122,8 @@ func FindProcess(pid int, interceptUnknown bool) *Process {
 		proc.readCwd()
 		proc.readEnv()
 		proc.cleanPath()
+		proc.ReadChecksums()
+		proc.readParents(0)
 
 		return proc
 	}
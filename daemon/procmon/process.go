@@ -34,12 +34,20 @@ type procStatm struct {
 	Dt       int
 }
 
+// ProcParent holds the details of an ancestor of a process.
+type ProcParent struct {
+	ID   int
+	Path string
+}
+
 // Process holds the details of a process.
 type Process struct {
 	ID          int
+	PPID        int
 	Path        string
 	Args        []string
 	Env         map[string]string
+	Checksums   map[string]string
 	CWD         string
 	Descriptors []*procDescriptors
 	IOStats     *procIOstats
@@ -48,15 +56,18 @@ type Process struct {
 	Statm       *procStatm
 	Stack       string
 	Maps        string
+	// ancestors of the process, from the parent to the init process
+	Tree []ProcParent
 }
 
 // NewProcess returns a new Process structure.
 func NewProcess(pid int, path string) *Process {
 	return &Process{
-		ID:   pid,
-		Path: path,
-		Args: make([]string, 0),
-		Env:  make(map[string]string),
+		ID:        pid,
+		Path:      path,
+		Args:      make([]string, 0),
+		Env:       make(map[string]string),
+		Checksums: make(map[string]string),
 	}
 }
 
@@ -1,8 +1,13 @@
 package procmon
 
 import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
 	"os"
 	"testing"
+
+	"github.com/evilsocket/ftrace"
 )
 
 var (
@@ -66,7 +71,7 @@ func TestProcEnv(t *testing.T) {
 }
 
 func TestProcIOStats(t *testing.T) {
-	proc.readIOStats()
+	proc.ReadIOStats()
 
 	if proc.IOStats.RChar == 0 {
 		t.Error("Proc.IOStats.RChar should not be 0:", proc.IOStats)
@@ -133,3 +138,126 @@ func TestProcCleanPath(t *testing.T) {
 		t.Error("Proc cleanPath() not cleaned:", proc.Path)
 	}
 }
+
+func TestProcChecksums(t *testing.T) {
+	data, err := ioutil.ReadFile("/proc/self/exe")
+	if err != nil {
+		t.Fatal("Error reading /proc/self/exe:", err)
+	}
+	expected := fmt.Sprintf("%x", sha256.Sum256(data))
+
+	// not computed unless enabled
+	p := NewProcess(myPid, "/fake/path")
+	if err := p.ReadChecksums(); err != nil || len(p.Checksums) != 0 {
+		t.Error("Proc checksums computed without being enabled:", p.Checksums, err)
+	}
+
+	EnableChecksums(p, HashSHA256)
+	defer EnableChecksums(p)
+	if err := p.ReadChecksums(); err != nil {
+		t.Fatal("Proc ReadChecksums() error:", err)
+	}
+	if p.Checksums[HashSHA256] != expected {
+		t.Error("Proc sha256 checksum not valid:", p.Checksums[HashSHA256], expected)
+	}
+	if _, found := p.Checksums[HashMD5]; found {
+		t.Error("Proc md5 checksum not enabled computed")
+	}
+
+	numCached := len(checksumsCache)
+	p = NewProcess(myPid, "/fake/path")
+	if err := p.ReadChecksums(HashMD5, HashSHA256); err != nil {
+		t.Fatal("Proc ReadChecksums() error:", err)
+	}
+	if len(checksumsCache) != numCached || p.Checksums[HashSHA256] != expected {
+		t.Error("Proc checksums not cached:", len(checksumsCache), numCached)
+	}
+	if len(p.Checksums[HashMD5]) != 32 {
+		t.Error("Proc md5 checksum not valid:", p.Checksums[HashMD5])
+	}
+
+	p = NewProcess(-1, "")
+	if err := p.ReadChecksums(); err == nil {
+		t.Error("Proc ReadChecksums() of an invalid PID should fail")
+	}
+}
+
+func TestChecksumsCacheLRU(t *testing.T) {
+	checksumsLock.Lock()
+	oldMax := maxCachedChecksums
+	maxCachedChecksums = 2
+	checksumsLock.Unlock()
+	defer func() {
+		checksumsLock.Lock()
+		maxCachedChecksums = oldMax
+		checksumsLock.Unlock()
+	}()
+
+	k1, k2, k3 := checksumKey{inode: 1}, checksumKey{inode: 2}, checksumKey{inode: 3}
+	cacheChecksums(k1, map[string]string{HashMD5: "1"})
+	cacheChecksums(k2, map[string]string{HashMD5: "2"})
+	// k1 is used, so k2 is the least recently used
+	if getCachedChecksums(k1) == nil {
+		t.Fatal("Checksums not cached")
+	}
+	cacheChecksums(k3, map[string]string{HashMD5: "3"})
+
+	if getCachedChecksums(k2) != nil {
+		t.Error("Least recently used checksums not evicted")
+	}
+	if getCachedChecksums(k1) == nil || getCachedChecksums(k3) == nil {
+		t.Error("Recently used checksums evicted")
+	}
+	if len(checksumsCache) != 2 || checksumsLRU.Len() != 2 {
+		t.Error("Unexpected size of the cache:", len(checksumsCache), checksumsLRU.Len())
+	}
+}
+
+func TestProcParents(t *testing.T) {
+	if ppid := readParentPid(myPid); ppid != os.Getppid() {
+		t.Error("Proc readParentPid() not valid:", ppid, os.Getppid())
+	}
+	if ppid := readParentPid(-1); ppid != -1 {
+		t.Error("Proc readParentPid() of an invalid PID should be -1:", ppid)
+	}
+
+	p := NewProcess(myPid, "/fake/path")
+	p.readParents(0)
+	if p.PPID != os.Getppid() {
+		t.Error("Proc PPID not valid:", p.PPID, os.Getppid())
+	}
+	if len(p.Tree) == 0 || p.Tree[0].ID != p.PPID {
+		t.Fatal("Proc Tree should start with the parent:", p.Tree)
+	}
+	if last := p.Tree[len(p.Tree)-1]; readParentPid(last.ID) != 0 {
+		t.Error("Proc Tree should end with a process without parent:", p.Tree)
+	}
+	if parentPath, _ := os.Readlink(fmt.Sprint("/proc/", p.PPID, "/exe")); parentPath != p.Tree[0].Path {
+		t.Error("Proc parent path not valid:", p.Tree[0].Path, parentPath)
+	}
+}
+
+func TestTrackProcessFork(t *testing.T) {
+	trackProcessPath(ftrace.Event{PID: 1000, Args: map[string]string{"filename": "/usr/bin/ci-agent"}})
+	trackProcessFork(ftrace.Event{PID: 1000, Args: map[string]string{"pid": "1000", "child_pid": "1001"}})
+	defer func() {
+		trackProcessExit(ftrace.Event{PID: 1000})
+		trackProcessExit(ftrace.Event{PID: 1001})
+	}()
+
+	lock.RLock()
+	child, found := index[1001]
+	lock.RUnlock()
+	if !found {
+		t.Fatal("Forked process not tracked")
+	}
+	if child.ppid != 1000 || child.path != "/usr/bin/ci-agent" {
+		t.Error("Forked process parent not valid:", child.ppid, child.path)
+	}
+
+	SetMonitorMethod(MethodFtrace)
+	defer SetMonitorMethod(MethodProc)
+	if ppid := getParentPid(1001); ppid != 1000 {
+		t.Error("getParentPid() of a forked process not valid:", ppid)
+	}
+}
@@ -22,6 +22,7 @@ const (
 )
 
 type procData struct {
+	ppid int
 	path string
 	args []string
 }
@@ -86,6 +87,34 @@ func trackProcessPath(e ftrace.Event) {
 	}
 }
 
+// trackProcessFork saves the parent of a new process. Until it executes
+// a new binary, the child runs the binary of the parent.
+func trackProcessFork(e ftrace.Event) {
+	ppid, err := strconv.Atoi(e.Args["pid"])
+	if err != nil {
+		ppid = e.PID
+	}
+	pid, err := strconv.Atoi(e.Args["child_pid"])
+	if err != nil {
+		return
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	path := ""
+	if parent, found := index[ppid]; found {
+		path = parent.path
+	}
+	if d, found := index[pid]; found == false {
+		index[pid] = &procData{
+			ppid: ppid,
+			path: path,
+		}
+	} else {
+		d.ppid = ppid
+	}
+}
+
 func trackProcessExit(e ftrace.Event) {
 	lock.Lock()
 	defer lock.Unlock()
@@ -98,6 +127,8 @@ func eventConsumer() {
 			trackProcessArgs(event)
 		} else if _, ok := event.Args["filename"]; ok && event.Name == "sched_process_exec" {
 			trackProcessPath(event)
+		} else if event.Name == "sched_process_fork" {
+			trackProcessFork(event)
 		} else if event.Name == "sched_process_exit" {
 			trackProcessExit(event)
 		}
@@ -0,0 +1,255 @@
+package rule
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/evilsocket/opensnitch/daemon/conman"
+)
+
+// operands indexed by value.
+var indexedOperands = []Operand{OpProcessPath, OpDstHost, OpDstIP, OpDstPort}
+
+// ruleIndex holds the position of the rules (in the sorted list of rules),
+// indexed by the value of the operand they check. It's used to evaluate only
+// the rules that may match a connection, instead of all of them.
+//
+// A rule is indexed if its operator (or one of the operators of an and/list)
+// is a simple comparison of an indexed operand, or a dest.network.
+// The rest of the rules are always evaluated.
+type ruleIndex struct {
+	always    []int
+	values    map[Operand]*valueIndex
+	networks4 *netTrie
+	networks6 *netTrie
+}
+
+// valueIndex indexes the simple operators of an operand.
+// Case-insensitive values are indexed by their folded form.
+type valueIndex struct {
+	sensitive   map[string][]int
+	insensitive map[string][]int
+}
+
+// netTrie is a binary prefix trie of networks.
+type netTrie struct {
+	prefix   bool
+	rules    []int
+	children [2]*netTrie
+}
+
+func newRuleIndex(keys []string, rules map[string]*Rule) *ruleIndex {
+	idx := &ruleIndex{
+		values:    make(map[Operand]*valueIndex, len(indexedOperands)),
+		networks4: &netTrie{},
+		networks6: &netTrie{},
+	}
+	for _, op := range indexedOperands {
+		idx.values[op] = &valueIndex{
+			sensitive:   make(map[string][]int),
+			insensitive: make(map[string][]int),
+		}
+	}
+
+	for pos, name := range keys {
+		if r, found := rules[name]; !found || !idx.add(pos, &r.Operator) {
+			idx.always = append(idx.always, pos)
+		}
+	}
+
+	return idx
+}
+
+// add indexes the operator of the rule at the given position.
+// It returns false if the operator can't be indexed.
+func (idx *ruleIndex) add(pos int, op *Operator) bool {
+	op = indexableOperator(op)
+	if op == nil {
+		return false
+	}
+	if op.Type == Network {
+		ip, ones, ok := networkPrefix(op.netMask)
+		if !ok {
+			return false
+		}
+		trie := idx.networks6
+		if len(ip) == net.IPv4len {
+			trie = idx.networks4
+		}
+		node := trie.insert(ip, ones)
+		node.rules = append(node.rules, pos)
+		return true
+	}
+
+	values := idx.values[op.Operand]
+	if op.Sensitive {
+		values.sensitive[op.Data] = append(values.sensitive[op.Data], pos)
+	} else {
+		key := foldKey(op.Data)
+		values.insensitive[key] = append(values.insensitive[key], pos)
+	}
+	return true
+}
+
+// candidates returns, sorted, the position of the rules that may match the connection.
+func (idx *ruleIndex) candidates(con *conman.Connection) []int {
+	if idx == nil {
+		return nil
+	}
+	res := make([]int, len(idx.always))
+	copy(res, idx.always)
+
+	if con.Process != nil {
+		res = idx.values[OpProcessPath].lookup(con.Process.Path, res)
+	}
+	if con.DstHost != "" {
+		res = idx.values[OpDstHost].lookup(con.DstHost, res)
+	}
+	res = idx.values[OpDstIP].lookup(con.DstIP.String(), res)
+	res = idx.values[OpDstPort].lookup(fmt.Sprintf("%d", con.DstPort), res)
+
+	ip := con.DstIP
+	if ip4 := ip.To4(); ip4 != nil {
+		res = idx.networks4.lookup(ip4, res)
+	} else if len(ip) == net.IPv6len {
+		res = idx.networks6.lookup(ip, res)
+	}
+
+	if len(res) == len(idx.always) {
+		return res
+	}
+	sort.Ints(res)
+	uniq := res[:1]
+	for _, pos := range res[1:] {
+		if pos != uniq[len(uniq)-1] {
+			uniq = append(uniq, pos)
+		}
+	}
+	return uniq
+}
+
+func (v *valueIndex) lookup(value string, res []int) []int {
+	res = append(res, v.sensitive[value]...)
+	if len(v.insensitive) > 0 {
+		res = append(res, v.insensitive[foldKey(value)]...)
+	}
+	return res
+}
+
+// insert adds a network to the trie, and returns its node.
+func (t *netTrie) insert(ip net.IP, ones int) *netTrie {
+	node := t
+	for i := 0; i < ones; i++ {
+		bit := (ip[i/8] >> (7 - uint(i%8))) & 1
+		if node.children[bit] == nil {
+			node.children[bit] = &netTrie{}
+		}
+		node = node.children[bit]
+	}
+	node.prefix = true
+	return node
+}
+
+// contains returns true if any of the networks of the trie contains the ip.
+func (t *netTrie) contains(ip net.IP) bool {
+	node := t
+	for i := 0; node != nil; i++ {
+		if node.prefix {
+			return true
+		}
+		if i == len(ip)*8 {
+			break
+		}
+		node = node.children[(ip[i/8]>>(7-uint(i%8)))&1]
+	}
+	return false
+}
+
+// lookup appends the rules of every network containing the ip.
+func (t *netTrie) lookup(ip net.IP, res []int) []int {
+	node := t
+	for i := 0; node != nil; i++ {
+		res = append(res, node.rules...)
+		if i == len(ip)*8 {
+			break
+		}
+		node = node.children[(ip[i/8]>>(7-uint(i%8)))&1]
+	}
+	return res
+}
+
+// indexableOperator returns the operator a rule can be indexed by, if any.
+// An and/list only matches if all its operators match, so it can be indexed
+// by any of them.
+func indexableOperator(op *Operator) *Operator {
+	switch op.Type {
+	case Simple:
+		for _, operand := range indexedOperands {
+			if op.Operand == operand {
+				return op
+			}
+		}
+	case Network:
+		if op.Operand == OpDstNetwork && op.netMask != nil {
+			return op
+		}
+	case List, And:
+		for i := range op.List {
+			if o := indexableOperator(&op.List[i]); o != nil {
+				return o
+			}
+		}
+	}
+	return nil
+}
+
+// networkPrefix returns the network address and the prefix length of a network,
+// normalized the same way net.IPNet.Contains() does.
+func networkPrefix(n *net.IPNet) (net.IP, int, bool) {
+	ip := n.IP.To4()
+	if ip == nil {
+		ip = n.IP
+		if len(ip) != net.IPv6len {
+			return nil, 0, false
+		}
+	}
+	mask := n.Mask
+	switch len(mask) {
+	case net.IPv4len:
+		if len(ip) != net.IPv4len {
+			return nil, 0, false
+		}
+	case net.IPv6len:
+		if len(ip) == net.IPv4len {
+			mask = mask[12:]
+		}
+	default:
+		return nil, 0, false
+	}
+	ones, bits := mask.Size()
+	if bits == 0 {
+		// non canonical mask
+		return nil, 0, false
+	}
+	return ip, ones, true
+}
+
+// foldKey returns the same key for the strings strings.EqualFold() considers equal,
+// replacing every rune by the smallest rune of its case folding orbit.
+func foldKey(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		min := r
+		for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+			if f < min {
+				min = f
+			}
+		}
+		b.WriteRune(min)
+	}
+	return b.String()
+}
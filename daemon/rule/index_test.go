@@ -0,0 +1,167 @@
+package rule
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/evilsocket/opensnitch/daemon/conman"
+	"github.com/evilsocket/opensnitch/daemon/netstat"
+	"github.com/evilsocket/opensnitch/daemon/procmon"
+)
+
+var (
+	indexPaths    = []string{"/usr/bin/curl", "/usr/bin/wget", "/USR/BIN/CURL", "/opt/google/chrome/chrome"}
+	indexHosts    = []string{"opensnitch.io", "OpenSnitch.IO", "example.com", "ads.example.com", "ſite.org", "site.org"}
+	indexIPs      = []string{"185.53.178.14", "8.8.8.8", "10.0.0.1", "2001:db8::1", "::ffff:10.0.0.1"}
+	indexPorts    = []string{"443", "80", "53"}
+	indexNetworks = []string{"185.53.178.0/24", "10.0.0.0/8", "0.0.0.0/0", "2001:db8::/32", "::ffff:10.0.0.0/104", "8.8.8.8/32"}
+)
+
+func randItem(r *rand.Rand, items []string) string {
+	return items[r.Intn(len(items))]
+}
+
+func randIndexOperator(r *rand.Rand, depth int) Operator {
+	sensitive := Sensitive(r.Intn(2) == 0)
+	switch n := r.Intn(10); {
+	case n == 0:
+		return Operator{Type: Simple, Operand: OpTrue}
+	case n == 1:
+		return Operator{Type: Regexp, Operand: OpDstHost, Sensitive: sensitive, Data: "example\\.com$"}
+	case n == 2:
+		return Operator{Type: Network, Operand: OpDstNetwork, Data: randItem(r, indexNetworks)}
+	case n >= 7 && depth < 2:
+		types := []Type{List, And, Or, Not}
+		op := Operator{Type: types[r.Intn(len(types))], Operand: OpList}
+		num := 1 + r.Intn(3)
+		if op.Type == Not {
+			num = 1
+		}
+		for i := 0; i < num; i++ {
+			op.List = append(op.List, randIndexOperator(r, depth+1))
+		}
+		return op
+	default:
+		switch r.Intn(4) {
+		case 0:
+			return Operator{Type: Simple, Operand: OpProcessPath, Sensitive: sensitive, Data: randItem(r, indexPaths)}
+		case 1:
+			return Operator{Type: Simple, Operand: OpDstHost, Sensitive: sensitive, Data: randItem(r, indexHosts)}
+		case 2:
+			return Operator{Type: Simple, Operand: OpDstIP, Sensitive: sensitive, Data: randItem(r, indexIPs)}
+		default:
+			return Operator{Type: Simple, Operand: OpDstPort, Sensitive: sensitive, Data: randItem(r, indexPorts)}
+		}
+	}
+}
+
+// findFirstMatchLinear is the reference implementation of FindFirstMatch,
+// evaluating every rule.
+func findFirstMatchLinear(l *Loader, con *conman.Connection) (match *Rule) {
+	for _, name := range l.rulesKeys {
+		rule := l.rules[name]
+		if rule.Enabled == false {
+			continue
+		}
+		if rule.Match(con) {
+			match = rule
+			if rule.Action == Deny || rule.Precedence == true {
+				return rule
+			}
+		}
+	}
+	return match
+}
+
+func TestRuleIndex(t *testing.T) {
+	t.Log("Test rules index")
+	r := rand.New(rand.NewSource(1))
+
+	for round := 0; round < 20; round++ {
+		l, err := NewLoader(false)
+		if err != nil {
+			t.Fatal("NewLoader() error:", err)
+		}
+		for i := 0; i < 200; i++ {
+			op := randIndexOperator(r, 0)
+			if err := op.Compile(); err != nil {
+				t.Fatal("Compile() error:", err, op)
+			}
+			action := Allow
+			if r.Intn(3) == 0 {
+				action = Deny
+			}
+			rule := Create(fmt.Sprintf("%03d-%d", r.Intn(1000), i), r.Intn(10) != 0, r.Intn(5) == 0, action, Restart, &op)
+			rule.Priority = r.Intn(3) - 1
+			l.Add(rule, false)
+		}
+
+		for i := 0; i < 500; i++ {
+			con := &conman.Connection{
+				Protocol: "TCP",
+				DstIP:    net.ParseIP(randItem(r, indexIPs)),
+				DstHost:  randItem(r, indexHosts),
+				Process:  &procmon.Process{Path: randItem(r, indexPaths)},
+				Entry:    &netstat.Entry{},
+			}
+			fmt.Sscanf(randItem(r, indexPorts), "%d", &con.DstPort)
+			if r.Intn(5) == 0 {
+				con.DstHost = ""
+			}
+
+			expected := findFirstMatchLinear(l, con)
+			match := l.FindFirstMatch(con)
+			if match != expected {
+				t.Fatalf("Index verdict differs from linear scan for %s %s %s:%d: %v != %v",
+					con.Process.Path, con.DstHost, con.DstIP, con.DstPort, match, expected)
+			}
+		}
+	}
+}
+
+func TestRuleIndexDelete(t *testing.T) {
+	t.Log("Test rules index after deleting rules")
+	var list []Operator
+	l, err := NewLoader(false)
+	if err != nil {
+		t.Fatal("NewLoader() error:", err)
+	}
+	hostOper, _ := NewOperator(Simple, false, OpDstHost, defaultDstHost, list)
+	netOper, _ := NewOperator(Network, false, OpDstNetwork, "185.53.178.0/24", list)
+	l.Add(Create("000-deny-host", true, false, Deny, Restart, hostOper), false)
+	l.Add(Create("001-deny-net", true, false, Deny, Restart, netOper), false)
+
+	if match := l.FindFirstMatch(conn); match == nil || match.Name != "000-deny-host" {
+		t.Error("Indexed host rule didn't match:", match)
+	}
+	l.Delete("000-deny-host")
+	if match := l.FindFirstMatch(conn); match == nil || match.Name != "001-deny-net" {
+		t.Error("Indexed network rule didn't match:", match)
+	}
+	l.Delete("001-deny-net")
+	if match := l.FindFirstMatch(conn); match != nil {
+		t.Error("Deleted rule matched:", match)
+	}
+}
+
+func TestFoldKey(t *testing.T) {
+	pairs := [][]string{
+		{"OpenSnitch.io", "opensnitch.IO"},
+		{"K", "k"}, // kelvin sign
+		{"ſite", "SITE"},
+	}
+	for _, p := range pairs {
+		if !strings.EqualFold(p[0], p[1]) {
+			t.Fatal("Test pair is not EqualFold:", p)
+		}
+		if foldKey(p[0]) != foldKey(p[1]) {
+			t.Error("foldKey() differs:", p, foldKey(p[0]), foldKey(p[1]))
+		}
+	}
+	if foldKey("ss") == foldKey("ß") {
+		t.Error("foldKey() ss == ß")
+	}
+}
@@ -0,0 +1,238 @@
+package rule
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/evilsocket/opensnitch/daemon/conman"
+)
+
+// Ask is the action of a Limit that asks the user what to do with the
+// connections that exceed it.
+const Ask = Action("ask")
+
+// max number of processes whose usage of the rules is tracked.
+var maxLimitStates = 1024
+
+// Limit restricts how much an allow rule can be used by a process.
+// The usage is tracked per process path, so a process can't avoid the limit
+// by spawning new instances of itself.
+//
+// Connections limits the new connections per Interval (a Go duration, like "1m").
+// BytesPerDay limits the bytes sent and received per day by the process, in
+// the connections allowed by the rules. The bytes of a connection are counted
+// once it's closed, as reported by conntrack, so the bytes quota needs the
+// traffic accounting to be enabled (-traffic-accounting).
+//
+// When any of the limits is exceeded, the connections are denied, rejected if
+// Exceeded is "reject", or the user is asked what to do if it's "ask".
+type Limit struct {
+	Connections int    `json:"connections,omitempty"`
+	Interval    string `json:"interval,omitempty"`
+	BytesPerDay uint64 `json:"bytes_per_day,omitempty"`
+	Exceeded    Action `json:"exceeded,omitempty"`
+
+	interval time.Duration
+}
+
+// Compile validates the limit.
+func (lm *Limit) Compile() error {
+	if lm.Connections < 0 {
+		return fmt.Errorf("Invalid limit of connections: %d", lm.Connections)
+	}
+	if lm.Connections > 0 {
+		interval, err := time.ParseDuration(lm.Interval)
+		if err != nil {
+			return fmt.Errorf("Invalid limit interval '%s': %s", lm.Interval, err)
+		}
+		if interval <= 0 {
+			return fmt.Errorf("Invalid limit interval '%s'", lm.Interval)
+		}
+		lm.interval = interval
+	}
+	if lm.Connections == 0 && lm.BytesPerDay == 0 {
+		return fmt.Errorf("Limit without connections nor bytes_per_day")
+	}
+	if lm.Exceeded == "" {
+		lm.Exceeded = Deny
+	}
+	if lm.Exceeded != Deny && lm.Exceeded != Reject && lm.Exceeded != Ask {
+		return fmt.Errorf("Invalid limit action '%s', expected deny, reject or ask", lm.Exceeded)
+	}
+	return nil
+}
+
+func (lm *Limit) String() string {
+	s := ""
+	if lm.Connections > 0 {
+		s = fmt.Sprintf("%d connections/%s ", lm.Connections, lm.interval)
+	}
+	if lm.BytesPerDay > 0 {
+		s += fmt.Sprintf("%d bytes/day ", lm.BytesPerDay)
+	}
+	return s + "then " + string(lm.Exceeded)
+}
+
+// AddTraffic adds the bytes transferred by a connection allowed by the rule,
+// once it's closed, to the traffic of its process counted by the bytes quotas.
+func (r *Rule) AddTraffic(processPath string, bytes uint64) {
+	if r.limits != nil {
+		r.limits.addTraffic(processPath, bytes, time.Now())
+	}
+}
+
+// limitState is the usage of a rule by a process.
+type limitState struct {
+	windowStart time.Time
+	connections int
+	lastUsed    time.Time
+}
+
+// processTraffic is the traffic of a process today, only tracked while
+// it's matched by rules with a bytes quota.
+type processTraffic struct {
+	day      string
+	bytes    uint64
+	lastUsed time.Time
+}
+
+// limiter tracks the usage of the rules with limits.
+type limiter struct {
+	sync.Mutex
+	states  map[string]*limitState
+	traffic map[string]*processTraffic
+}
+
+func newLimiter() *limiter {
+	return &limiter{
+		states:  make(map[string]*limitState),
+		traffic: make(map[string]*processTraffic),
+	}
+}
+
+// exceeded records a new connection of the process to the rule, and returns
+// true if the process has exceeded the limits of the rule.
+func (lt *limiter) exceeded(r *Rule, con *conman.Connection, now time.Time) bool {
+	lm := r.Limit
+	if lm == nil || con.Process == nil {
+		return false
+	}
+
+	lt.Lock()
+	defer lt.Unlock()
+
+	exceeded := false
+	if lm.Connections > 0 {
+		key := r.Name + "|" + con.Process.Path
+		st, found := lt.states[key]
+		if !found {
+			if len(lt.states) >= maxLimitStates {
+				lt.expire(now)
+			}
+			st = &limitState{windowStart: now}
+			lt.states[key] = st
+		}
+		st.lastUsed = now
+		if now.Sub(st.windowStart) >= lm.interval {
+			st.windowStart = now
+			st.connections = 0
+		}
+		st.connections++
+		exceeded = st.connections > lm.Connections
+	}
+
+	if lm.BytesPerDay > 0 {
+		tr := lt.processTraffic(con.Process.Path, now)
+		exceeded = exceeded || tr.bytes > lm.BytesPerDay
+	}
+
+	return exceeded
+}
+
+// processTraffic returns the traffic of the process today, tracking it
+// if it wasn't.
+func (lt *limiter) processTraffic(path string, now time.Time) *processTraffic {
+	tr, found := lt.traffic[path]
+	if !found {
+		if len(lt.traffic) >= maxLimitStates {
+			lt.expire(now)
+		}
+		tr = &processTraffic{}
+		lt.traffic[path] = tr
+	}
+	if today := now.Format("2006-01-02"); tr.day != today {
+		tr.day = today
+		tr.bytes = 0
+	}
+	tr.lastUsed = now
+	return tr
+}
+
+// addTraffic adds the bytes of a connection closed to the traffic of the
+// process, if a rule with a bytes quota has matched it.
+func (lt *limiter) addTraffic(path string, bytes uint64, now time.Time) {
+	lt.Lock()
+	defer lt.Unlock()
+
+	if _, found := lt.traffic[path]; found {
+		lt.processTraffic(path, now).bytes += bytes
+	}
+}
+
+// expire deletes the states that don't limit anything anymore and, if there're
+// still too many, the least recently used ones.
+func (lt *limiter) expire(now time.Time) {
+	today := now.Format("2006-01-02")
+	for key, st := range lt.states {
+		if now.Sub(st.lastUsed) >= 24*time.Hour {
+			delete(lt.states, key)
+		}
+	}
+	for path, tr := range lt.traffic {
+		if tr.day != today {
+			delete(lt.traffic, path)
+		}
+	}
+
+	// evict a tenth of the states at once, to not sort them on every connection
+	if len(lt.states) >= maxLimitStates {
+		keys := make([]string, 0, len(lt.states))
+		for key := range lt.states {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return lt.states[keys[i]].lastUsed.Before(lt.states[keys[j]].lastUsed)
+		})
+		for _, key := range keys[:len(keys)-maxLimitStates*9/10] {
+			delete(lt.states, key)
+		}
+	}
+	if len(lt.traffic) >= maxLimitStates {
+		paths := make([]string, 0, len(lt.traffic))
+		for path := range lt.traffic {
+			paths = append(paths, path)
+		}
+		sort.Slice(paths, func(i, j int) bool {
+			return lt.traffic[paths[i]].lastUsed.Before(lt.traffic[paths[j]].lastUsed)
+		})
+		for _, path := range paths[:len(paths)-maxLimitStates*9/10] {
+			delete(lt.traffic, path)
+		}
+	}
+}
+
+// reset forgets the usage of a rule.
+func (lt *limiter) reset(ruleName string) {
+	lt.Lock()
+	defer lt.Unlock()
+
+	prefix := ruleName + "|"
+	for key := range lt.states {
+		if strings.HasPrefix(key, prefix) {
+			delete(lt.states, key)
+		}
+	}
+}
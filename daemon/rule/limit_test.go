@@ -0,0 +1,238 @@
+package rule
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/evilsocket/opensnitch/daemon/conman"
+	"github.com/evilsocket/opensnitch/daemon/procmon"
+)
+
+func newLimitedRule(t *testing.T, limit *Limit) *Rule {
+	var list []Operator
+	op, _ := NewOperator(Simple, false, OpTrue, "", list)
+	r := Create("000-limited", true, false, Allow, Restart, op)
+	r.Limit = limit
+	if err := r.Compile(); err != nil {
+		t.Fatal("Rule Compile() error:", err)
+	}
+	return r
+}
+
+func TestLimitConnections(t *testing.T) {
+	t.Log("Test rules limit of connections")
+	r := newLimitedRule(t, &Limit{Connections: 3, Interval: "1m"})
+	lt := newLimiter()
+	now := time.Now()
+
+	for i := 1; i <= 3; i++ {
+		if lt.exceeded(r, conn, now) {
+			t.Error("Limit exceeded before the limit:", i)
+		}
+	}
+	if !lt.exceeded(r, conn, now.Add(time.Second)) {
+		t.Error("Limit not exceeded")
+	}
+
+	other := &conman.Connection{Process: procmon.NewProcess(1, "/usr/bin/other"), Entry: conn.Entry}
+	if lt.exceeded(r, other, now.Add(time.Second)) {
+		t.Error("Limit exceeded by another process")
+	}
+
+	if lt.exceeded(r, conn, now.Add(time.Minute)) {
+		t.Error("Limit not reset after the interval")
+	}
+
+	lt.reset(r.Name)
+	if len(lt.states) != 0 {
+		t.Error("Limit states not reset:", lt.states)
+	}
+}
+
+func TestLimitBytes(t *testing.T) {
+	t.Log("Test rules limit of bytes per day")
+	r := newLimitedRule(t, &Limit{BytesPerDay: 4096, Exceeded: Ask})
+	lt := newLimiter()
+	now := time.Now()
+
+	// the traffic of the processes not limited is not tracked
+	lt.addTraffic(conn.Process.Path, 8192, now)
+	if lt.exceeded(r, conn, now) {
+		t.Error("Limit exceeded by the first connection")
+	}
+	lt.addTraffic(conn.Process.Path, 2048, now)
+	if lt.exceeded(r, conn, now) {
+		t.Error("Limit exceeded before the limit")
+	}
+	lt.addTraffic(conn.Process.Path, 4096, now)
+	if !lt.exceeded(r, conn, now) {
+		t.Error("Limit of bytes not exceeded")
+	}
+	if lt.exceeded(r, conn, now.Add(24*time.Hour)) {
+		t.Error("Limit of bytes not reset the next day")
+	}
+
+	// the traffic of a process is counted by all the rules
+	r2 := newLimitedRule(t, &Limit{BytesPerDay: 1024})
+	lt.addTraffic(conn.Process.Path, 2048, now.Add(24*time.Hour))
+	if !lt.exceeded(r2, conn, now.Add(24*time.Hour)) {
+		t.Error("Limit of bytes not exceeded by the traffic of the process")
+	}
+}
+
+func TestLimitExpire(t *testing.T) {
+	t.Log("Test eviction of the least recently used limits")
+	oldMax := maxLimitStates
+	maxLimitStates = 10
+	defer func() { maxLimitStates = oldMax }()
+
+	r := newLimitedRule(t, &Limit{Connections: 1, Interval: "1h"})
+	lt := newLimiter()
+	now := time.Now()
+
+	// the limit of the first process is exceeded, and used again later
+	first := &conman.Connection{Process: procmon.NewProcess(1, "/usr/bin/first"), Entry: conn.Entry}
+	lt.exceeded(r, first, now)
+	for i := 0; i < maxLimitStates-1; i++ {
+		other := &conman.Connection{Process: procmon.NewProcess(i+2, fmt.Sprint("/usr/bin/other", i)), Entry: conn.Entry}
+		lt.exceeded(r, other, now.Add(time.Duration(i+1)*time.Second))
+	}
+	if !lt.exceeded(r, first, now.Add(time.Minute)) {
+		t.Error("Limit not exceeded")
+	}
+
+	// evicts the least recently used processes, not all of them
+	last := &conman.Connection{Process: procmon.NewProcess(100, "/usr/bin/last"), Entry: conn.Entry}
+	lt.exceeded(r, last, now.Add(2*time.Minute))
+	if len(lt.states) > maxLimitStates || len(lt.states) < maxLimitStates/2 {
+		t.Error("Unexpected number of limits after evicting:", len(lt.states))
+	}
+	if _, found := lt.states[r.Name+"|/usr/bin/other0"]; found {
+		t.Error("Least recently used limit not evicted")
+	}
+	if !lt.exceeded(r, first, now.Add(3*time.Minute)) {
+		t.Error("Limit of the process recently used reset")
+	}
+}
+
+func TestLimitCompile(t *testing.T) {
+	t.Log("Test rules limit validation")
+	var list []Operator
+	op, _ := NewOperator(Simple, false, OpTrue, "", list)
+
+	invalid := []*Limit{
+		{},
+		{Connections: 10},
+		{Connections: 10, Interval: "1 minute"},
+		{Connections: 10, Interval: "-1m"},
+		{Connections: -1, BytesPerDay: 10},
+		{BytesPerDay: 10, Exceeded: "allow"},
+	}
+	for _, limit := range invalid {
+		r := Create("000-limited", true, false, Allow, Restart, op)
+		r.Limit = limit
+		if err := r.Compile(); err == nil {
+			t.Error("Rule Compile() should fail:", limit)
+		}
+	}
+
+	r := Create("000-limited", true, false, Deny, Restart, op)
+	r.Limit = &Limit{BytesPerDay: 10}
+	if err := r.Compile(); err == nil {
+		t.Error("Rule Compile() of a deny rule with limit should fail")
+	}
+
+	r = newLimitedRule(t, &Limit{BytesPerDay: 10})
+	if r.Limit.Exceeded != Deny {
+		t.Error("Limit default action should be deny:", r.Limit.Exceeded)
+	}
+	r = newLimitedRule(t, &Limit{BytesPerDay: 10, Exceeded: Reject})
+	if r.Limit.Exceeded != Reject {
+		t.Error("Limit action should be reject:", r.Limit.Exceeded)
+	}
+}
+
+func TestLimitSerialize(t *testing.T) {
+	t.Log("Test rules limit serialization")
+	r := newLimitedRule(t, &Limit{Connections: 100, Interval: "10s", BytesPerDay: 1 << 20, Exceeded: Ask})
+
+	r2, err := Deserialize(r.Serialize())
+	if err != nil {
+		t.Fatal("Deserialize() error:", err)
+	}
+	if r2.Limit == nil || r2.Limit.Connections != 100 || r2.Limit.interval != 10*time.Second ||
+		r2.Limit.BytesPerDay != 1<<20 || r2.Limit.Exceeded != Ask {
+		t.Error("Limit not deserialized:", r2.Limit)
+	}
+
+	raw, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal("Error serializing rule:", err)
+	}
+	var r3 Rule
+	if err := json.Unmarshal(raw, &r3); err != nil {
+		t.Fatal("Error parsing rule:", err)
+	}
+	if err := r3.Compile(); err != nil {
+		t.Fatal("Rule Compile() error:", err)
+	}
+	if r3.Limit == nil || *r3.Limit != *r.Limit {
+		t.Error("Limit not serialized to JSON:", string(raw))
+	}
+}
+
+func TestLoaderLimitExceeded(t *testing.T) {
+	t.Log("Test loader limits")
+	l, err := NewLoader(false)
+	if err != nil {
+		t.Fatal("NewLoader() error:", err)
+	}
+	r := newLimitedRule(t, &Limit{Connections: 1, Interval: "1h"})
+	l.Add(r, false)
+
+	if match, exceeded := l.FindMatchWithLimits(conn); match != r || exceeded != nil {
+		t.Error("Limit exceeded by the first connection")
+	}
+	if _, exceeded := l.FindMatchWithLimits(conn); exceeded != r {
+		t.Error("Limit not exceeded by the second connection")
+	}
+	if match := l.FindFirstMatch(conn); match != r {
+		t.Error("FindFirstMatch() should not check the limits:", match)
+	}
+
+	l.Replace(newLimitedRule(t, &Limit{Connections: 1, Interval: "1h"}), false)
+	if _, exceeded := l.FindMatchWithLimits(conn); exceeded != nil {
+		t.Error("Limit not reset after replacing the rule")
+	}
+
+	// an allow rule without limit matching later doesn't bypass the limit
+	var list []Operator
+	op, _ := NewOperator(Simple, false, OpTrue, "", list)
+	l.Add(Create("001-unlimited", true, false, Allow, Restart, op), false)
+	if match, exceeded := l.FindMatchWithLimits(conn); match == nil || match.Name != "001-unlimited" || exceeded == nil || exceeded.Name != "000-limited" {
+		t.Error("Limit bypassed by a rule without limit:", match, exceeded)
+	}
+}
+
+func TestRuleAddTraffic(t *testing.T) {
+	t.Log("Test accounting the traffic of the rules")
+	l, err := NewLoader(false)
+	if err != nil {
+		t.Fatal("NewLoader() error:", err)
+	}
+	r := newLimitedRule(t, &Limit{BytesPerDay: 1024})
+	l.Add(r, false)
+
+	if _, exceeded := l.FindMatchWithLimits(conn); exceeded != nil {
+		t.Error("Limit exceeded by the first connection")
+	}
+	r.AddTraffic(conn.Process.Path, 2048)
+	if _, exceeded := l.FindMatchWithLimits(conn); exceeded != r {
+		t.Error("Limit of bytes not exceeded")
+	}
+
+	// rules not loaded don't account anything
+	newLimitedRule(t, &Limit{BytesPerDay: 1024}).AddTraffic(conn.Process.Path, 2048)
+}
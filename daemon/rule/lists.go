@@ -0,0 +1,225 @@
+package rule
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/evilsocket/opensnitch/daemon/log"
+)
+
+// names of hosts files entries that are not domains to block.
+var hostsLocalNames = map[string]bool{
+	"localhost":             true,
+	"localhost.localdomain": true,
+	"local":                 true,
+	"broadcasthost":         true,
+	"ip6-localhost":         true,
+	"ip6-loopback":          true,
+	"ip6-localnet":          true,
+	"ip6-mcastprefix":       true,
+	"ip6-allnodes":          true,
+	"ip6-allrouters":        true,
+	"ip6-allhosts":          true,
+	"0.0.0.0":               true,
+}
+
+// domainsList is a set of domains loaded from a file.
+//
+// The file can be in hosts format (0.0.0.0 ads.example.com), or have one
+// domain per line. Lines starting with # are comments.
+// A domain matches itself, and a wildcard domain (*.example.com or
+// .example.com) matches any subdomain of it.
+type domainsList struct {
+	sync.RWMutex
+	path      string
+	domains   map[string]struct{}
+	wildcards map[string]struct{}
+}
+
+func newDomainsList(path string) (*domainsList, error) {
+	dl := &domainsList{path: path}
+	if err := dl.load(); err != nil {
+		return nil, err
+	}
+	return dl, nil
+}
+
+// load (re)reads the domains from disk. On error, the domains loaded
+// previously are kept.
+func (dl *domainsList) load() error {
+	f, err := os.Open(dl.path)
+	if err != nil {
+		return fmt.Errorf("Error opening domains list %s: %s", dl.path, err)
+	}
+	defer f.Close()
+
+	domains := make(map[string]struct{})
+	wildcards := make(map[string]struct{})
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i != -1 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		// hosts format: ip name [name...]
+		if net.ParseIP(fields[0]) != nil {
+			fields = fields[1:]
+		}
+		for _, name := range fields {
+			name = normalizeDomain(name)
+			if name == "" || hostsLocalNames[name] {
+				continue
+			}
+			if strings.HasPrefix(name, "*.") {
+				wildcards[name[2:]] = struct{}{}
+			} else if strings.HasPrefix(name, ".") {
+				wildcards[name[1:]] = struct{}{}
+			} else {
+				domains[name] = struct{}{}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("Error reading domains list %s: %s", dl.path, err)
+	}
+
+	dl.Lock()
+	dl.domains = domains
+	dl.wildcards = wildcards
+	dl.Unlock()
+
+	log.Debug("Loaded %d domains and %d wildcards from %s", len(domains), len(wildcards), dl.path)
+	return nil
+}
+
+// contains returns true if the host is in the list, or is a subdomain of
+// a wildcard domain of the list.
+func (dl *domainsList) contains(host string) bool {
+	host = normalizeDomain(host)
+	if host == "" {
+		return false
+	}
+
+	dl.RLock()
+	defer dl.RUnlock()
+
+	if _, found := dl.domains[host]; found {
+		return true
+	}
+	for i := 0; i < len(host); i++ {
+		if host[i] != '.' {
+			continue
+		}
+		if _, found := dl.wildcards[host[i+1:]]; found {
+			return true
+		}
+	}
+	return false
+}
+
+// ipsList is a set of IPs and networks loaded from a file, like the FireHOL
+// or Spamhaus DROP lists.
+//
+// The file has one IP or CIDR per line. Anything after the first field is
+// ignored, as well as lines starting with # or ;.
+type ipsList struct {
+	sync.RWMutex
+	path string
+	ipv4 *netTrie
+	ipv6 *netTrie
+}
+
+func newIPsList(path string) (*ipsList, error) {
+	il := &ipsList{path: path}
+	if err := il.load(); err != nil {
+		return nil, err
+	}
+	return il, nil
+}
+
+// load (re)reads the networks from disk. On error, the networks loaded
+// previously are kept.
+func (il *ipsList) load() error {
+	f, err := os.Open(il.path)
+	if err != nil {
+		return fmt.Errorf("Error opening IPs list %s: %s", il.path, err)
+	}
+	defer f.Close()
+
+	ipv4 := &netTrie{}
+	ipv6 := &netTrie{}
+	count := 0
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0][0] == '#' || fields[0][0] == ';' {
+			continue
+		}
+		ip, ones, ok := parseNetwork(fields[0])
+		if !ok {
+			log.Warning("Invalid IP or network in %s:%d: %s", il.path, n, fields[0])
+			continue
+		}
+		if len(ip) == net.IPv4len {
+			ipv4.insert(ip, ones)
+		} else {
+			ipv6.insert(ip, ones)
+		}
+		count++
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("Error reading IPs list %s: %s", il.path, err)
+	}
+
+	il.Lock()
+	il.ipv4 = ipv4
+	il.ipv6 = ipv6
+	il.Unlock()
+
+	log.Debug("Loaded %d IPs and networks from %s", count, il.path)
+	return nil
+}
+
+// contains returns true if the ip is in any of the networks of the list.
+func (il *ipsList) contains(ip net.IP) bool {
+	il.RLock()
+	defer il.RUnlock()
+
+	if ip4 := ip.To4(); ip4 != nil {
+		return il.ipv4.contains(ip4)
+	} else if len(ip) == net.IPv6len {
+		return il.ipv6.contains(ip)
+	}
+	return false
+}
+
+// parseNetwork returns the address and prefix length of an IP or CIDR.
+func parseNetwork(s string) (net.IP, int, bool) {
+	if strings.IndexByte(s, '/') != -1 {
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, 0, false
+		}
+		return networkPrefix(n)
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, 0, false
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4, 8 * net.IPv4len, true
+	}
+	return ip, 8 * net.IPv6len, true
+}
+
+func normalizeDomain(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
@@ -0,0 +1,227 @@
+package rule
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var domainsListData = `# ads
+127.0.0.1 localhost
+::1 localhost ip6-localhost
+0.0.0.0 ads.example.com tracker.example.com # inline comment
+*.doubleclick.net
+.Metrics.Example.ORG
+plain.example.net.
+`
+
+func writeList(t *testing.T, path, data string) {
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal("Error writing list:", err)
+	}
+}
+
+func TestDomainsList(t *testing.T) {
+	t.Log("Test domains list")
+	listPath := filepath.Join(tmpDir, "domains-list.txt")
+	writeList(t, listPath, domainsListData)
+
+	dl, err := newDomainsList(listPath)
+	if err != nil {
+		t.Fatal("newDomainsList() error:", err)
+	}
+
+	tests := map[string]bool{
+		"ads.example.com":            true,
+		"ADS.example.com.":           true,
+		"tracker.example.com":        true,
+		"sub.ads.example.com":        false,
+		"example.com":                false,
+		"ad.doubleclick.net":         true,
+		"a.b.doubleclick.net":        true,
+		"doubleclick.net":            false,
+		"notdoubleclick.net":         false,
+		"x.metrics.example.org":      true,
+		"plain.example.net":          true,
+		"localhost":                  false,
+		"ip6-localhost":              false,
+		"":                           false,
+		"inline":                     false,
+		"comment":                    false,
+		"127.0.0.1":                  false,
+		"evil.doubleclick.net.co.uk": false,
+	}
+	for host, expected := range tests {
+		if dl.contains(host) != expected {
+			t.Error("domainsList.contains() unexpected result:", host, !expected)
+		}
+	}
+
+	if _, err := newDomainsList("/non/existent/list"); err == nil {
+		t.Error("newDomainsList() of a non existent file should fail")
+	}
+}
+
+func TestNewOperatorDomainsList(t *testing.T) {
+	t.Log("Test NewOperator() lists.domains")
+	var list []Operator
+	listPath := filepath.Join(tmpDir, "domains-operator.txt")
+	writeList(t, listPath, "0.0.0.0 www.opensnitch.io\n*.opensnitch.io\n")
+
+	op, err := NewOperator(Lists, false, OpDomainsLists, listPath, list)
+	if err != nil {
+		t.Fatal("NewOperator() lists.domains error:", err)
+	}
+	conn.DstHost = "opensnitch.io"
+	if op.Match(conn) {
+		t.Error("lists.domains should not match the parent domain of a wildcard")
+	}
+	conn.DstHost = "api.opensnitch.io"
+	if !op.Match(conn) {
+		t.Error("lists.domains should match a subdomain")
+	}
+	conn.DstHost = ""
+	if op.Match(conn) {
+		t.Error("lists.domains should not match an empty host")
+	}
+	restoreConnection()
+
+	if _, err := NewOperator(Lists, false, Operand("lists.unknown"), listPath, list); err == nil {
+		t.Error("NewOperator() of an unknown list should fail")
+	}
+}
+
+func TestDomainsListLiveReload(t *testing.T) {
+	t.Log("Test domains list live reload")
+	rulesDir, err := ioutil.TempDir("", "opensnitch-lists-rules")
+	if err != nil {
+		t.Fatal("Error creating temp dir:", err)
+	}
+	defer os.RemoveAll(rulesDir)
+	listsDir, err := ioutil.TempDir("", "opensnitch-lists")
+	if err != nil {
+		t.Fatal("Error creating temp dir:", err)
+	}
+	defer os.RemoveAll(listsDir)
+
+	listPath := filepath.Join(listsDir, "ads.txt")
+	writeList(t, listPath, "ads.example.com\n")
+
+	r := Create("000-deny-ads", true, false, Deny, Always, &Operator{
+		Type:    Lists,
+		Operand: OpDomainsLists,
+		Data:    listPath,
+	})
+	raw, _ := json.Marshal(r)
+	writeList(t, filepath.Join(rulesDir, "000-deny-ads.json"), string(raw))
+
+	l, err := NewLoader(true)
+	if err != nil {
+		t.Fatal("NewLoader() error:", err)
+	}
+	if err = l.Load(rulesDir); err != nil {
+		t.Fatal("Error loading rules:", err)
+	}
+	testNumRules(t, l, 1)
+
+	conn.DstHost = "ads.example.com"
+	if match := l.FindFirstMatch(conn); match == nil || match.Name != "000-deny-ads" {
+		t.Error("Domains list rule didn't match:", match)
+	}
+
+	//wait for watcher to activate
+	time.Sleep(time.Second)
+	writeList(t, listPath, "*.tracker.example.com\n")
+	time.Sleep(time.Second)
+
+	if match := l.FindFirstMatch(conn); match != nil {
+		t.Error("Domain removed from the list matched:", match)
+	}
+	conn.DstHost = "a.tracker.example.com"
+	if match := l.FindFirstMatch(conn); match == nil {
+		t.Error("Domain added to the list didn't match")
+	}
+	restoreConnection()
+}
+
+var ipsListData = `# firehol
+; Spamhaus DROP List
+1.10.16.0/20 ; SBL256894
+185.53.178.14
+10.0.0.0/8
+::ffff:192.168.0.0/112
+2001:db8::/32
+invalid
+300.1.1.1/24
+`
+
+func TestIPsList(t *testing.T) {
+	t.Log("Test IPs list")
+	listPath := filepath.Join(tmpDir, "ips-list.txt")
+	writeList(t, listPath, ipsListData)
+
+	il, err := newIPsList(listPath)
+	if err != nil {
+		t.Fatal("newIPsList() error:", err)
+	}
+
+	tests := map[string]bool{
+		"1.10.16.1":           true,
+		"1.10.31.255":         true,
+		"1.10.32.0":           false,
+		"185.53.178.14":       true,
+		"185.53.178.15":       false,
+		"10.255.0.1":          true,
+		"::ffff:10.1.1.1":     true,
+		"192.168.4.4":         true,
+		"192.169.0.1":         false,
+		"2001:db8:1::1":       true,
+		"2001:db9::1":         false,
+		"8.8.8.8":             false,
+		"::1":                 false,
+		"2a00:1450:4003::200": false,
+	}
+	for ip, expected := range tests {
+		if il.contains(net.ParseIP(ip)) != expected {
+			t.Error("ipsList.contains() unexpected result:", ip, !expected)
+		}
+	}
+	if il.contains(nil) {
+		t.Error("ipsList.contains() matched a nil IP")
+	}
+}
+
+func TestNewOperatorIPsList(t *testing.T) {
+	t.Log("Test NewOperator() lists.ips")
+	var list []Operator
+	listPath := filepath.Join(tmpDir, "ips-operator.txt")
+	writeList(t, listPath, "185.53.178.0/24\n")
+
+	op, err := NewOperator(Lists, false, OpIPLists, listPath, list)
+	if err != nil {
+		t.Fatal("NewOperator() lists.ips error:", err)
+	}
+	if !op.Match(conn) {
+		t.Error("lists.ips should match", conn.DstIP)
+	}
+
+	writeList(t, listPath, "8.8.8.8\n")
+	if err := op.reloadList(); err != nil {
+		t.Fatal("reloadList() error:", err)
+	}
+	if op.Match(conn) {
+		t.Error("lists.ips should not match after reloading", conn.DstIP)
+	}
+
+	os.Remove(listPath)
+	if err := op.reloadList(); err == nil {
+		t.Error("reloadList() of a deleted file should fail")
+	}
+	if _, err := NewOperator(Lists, false, OpIPLists, listPath, list); err == nil {
+		t.Error("NewOperator() of a non existent list should fail")
+	}
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/evilsocket/opensnitch/daemon/conman"
 	"github.com/evilsocket/opensnitch/daemon/core"
 	"github.com/evilsocket/opensnitch/daemon/log"
+	"github.com/evilsocket/opensnitch/daemon/procmon"
 
 	"github.com/fsnotify/fsnotify"
 )
@@ -26,6 +27,10 @@ type Loader struct {
 	path              string
 	rules             map[string]*Rule
 	rulesKeys         []string
+	index             *ruleIndex
+	lists             map[string][]*Operator
+	listsDirs         map[string]bool
+	limits            *limiter
 	watcher           *fsnotify.Watcher
 	liveReload        bool
 	liveReloadRunning bool
@@ -41,6 +46,8 @@ func NewLoader(liveReload bool) (*Loader, error) {
 	return &Loader{
 		path:              "",
 		rules:             make(map[string]*Rule),
+		listsDirs:         make(map[string]bool),
+		limits:            newLimiter(),
 		liveReload:        liveReload,
 		watcher:           watcher,
 		liveReloadRunning: false,
@@ -90,7 +97,12 @@ func (l *Loader) Load(path string) error {
 			continue
 		}
 
-		r.Operator.Compile()
+		// a rule which doesn't compile could never match (invalid regexp,
+		// list, schedule...), so it's not loaded, and the error is logged.
+		if err := r.Compile(); err != nil {
+			log.Error("Error compiling rule from %s: %s", fileName, err)
+			continue
+		}
 		diskRules[r.Name] = r.Name
 
 		log.Debug("Loaded rule from %s: %s", fileName, r.String())
@@ -126,7 +138,22 @@ func (l *Loader) liveReloadWorker() {
 
 	for {
 		select {
-		case event := <-l.watcher.Events:
+		case event, ok := <-l.watcher.Events:
+			if !ok {
+				return
+			}
+			// a list used by a rule has been created or updated
+			if ops := l.getListOperators(event.Name); len(ops) > 0 {
+				if (event.Op&fsnotify.Write == fsnotify.Write) || (event.Op&fsnotify.Create == fsnotify.Create) {
+					log.Important("List %s changed, reloading ...", event.Name)
+					for _, op := range ops {
+						if err := op.reloadList(); err != nil {
+							log.Error("%s", err)
+						}
+					}
+				}
+				continue
+			}
 			// a new rule json file has been created or updated
 			if (event.Op&fsnotify.Write == fsnotify.Write) || (event.Op&fsnotify.Remove == fsnotify.Remove) {
 				if strings.HasSuffix(event.Name, ".json") {
@@ -136,12 +163,22 @@ func (l *Loader) liveReloadWorker() {
 					}
 				}
 			}
-		case err := <-l.watcher.Errors:
+		case err, ok := <-l.watcher.Errors:
+			if !ok {
+				return
+			}
 			log.Error("File system watcher error: %s", err)
 		}
 	}
 }
 
+// Close stops watching the rules and lists for changes, and stops computing
+// the checksums used only by its rules.
+func (l *Loader) Close() {
+	l.watcher.Close()
+	procmon.EnableChecksums(l)
+}
+
 // Reload reloads the rules from disk.
 func (l *Loader) Reload() error {
 	return l.Load(l.path)
@@ -154,6 +191,13 @@ func (l *Loader) GetAll() map[string]*Rule {
 	return l.rules
 }
 
+// Get returns the rule with the given name, or nil if it's not loaded.
+func (l *Loader) Get(name string) *Rule {
+	l.RLock()
+	defer l.RUnlock()
+	return l.rules[name]
+}
+
 func (l *Loader) isUniqueName(name string) bool {
 	_, found := l.rules[name]
 	return !found
@@ -171,12 +215,107 @@ func (l *Loader) setUniqueName(rule *Rule) {
 	}
 }
 
+// sortRules sorts the rules by priority, and by name the ones with the same priority.
+// Names are unique, so the order is always the same.
+// The index of the rules and the lists are rebuilt afterwards.
 func (l *Loader) sortRules() {
 	l.rulesKeys = make([]string, 0, len(l.rules))
-	for k := range l.rules {
+	for k, r := range l.rules {
 		l.rulesKeys = append(l.rulesKeys, k)
+		// set once, before the rule is used, as it's read without the lock
+		if r.limits == nil {
+			r.limits = l.limits
+		}
+	}
+	sort.Slice(l.rulesKeys, func(i, j int) bool {
+		pi, pj := l.rules[l.rulesKeys[i]].Priority, l.rules[l.rulesKeys[j]].Priority
+		if pi != pj {
+			return pi < pj
+		}
+		return l.rulesKeys[i] < l.rulesKeys[j]
+	})
+	l.index = newRuleIndex(l.rulesKeys, l.rules)
+	l.updateLists()
+	l.updateChecksums()
+}
+
+// updateChecksums enables the checksums of the processes used by the rules,
+// hashing the executables only while there're rules with hash operands, in
+// this loader or any other.
+func (l *Loader) updateChecksums() {
+	algos := make(map[string]bool)
+	for _, r := range l.rules {
+		addChecksumOperands(&r.Operator, algos)
+	}
+	enabled := make([]string, 0, len(algos))
+	for algo := range algos {
+		enabled = append(enabled, algo)
+	}
+	procmon.EnableChecksums(l, enabled...)
+}
+
+func addChecksumOperands(op *Operator, algos map[string]bool) {
+	switch op.Operand {
+	case OpProcessHashMD5:
+		algos[procmon.HashMD5] = true
+	case OpProcessHashSHA256:
+		algos[procmon.HashSHA256] = true
+	}
+	for i := range op.List {
+		addChecksumOperands(&op.List[i], algos)
+	}
+}
+
+// updateLists collects the operators that use a list file, and watches
+// the directories of the lists in order to reload them when they change.
+func (l *Loader) updateLists() {
+	l.lists = make(map[string][]*Operator)
+	for _, r := range l.rules {
+		l.addListOperators(&r.Operator)
+	}
+	if l.liveReload == false {
+		return
+	}
+
+	dirs := make(map[string]bool)
+	for listPath := range l.lists {
+		dirs[filepath.Dir(listPath)] = true
 	}
-	sort.Strings(l.rulesKeys)
+	for dir := range dirs {
+		if l.listsDirs[dir] {
+			continue
+		}
+		if err := l.watcher.Add(dir); err != nil {
+			log.Error("Could not watch lists path %s: %s", dir, err)
+			continue
+		}
+		l.listsDirs[dir] = true
+	}
+	for dir := range l.listsDirs {
+		if dirs[dir] == false {
+			// the rules path is watched by the liveReloadWorker
+			if dir != filepath.Clean(l.path) {
+				l.watcher.Remove(dir)
+			}
+			delete(l.listsDirs, dir)
+		}
+	}
+}
+
+func (l *Loader) addListOperators(op *Operator) {
+	if op.Type == Lists {
+		listPath := filepath.Clean(op.Data)
+		l.lists[listPath] = append(l.lists[listPath], op)
+	}
+	for i := range op.List {
+		l.addListOperators(&op.List[i])
+	}
+}
+
+func (l *Loader) getListOperators(listPath string) []*Operator {
+	l.RLock()
+	defer l.RUnlock()
+	return l.lists[filepath.Clean(listPath)]
 }
 
 func (l *Loader) addUserRule(rule *Rule) {
@@ -190,14 +329,10 @@ func (l *Loader) addUserRule(rule *Rule) {
 
 func (l *Loader) replaceUserRule(rule *Rule) {
 	l.Lock()
-	if rule.Operator.Type == List {
-		if err := json.Unmarshal([]byte(rule.Operator.Data), &rule.Operator.List); err != nil {
-			log.Error("Error loading rule of type list: %s", err)
-		}
-	}
 	l.rules[rule.Name] = rule
 	l.sortRules()
 	l.Unlock()
+	l.limits.reset(rule.Name)
 
 	if rule.Duration == Restart || rule.Duration == Always {
 		return
@@ -268,6 +403,7 @@ func (l *Loader) Delete(ruleName string) error {
 
 	delete(l.rules, ruleName)
 	l.sortRules()
+	l.limits.reset(ruleName)
 
 	if rule.Duration != Always {
 		return nil
@@ -278,26 +414,66 @@ func (l *Loader) Delete(ruleName string) error {
 	return os.Remove(path)
 }
 
-// FindFirstMatch will try match the connection against the existing rule set.
+// FindFirstMatch evaluates the rules in priority order, and returns the first
+// rule that matches the connection whose verdict is final: any action but
+// Allow (deny, reject, requeue, mark), or a Precedence rule. If there's none, the
+// last Allow rule that matched within the lowest priority with a match is
+// returned: rules of higher priorities are not evaluated.
+// Disabled rules, and rules whose schedule is not active, are skipped.
 func (l *Loader) FindFirstMatch(con *conman.Connection) (match *Rule) {
+	match, _ = l.findMatch(con, false)
+	return match
+}
+
+// FindMatchWithLimits returns the rule that matches the connection, like
+// FindFirstMatch. If it's an Allow rule, the connection is recorded in the
+// limits of every Allow rule that matched with the same priority, so a rule
+// without limit can't be used to bypass the limit of another one.
+// If any of the limits is exceeded, the rule whose limit was exceeded is
+// returned too.
+func (l *Loader) FindMatchWithLimits(con *conman.Connection) (match, exceeded *Rule) {
+	return l.findMatch(con, true)
+}
+
+func (l *Loader) findMatch(con *conman.Connection, checkLimits bool) (match, exceeded *Rule) {
 	l.RLock()
 	defer l.RUnlock()
 
-	for _, idx := range l.rulesKeys {
-		rule, _ := l.rules[idx]
-		if rule.Enabled == false {
+	now := time.Now()
+	var limited []*Rule
+
+	// only the rules that may match the connection are evaluated,
+	// in the same order as they're sorted.
+	for _, pos := range l.index.candidates(con) {
+		rule, _ := l.rules[l.rulesKeys[pos]]
+		// the lowest priority with a match decides the verdict
+		if match != nil && rule.Priority != match.Priority {
+			break
+		}
+		if rule.Enabled == false || rule.IsActive(now) == false {
 			continue
 		}
 		if rule.Match(con) {
 			// We have a match.
 			// Save the rule in order to don't ask the user to take action,
-			// and keep iterating until a Deny or a Priority rule appears.
+			// and keep iterating until a rule other than Allow or a Priority rule appears.
 			match = rule
-			if rule.Action == Deny || rule.Precedence == true {
-				return rule
+			if rule.Limit != nil {
+				limited = append(limited, rule)
+			}
+			if rule.Action != Allow || rule.Precedence == true {
+				break
 			}
 		}
 	}
 
-	return match
+	if checkLimits && match != nil && match.Action == Allow {
+		for _, r := range limited {
+			if l.limits.exceeded(r, con, now) && exceeded == nil {
+				exceeded = r
+			}
+		}
+	}
+
+	return match, exceeded
 }
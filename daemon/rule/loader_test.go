@@ -6,6 +6,9 @@ import (
 	"os"
 	"testing"
 	"time"
+
+	"github.com/evilsocket/opensnitch/daemon/conman"
+	"github.com/evilsocket/opensnitch/daemon/procmon"
 )
 
 var tmpDir string
@@ -97,6 +100,207 @@ func TestLiveReload(t *testing.T) {
 	testNumRules(t, l, 2)
 }
 
+func TestLoadInvalidRule(t *testing.T) {
+	t.Parallel()
+	t.Log("Test loading rules which don't compile")
+
+	l, err := NewLoader(false)
+	if err != nil {
+		t.Fail()
+	}
+	if err = l.Load("testdata/invalid/"); err != nil {
+		t.Error("Error loading test rules: ", err)
+	}
+	testNumRules(t, l, 1)
+	if l.Get("002-invalid-regexp") != nil {
+		t.Error("Rule with an invalid regexp loaded")
+	}
+}
+
+func TestRulePriority(t *testing.T) {
+	t.Parallel()
+	t.Log("Test rules priority")
+
+	var list []Operator
+	chromeOper, _ := NewOperator(Simple, false, OpProcessPath, "/opt/google/chrome/chrome", list)
+	denyChrome := Create("zzz-deny-chrome", true, false, Deny, Restart, chromeOper)
+	denyChrome.Priority = -1
+	allowChrome := Create("aaa-allow-chrome", true, false, Allow, Restart, chromeOper)
+	allowChrome.Priority = 10
+
+	l, err := NewLoader(false)
+	if err != nil {
+		t.Fail()
+	}
+	if err = l.Load("testdata/"); err != nil {
+		t.Error("Error loading test rules: ", err)
+	}
+	if err = l.Add(denyChrome, false); err != nil {
+		t.Error("Error adding rule: ", err)
+	}
+	if err = l.Add(allowChrome, false); err != nil {
+		t.Error("Error adding rule: ", err)
+	}
+
+	expected := []string{"zzz-deny-chrome", "000-allow-chrome", "001-deny-chrome", "aaa-allow-chrome"}
+	for i, name := range expected {
+		if l.rulesKeys[i] != name {
+			t.Error("Rules not in priority order: ", l.rulesKeys)
+			break
+		}
+	}
+
+	conn.Process.Path = "/opt/google/chrome/chrome"
+	defer restoreConnection()
+
+	// zzz-deny-chrome is evaluated before 000-allow-chrome (precedence)
+	if match := l.FindFirstMatch(conn); match == nil || match.Name != "zzz-deny-chrome" {
+		t.Error("Priority rule didn't match first: ", match)
+	}
+
+	// same priority, the order is alphabetical
+	l.Lock()
+	denyChrome.Priority = 0
+	l.sortRules()
+	l.Unlock()
+	if match := l.FindFirstMatch(conn); match == nil || match.Name != "000-allow-chrome" {
+		t.Error("Rules with the same priority not sorted by name: ", match, l.rulesKeys)
+	}
+}
+
+func TestRulePriorityBands(t *testing.T) {
+	t.Parallel()
+	t.Log("Test that the lowest priority with a match decides")
+
+	var list []Operator
+	chromeOper, _ := NewOperator(Simple, false, OpProcessPath, "/opt/google/chrome/chrome", list)
+	allowLow := Create("zzz-allow-chrome-low", true, false, Allow, Restart, chromeOper)
+	allowLow.Priority = -1
+	allowLow2 := Create("aaa-allow-chrome-low", true, false, Allow, Restart, chromeOper)
+	allowLow2.Priority = -1
+	allowHigh := Create("zzz-allow-chrome-high", true, false, Allow, Restart, chromeOper)
+	allowHigh.Priority = 10
+
+	l, err := NewLoader(false)
+	if err != nil {
+		t.Fail()
+	}
+	if err = l.Add(allowHigh, false); err != nil {
+		t.Error("Error adding rule: ", err)
+	}
+	if err = l.Add(allowLow, false); err != nil {
+		t.Error("Error adding rule: ", err)
+	}
+
+	conn.Process.Path = "/opt/google/chrome/chrome"
+	defer restoreConnection()
+
+	if match := l.FindFirstMatch(conn); match == nil || match.Name != "zzz-allow-chrome-low" {
+		t.Error("Rule of the lowest priority didn't decide: ", match)
+	}
+
+	// within a priority, the last allow rule that matches wins
+	if err = l.Add(allowLow2, false); err != nil {
+		t.Error("Error adding rule: ", err)
+	}
+	if match := l.FindFirstMatch(conn); match == nil || match.Name != "zzz-allow-chrome-low" {
+		t.Error("Last rule of the priority didn't win: ", match)
+	}
+
+	// the rules of a priority without matches are skipped
+	l.Lock()
+	allowLow.Enabled = false
+	allowLow2.Enabled = false
+	l.Unlock()
+	if match := l.FindFirstMatch(conn); match == nil || match.Name != "zzz-allow-chrome-high" {
+		t.Error("Rule of the next priority didn't match: ", match)
+	}
+}
+
+func TestFinalActions(t *testing.T) {
+	t.Parallel()
+	t.Log("Test that the actions other than allow are final")
+
+	var list []Operator
+	chromeOper, _ := NewOperator(Simple, false, OpProcessPath, "/opt/google/chrome/chrome", list)
+	con := &conman.Connection{Process: procmon.NewProcess(1, "/opt/google/chrome/chrome"), Entry: conn.Entry}
+
+	for _, action := range []Action{Deny, Reject, Requeue, Mark} {
+		l, err := NewLoader(false)
+		if err != nil {
+			t.Fatal("NewLoader() error:", err)
+		}
+		// evaluated in order of their names
+		l.Add(Create("000-first", true, false, action, Restart, chromeOper), false)
+		l.Add(Create("001-allow-chrome", true, false, Allow, Restart, chromeOper), false)
+
+		if match := l.FindFirstMatch(con); match == nil || match.Name != "000-first" {
+			t.Errorf("Rule %s overridden by an allow rule: %v", action, match)
+		}
+	}
+}
+
+func TestChecksumOperands(t *testing.T) {
+	t.Log("Test collecting the checksums used by the rules")
+
+	op := Operator{Type: And, Operand: OpList, List: []Operator{
+		{Type: Simple, Operand: OpProcessPath, Data: "/usr/bin/curl"},
+		{Type: Not, Operand: OpList, List: []Operator{
+			{Type: Simple, Operand: OpProcessHashSHA256, Data: "abcd"},
+		}},
+	}}
+	algos := make(map[string]bool)
+	addChecksumOperands(&op, algos)
+	if len(algos) != 1 || !algos[procmon.HashSHA256] {
+		t.Error("Unexpected checksums used: ", algos)
+	}
+
+	algos = make(map[string]bool)
+	addChecksumOperands(&op.List[0], algos)
+	if len(algos) != 0 {
+		t.Error("Checksums used without hash operands: ", algos)
+	}
+}
+
+func TestChecksumsLoaders(t *testing.T) {
+	t.Log("Test the checksums used by the rules of several loaders")
+
+	enabled := func(algo string) bool {
+		for _, a := range procmon.EnabledChecksums() {
+			if a == algo {
+				return true
+			}
+		}
+		return false
+	}
+
+	system, _ := NewLoader(false)
+	user, _ := NewLoader(false)
+	system.Add(Create("000-pin-curl", true, false, Deny, Restart,
+		&Operator{Type: Simple, Operand: OpProcessHashSHA256, Data: "abcd"}), false)
+	user.Add(Create("000-allow-curl", true, false, Allow, Restart,
+		&Operator{Type: Simple, Operand: OpProcessPath, Data: "/usr/bin/curl"}), false)
+	if !enabled(procmon.HashSHA256) {
+		t.Error("Checksums of a loader disabled by another loader: ", procmon.EnabledChecksums())
+	}
+
+	user.Add(Create("001-pin-wget", true, false, Allow, Restart,
+		&Operator{Type: Simple, Operand: OpProcessHashMD5, Data: "abcd"}), false)
+	if !enabled(procmon.HashSHA256) || !enabled(procmon.HashMD5) {
+		t.Error("Checksums of the loaders not enabled: ", procmon.EnabledChecksums())
+	}
+
+	user.Close()
+	if !enabled(procmon.HashSHA256) || enabled(procmon.HashMD5) {
+		t.Error("Checksums of a closed loader still enabled: ", procmon.EnabledChecksums())
+	}
+	system.Delete("000-pin-curl")
+	if enabled(procmon.HashSHA256) {
+		t.Error("Checksums enabled without hash operands: ", procmon.EnabledChecksums())
+	}
+	system.Close()
+}
+
 func randString() string {
 	rand.Seed(time.Now().UnixNano())
 	var letterRunes = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
@@ -1,14 +1,18 @@
 package rule
 
 import (
+	"encoding/json"
 	"fmt"
 	"net"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/evilsocket/opensnitch/daemon/conman"
 	"github.com/evilsocket/opensnitch/daemon/core"
 	"github.com/evilsocket/opensnitch/daemon/log"
+	"github.com/evilsocket/opensnitch/daemon/procmon"
+	"github.com/evilsocket/opensnitch/daemon/ui/protocol"
 )
 
 // Type is the type of rule.
@@ -28,6 +32,15 @@ const (
 	Complex = Type("complex") // for future use
 	List    = Type("list")
 	Network = Type("network")
+	And     = Type("and")
+	Or      = Type("or")
+	Not     = Type("not")
+	Lists   = Type("lists")
+	// numeric comparisons: 1024-65535, 1000, 22,80,443
+	Range       = Type("range")
+	LessThan    = Type("lt")
+	GreaterThan = Type("gt")
+	InSet       = Type("in-set")
 )
 
 // Available operands
@@ -36,17 +49,35 @@ const (
 	OpProcessID           = Operand("process.id")
 	OpProcessPath         = Operand("process.path")
 	OpProcessCmd          = Operand("process.command")
+	OpProcessHashMD5      = Operand("process.hash.md5")
+	OpProcessHashSHA256   = Operand("process.hash.sha256")
+	OpProcessParentPath   = Operand("process.parent.path")
+	OpProcessTree         = Operand("process.tree")
 	OpProcessEnvPrefix    = Operand("process.env.")
 	OpProcessEnvPrefixLen = 12
 	OpUserID              = Operand("user.id")
+	OpSrcIP               = Operand("source.ip")
+	OpSrcPort             = Operand("source.port")
+	OpIfaceIn             = Operand("iface.in")
+	OpIfaceOut            = Operand("iface.out")
 	OpDstIP               = Operand("dest.ip")
 	OpDstHost             = Operand("dest.host")
 	OpDstPort             = Operand("dest.port")
 	OpDstNetwork          = Operand("dest.network")
 	OpProto               = Operand("protocol")
 	OpList                = Operand("list")
+	OpDomainsLists        = Operand("lists.domains")
+	OpIPLists             = Operand("lists.ips")
 )
 
+// operands that can be compared numerically
+var numericOperands = map[Operand]bool{
+	OpProcessID: true,
+	OpUserID:    true,
+	OpSrcPort:   true,
+	OpDstPort:   true,
+}
+
 type opCallback func(value interface{}) bool
 
 // Operator represents what we want to filter of a connection, and how.
@@ -60,6 +91,11 @@ type Operator struct {
 	cb      opCallback
 	re      *regexp.Regexp
 	netMask *net.IPNet
+	domains *domainsList
+	ips     *ipsList
+	min     int64
+	max     int64
+	set     map[int64]bool
 }
 
 // NewOperator returns a new operator object
@@ -92,8 +128,9 @@ func (o *Operator) Compile() error {
 			return err
 		}
 		o.re = re
-	} else if o.Type == List {
+	} else if o.isBoolean() {
 		o.Operand = OpList
+		return o.compileList()
 	} else if o.Type == Network {
 		var err error
 		_, o.netMask, err = net.ParseCIDR(o.Data)
@@ -101,15 +138,165 @@ func (o *Operator) Compile() error {
 			return err
 		}
 		o.cb = o.cmpNetwork
+	} else if o.Type == Lists {
+		return o.compileLists()
+	} else if o.isNumeric() {
+		return o.compileNumeric()
+	}
+
+	return nil
+}
+
+func (o *Operator) isNumeric() bool {
+	return o.Type == Range || o.Type == LessThan || o.Type == GreaterThan || o.Type == InSet
+}
+
+// compileNumeric parses the numbers of a numeric comparison.
+func (o *Operator) compileNumeric() error {
+	if numericOperands[o.Operand] == false {
+		return fmt.Errorf("Operand %s can't be compared numerically", o.Operand)
+	}
+
+	var err error
+	switch o.Type {
+	case Range:
+		// the min value may be negative
+		data := strings.TrimSpace(o.Data)
+		sep := -1
+		if len(data) > 0 {
+			sep = strings.IndexByte(data[1:], '-') + 1
+		}
+		if sep <= 0 {
+			return fmt.Errorf("Invalid range %s, expected min-max", o.Data)
+		}
+		if o.min, err = parseNumber(data[:sep]); err != nil {
+			return err
+		}
+		if o.max, err = parseNumber(data[sep+1:]); err != nil {
+			return err
+		}
+		if o.min > o.max {
+			return fmt.Errorf("Invalid range %s, min is greater than max", o.Data)
+		}
+	case LessThan:
+		o.max, err = parseNumber(o.Data)
+	case GreaterThan:
+		o.min, err = parseNumber(o.Data)
+	case InSet:
+		o.set = make(map[int64]bool)
+		for _, item := range strings.Split(o.Data, ",") {
+			n, err := parseNumber(item)
+			if err != nil {
+				return err
+			}
+			o.set[n] = true
+		}
+	}
+	if err != nil {
+		return err
 	}
 
+	o.cb = o.numericCmp
+	return nil
+}
+
+func parseNumber(s string) (int64, error) {
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid number %s: %s", s, err)
+	}
+	return n, nil
+}
+
+// compileLists loads the list of the operator from the file pointed by Data.
+func (o *Operator) compileLists() error {
+	switch o.Operand {
+	case OpDomainsLists:
+		dl, err := newDomainsList(o.Data)
+		if err != nil {
+			return err
+		}
+		o.domains = dl
+		o.cb = o.domainsListCmp
+	case OpIPLists:
+		il, err := newIPsList(o.Data)
+		if err != nil {
+			return err
+		}
+		o.ips = il
+		o.cb = o.ipsListCmp
+	default:
+		return fmt.Errorf("Unknown operand of type lists: %s", o.Operand)
+	}
+	return nil
+}
+
+// reloadList reloads from disk the list of a lists operator.
+func (o *Operator) reloadList() error {
+	if o.domains != nil {
+		return o.domains.load()
+	} else if o.ips != nil {
+		return o.ips.load()
+	}
+	return nil
+}
+
+// isBoolean returns true if the operator combines the results of its children.
+// A list behaves like an and.
+func (o *Operator) isBoolean() bool {
+	return o.Type == List || o.Type == And || o.Type == Or || o.Type == Not
+}
+
+// compileList compiles the children of a boolean operator. If the children
+// are not defined, they're read from the Data field, as sent by the UI.
+func (o *Operator) compileList() error {
+	if len(o.List) == 0 && o.Data != "" {
+		if err := json.Unmarshal([]byte(o.Data), &o.List); err != nil {
+			return fmt.Errorf("Error loading operator of type %s: %s", o.Type, err)
+		}
+	}
+	if o.Type == Not && len(o.List) != 1 {
+		return fmt.Errorf("Operator of type not must have exactly one operator, has %d", len(o.List))
+	}
+	if len(o.List) == 0 {
+		return fmt.Errorf("Operator of type %s has no operators", o.Type)
+	}
+	for i := 0; i < len(o.List); i++ {
+		if err := o.List[i].Compile(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 func (o *Operator) String() string {
+	if o.isBoolean() {
+		if o.Type == Not {
+			return fmt.Sprintf("not (%s)", o.List[0].String())
+		}
+		how := string(And)
+		if o.Type == Or {
+			how = string(Or)
+		}
+		ops := make([]string, len(o.List))
+		for i := range o.List {
+			ops[i] = o.List[i].String()
+		}
+		return "(" + strings.Join(ops, " "+how+" ") + ")"
+	}
 	how := "is"
 	if o.Type == Regexp {
 		how = "matches"
+	} else if o.Type == Lists {
+		how = "in"
+	} else if o.Type == Range {
+		how = "is in range"
+	} else if o.Type == LessThan {
+		how = "is less than"
+	} else if o.Type == GreaterThan {
+		how = "is greater than"
+	} else if o.Type == InSet {
+		how = "is one of"
 	}
 	return fmt.Sprintf("%s %s '%s'", log.Bold(string(o.Operand)), how, log.Yellow(string(o.Data)))
 }
@@ -128,6 +315,24 @@ func (o *Operator) reCmp(v interface{}) bool {
 	return o.re.MatchString(v.(string))
 }
 
+func (o *Operator) numericCmp(v interface{}) bool {
+	n, err := strconv.ParseInt(v.(string), 10, 64)
+	if err != nil {
+		return false
+	}
+	switch o.Type {
+	case Range:
+		return n >= o.min && n <= o.max
+	case LessThan:
+		return n < o.max
+	case GreaterThan:
+		return n > o.min
+	case InSet:
+		return o.set[n]
+	}
+	return false
+}
+
 func (o *Operator) cmpNetwork(destIP interface{}) bool {
 	// 192.0.2.1/24, 2001:db8:a0b:12f0::1/32
 	if o.netMask == nil {
@@ -137,16 +342,50 @@ func (o *Operator) cmpNetwork(destIP interface{}) bool {
 	return o.netMask.Contains(destIP.(net.IP))
 }
 
-func (o *Operator) listMatch(con interface{}) bool {
-	res := true
-	for i := 0; i < len(o.List); i += 1 {
-		o := o.List[i]
-		if err := o.Compile(); err != nil {
+func (o *Operator) domainsListCmp(host interface{}) bool {
+	return o.domains.contains(host.(string))
+}
+
+func (o *Operator) ipsListCmp(ip interface{}) bool {
+	return o.ips.contains(ip.(net.IP))
+}
+
+func (o *Operator) listMatch(con *conman.Connection) bool {
+	switch o.Type {
+	case Not:
+		if len(o.List) == 0 {
 			return false
 		}
-		res = res && o.Match(con.(*conman.Connection))
+		return !o.List[0].Match(con)
+	case Or:
+		for i := 0; i < len(o.List); i++ {
+			if o.List[i].Match(con) {
+				return true
+			}
+		}
+		return false
+	default:
+		for i := 0; i < len(o.List); i++ {
+			if !o.List[i].Match(con) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Serialize translates an Operator, and its children, to the protocol object.
+func (o *Operator) Serialize() *protocol.Operator {
+	op := &protocol.Operator{
+		Type:      string(o.Type),
+		Sensitive: bool(o.Sensitive),
+		Operand:   string(o.Operand),
+		Data:      string(o.Data),
 	}
-	return res
+	for i := range o.List {
+		op.List = append(op.List, o.List[i].Serialize())
+	}
+	return op
 }
 
 // Match tries to match parts of a connection with the given operator.
@@ -162,10 +401,41 @@ func (o *Operator) Match(con *conman.Connection) bool {
 		return o.cb(con.Process.Path)
 	} else if o.Operand == OpProcessCmd {
 		return o.cb(strings.Join(con.Process.Args, " "))
+	} else if o.Operand == OpProcessParentPath {
+		if len(con.Process.Tree) == 0 {
+			return false
+		}
+		return o.cb(con.Process.Tree[0].Path)
+	} else if o.Operand == OpProcessTree {
+		// matches if any of the ancestors of the process matches
+		for _, parent := range con.Process.Tree {
+			if o.cb(parent.Path) {
+				return true
+			}
+		}
+		return false
+	} else if o.Operand == OpProcessHashMD5 || o.Operand == OpProcessHashSHA256 {
+		algo := procmon.HashMD5
+		if o.Operand == OpProcessHashSHA256 {
+			algo = procmon.HashSHA256
+		}
+		// the checksum may not be available, if the process has exited
+		if sum := con.Process.Checksums[algo]; sum != "" {
+			return o.cb(sum)
+		}
+		return false
 	} else if strings.HasPrefix(string(o.Operand), string(OpProcessEnvPrefix)) {
 		envVarName := core.Trim(string(o.Operand[OpProcessEnvPrefixLen:]))
 		envVarValue, _ := con.Process.Env[envVarName]
 		return o.cb(envVarValue)
+	} else if o.Operand == OpSrcIP {
+		return o.cb(con.SrcIP.String())
+	} else if o.Operand == OpSrcPort {
+		return o.cb(fmt.Sprintf("%d", con.SrcPort))
+	} else if o.Operand == OpIfaceIn && con.IfaceIn != "" {
+		return o.cb(con.IfaceIn)
+	} else if o.Operand == OpIfaceOut && con.IfaceOut != "" {
+		return o.cb(con.IfaceOut)
 	} else if o.Operand == OpDstIP {
 		return o.cb(con.DstIP.String())
 	} else if o.Operand == OpDstHost && con.DstHost != "" {
@@ -178,6 +448,10 @@ func (o *Operator) Match(con *conman.Connection) bool {
 		return o.cb(con.DstIP)
 	} else if o.Operand == OpList {
 		return o.listMatch(con)
+	} else if o.Operand == OpDomainsLists && con.DstHost != "" {
+		return o.cb(con.DstHost)
+	} else if o.Operand == OpIPLists {
+		return o.cb(con.DstIP)
 	}
 
 	return false
@@ -1,11 +1,13 @@
 package rule
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/evilsocket/opensnitch/daemon/conman"
 	"github.com/evilsocket/opensnitch/daemon/netstat"
 	"github.com/evilsocket/opensnitch/daemon/procmon"
 	"net"
+	"strings"
 	"testing"
 )
 
@@ -365,3 +367,313 @@ func TestNewOperatorList(t *testing.T) {
 
 	restoreConnection()
 }
+
+func TestNewOperatorBoolean(t *testing.T) {
+	t.Log("Test NewOperator() and, or, not")
+	var list []Operator
+	// (process.path is curl or process.path is wget) and not dest.host is opensnitch.io
+	listData := `[
+		{"type": "or", "operand": "list", "list": [
+			{"type": "simple", "operand": "process.path", "data": "/usr/bin/curl"},
+			{"type": "simple", "operand": "process.path", "data": "/usr/bin/wget"}
+		]},
+		{"type": "not", "operand": "list", "list": [
+			{"type": "simple", "operand": "dest.host", "data": "opensnitch.io"}
+		]}
+	]`
+
+	opAnd, err := NewOperator(And, false, OpList, listData, list)
+	if err != nil {
+		t.Error("NewOperator and.err should be nil: ", err)
+		t.Fail()
+	}
+
+	conn.Process.Path = "/usr/bin/wget"
+	conn.DstHost = "example.com"
+	if opAnd.Match(conn) == false {
+		t.Error("Test NewOperator() and.or doesn't match:", conn.Process.Path, conn.DstHost)
+		t.Fail()
+	}
+
+	conn.DstHost = defaultDstHost
+	if opAnd.Match(conn) == true {
+		t.Error("Test NewOperator() and.not matches:", conn.Process.Path, conn.DstHost)
+		t.Fail()
+	}
+
+	conn.Process.Path = "/usr/bin/firefox"
+	conn.DstHost = "example.com"
+	if opAnd.Match(conn) == true {
+		t.Error("Test NewOperator() and.or matches:", conn.Process.Path, conn.DstHost)
+		t.Fail()
+	}
+
+	if s := opAnd.List[1].String(); s == "" {
+		t.Error("Test NewOperator() not.String() is empty")
+		t.Fail()
+	}
+
+	t.Run("not must have one operator", func(t *testing.T) {
+		if _, err := NewOperator(Not, false, OpList, `[]`, list); err == nil {
+			t.Error("NewOperator not with no operators should fail")
+			t.Fail()
+		}
+		two := `[{"type": "simple", "operand": "dest.port", "data": "443"}, {"type": "simple", "operand": "dest.port", "data": "80"}]`
+		if _, err := NewOperator(Not, false, OpList, two, list); err == nil {
+			t.Error("NewOperator not with two operators should fail")
+			t.Fail()
+		}
+	})
+
+	t.Run("children are validated", func(t *testing.T) {
+		bad := `[{"type": "regexp", "operand": "process.path", "data": "^(/usr"}]`
+		if _, err := NewOperator(Or, false, OpList, bad, list); err == nil {
+			t.Error("NewOperator or with an invalid regexp should fail")
+			t.Fail()
+		}
+	})
+
+	t.Run("serialize and deserialize", func(t *testing.T) {
+		r := Create("000-bool", true, false, Deny, Always, opAnd)
+		r2, err := Deserialize(r.Serialize())
+		if err != nil {
+			t.Error("Deserialize() error:", err)
+			t.Fail()
+			return
+		}
+		if len(r2.Operator.List) != 2 || r2.Operator.List[1].Type != Not || len(r2.Operator.List[0].List) != 2 {
+			t.Error("Deserialized operator differs:", r2.Operator)
+			t.Fail()
+		}
+		conn.Process.Path = "/usr/bin/curl"
+		if r2.Match(conn) == false {
+			t.Error("Deserialized operator doesn't match:", conn.Process.Path, conn.DstHost)
+			t.Fail()
+		}
+	})
+
+	restoreConnection()
+}
+
+func TestNewOperatorProcessHash(t *testing.T) {
+	t.Log("Test NewOperator() process.hash")
+	var list []Operator
+	sha256sum := "1c9c8e2fb2b4b1a5b3d1e0ba3e4a2fe3f0f2c4f6a8d1b0e3e4f6a7c8d9e0f1a2"
+	md5sum := "b1946ac92492d2347c6235b4d2611184"
+	conn.Process.Checksums = map[string]string{
+		procmon.HashSHA256: sha256sum,
+		procmon.HashMD5:    md5sum,
+	}
+	defer func() { conn.Process.Checksums = nil }()
+
+	op, err := NewOperator(Simple, false, OpProcessHashSHA256, strings.ToUpper(sha256sum), list)
+	if err != nil {
+		t.Fatal("NewOperator process.hash.sha256 err should be nil:", err)
+	}
+	if op.Match(conn) == false {
+		t.Error("Test NewOperator() process.hash.sha256 doesn't match")
+	}
+	op, _ = NewOperator(Simple, true, OpProcessHashMD5, md5sum, list)
+	if op.Match(conn) == false {
+		t.Error("Test NewOperator() process.hash.md5 doesn't match")
+	}
+	op, _ = NewOperator(Simple, false, OpProcessHashMD5, sha256sum, list)
+	if op.Match(conn) == true {
+		t.Error("Test NewOperator() process.hash.md5 should not match a sha256")
+	}
+
+	conn.Process.Checksums = nil
+	op, _ = NewOperator(Regexp, false, OpProcessHashSHA256, ".*", list)
+	if op.Match(conn) == true {
+		t.Error("Test NewOperator() process.hash.sha256 should not match without checksum")
+	}
+}
+
+func TestNewOperatorProcessTree(t *testing.T) {
+	t.Log("Test NewOperator() process.parent.path and process.tree")
+	var list []Operator
+	conn.Process.Tree = []procmon.ProcParent{
+		{ID: 1000, Path: "/usr/bin/bash"},
+		{ID: 100, Path: "/opt/ci/ci-agent"},
+		{ID: 1, Path: "/usr/lib/systemd/systemd"},
+	}
+	defer func() { conn.Process.Tree = nil }()
+
+	op, err := NewOperator(Simple, false, OpProcessParentPath, "/usr/bin/bash", list)
+	if err != nil {
+		t.Fatal("NewOperator process.parent.path err should be nil:", err)
+	}
+	if op.Match(conn) == false {
+		t.Error("Test NewOperator() process.parent.path doesn't match")
+	}
+	op, _ = NewOperator(Simple, false, OpProcessParentPath, "/opt/ci/ci-agent", list)
+	if op.Match(conn) == true {
+		t.Error("Test NewOperator() process.parent.path should only match the parent")
+	}
+
+	op, _ = NewOperator(Simple, false, OpProcessTree, "/opt/ci/ci-agent", list)
+	if op.Match(conn) == false {
+		t.Error("Test NewOperator() process.tree doesn't match")
+	}
+	op, _ = NewOperator(Regexp, false, OpProcessTree, "^/opt/ci/", list)
+	if op.Match(conn) == false {
+		t.Error("Test NewOperator() process.tree regexp doesn't match")
+	}
+	op, _ = NewOperator(Simple, false, OpProcessTree, "/usr/bin/sshd", list)
+	if op.Match(conn) == true {
+		t.Error("Test NewOperator() process.tree should not match")
+	}
+
+	conn.Process.Tree = nil
+	op, _ = NewOperator(Regexp, false, OpProcessParentPath, ".*", list)
+	if op.Match(conn) == true {
+		t.Error("Test NewOperator() process.parent.path should not match without parent")
+	}
+}
+
+func TestNewOperatorSource(t *testing.T) {
+	t.Log("Test NewOperator() source.ip, source.port and iface")
+	var list []Operator
+
+	op, err := NewOperator(Simple, false, OpSrcIP, "192.168.1.111", list)
+	if err != nil {
+		t.Fatal("NewOperator source.ip err should be nil:", err)
+	}
+	if op.Match(conn) == false {
+		t.Error("Test NewOperator() source.ip doesn't match")
+	}
+	op, _ = NewOperator(Simple, false, OpSrcPort, "66666", list)
+	if op.Match(conn) == false {
+		t.Error("Test NewOperator() source.port doesn't match")
+	}
+	op, _ = NewOperator(Simple, false, OpSrcPort, fmt.Sprint(defaultDstPort), list)
+	if op.Match(conn) == true {
+		t.Error("Test NewOperator() source.port should not match the dest.port")
+	}
+
+	conn.IfaceOut = "wg0"
+	conn.IfaceIn = "eth0"
+	defer func() {
+		conn.IfaceOut = ""
+		conn.IfaceIn = ""
+	}()
+	op, _ = NewOperator(Regexp, false, OpIfaceOut, "^(tun|wg)[0-9]+$", list)
+	if op.Match(conn) == false {
+		t.Error("Test NewOperator() iface.out doesn't match")
+	}
+	op, _ = NewOperator(Simple, false, OpIfaceIn, "eth0", list)
+	if op.Match(conn) == false {
+		t.Error("Test NewOperator() iface.in doesn't match")
+	}
+
+	conn.IfaceOut = ""
+	op, _ = NewOperator(Regexp, false, OpIfaceOut, ".*", list)
+	if op.Match(conn) == true {
+		t.Error("Test NewOperator() iface.out should not match an unknown interface")
+	}
+}
+
+func TestNewOperatorNumeric(t *testing.T) {
+	t.Log("Test NewOperator() numeric comparisons")
+	var list []Operator
+
+	tests := []struct {
+		t       Type
+		operand Operand
+		data    string
+		match   bool
+	}{
+		{Range, OpDstPort, "1-1024", true},
+		{Range, OpDstPort, " 443 - 443 ", true},
+		{Range, OpDstPort, "1024-65535", false},
+		{Range, OpSrcPort, "1024-65535", false},
+		{Range, OpSrcPort, "1024-66666", true},
+		{Range, OpUserID, "-1-1000", true},
+		{GreaterThan, OpUserID, "665", true},
+		{GreaterThan, OpUserID, "666", false},
+		{LessThan, OpUserID, "667", true},
+		{LessThan, OpUserID, "666", false},
+		{LessThan, OpProcessID, "20000", true},
+		{InSet, OpDstPort, "22,80,443", true},
+		{InSet, OpDstPort, "22, 80, 8080", false},
+		{InSet, OpProcessID, "12345", true},
+	}
+	for _, test := range tests {
+		op, err := NewOperator(test.t, false, test.operand, test.data, list)
+		if err != nil {
+			t.Error("NewOperator() numeric err should be nil:", test, err)
+			continue
+		}
+		if op.Match(conn) != test.match {
+			t.Error("Test NewOperator() numeric unexpected result:", test)
+		}
+	}
+
+	invalid := []struct {
+		t       Type
+		operand Operand
+		data    string
+	}{
+		{Range, OpDstPort, "1024"},
+		{Range, OpDstPort, ""},
+		{Range, OpDstPort, "-"},
+		{Range, OpDstPort, "65535-1024"},
+		{Range, OpDstPort, "a-b"},
+		{GreaterThan, OpUserID, "1000a"},
+		{LessThan, OpUserID, ""},
+		{InSet, OpDstPort, "22,,80"},
+		{Range, OpDstHost, "1-10"},
+		{GreaterThan, OpProcessPath, "10"},
+	}
+	for _, test := range invalid {
+		if _, err := NewOperator(test.t, false, test.operand, test.data, list); err == nil {
+			t.Error("NewOperator() numeric should fail:", test)
+		}
+	}
+}
+
+func TestNumericRuleJSON(t *testing.T) {
+	t.Log("Test numeric rules JSON round-trip")
+	raw := `{"name": "allow-unprivileged-ports", "enabled": true, "action": "allow", "duration": "always",
+		"operator": {"type": "and", "operand": "list", "list": [
+			{"type": "range", "operand": "dest.port", "data": "1024-65535"},
+			{"type": "gt", "operand": "user.id", "data": "999"},
+			{"type": "in-set", "operand": "process.id", "data": "1,12345"}
+		]}}`
+
+	var r Rule
+	if err := json.Unmarshal([]byte(raw), &r); err != nil {
+		t.Fatal("Error parsing rule:", err)
+	}
+	if err := r.Operator.Compile(); err != nil {
+		t.Fatal("Error compiling rule:", err)
+	}
+	conn.DstPort = 8080
+	conn.Entry.UserId = 1000
+	defer restoreConnection()
+	if r.Match(conn) == false {
+		t.Error("Numeric rule doesn't match")
+	}
+
+	out, err := json.Marshal(&r)
+	if err != nil {
+		t.Fatal("Error serializing rule:", err)
+	}
+	var r2 Rule
+	if err := json.Unmarshal(out, &r2); err != nil {
+		t.Fatal("Error parsing serialized rule:", err)
+	}
+	if err := r2.Operator.Compile(); err != nil {
+		t.Fatal("Error compiling serialized rule:", err)
+	}
+	if len(r2.Operator.List) != 3 || r2.Operator.List[0].Type != Range || r2.Operator.List[0].Data != "1024-65535" {
+		t.Error("Numeric rule not serialized:", string(out))
+	}
+	if r2.Match(conn) == false {
+		t.Error("Serialized numeric rule doesn't match")
+	}
+	conn.Entry.UserId = 999
+	if r2.Match(conn) == true {
+		t.Error("Serialized numeric rule should not match")
+	}
+}
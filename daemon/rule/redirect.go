@@ -0,0 +1,51 @@
+package rule
+
+import (
+	"fmt"
+
+	"github.com/evilsocket/opensnitch/daemon/firewall/marks"
+)
+
+// Redirect holds the parameters of the requeue and mark actions.
+//
+// Requeue rules hand the connections over to the netfilter queue Queue, where
+// another program (an IDS for example) decides what to do with them. If no
+// program is listening on that queue, the connections are dropped.
+//
+// Mark rules accept the connections, setting the firewall mark Mark on them,
+// so they can be routed with policy routing (ip rule add fwmark ...). Only the
+// first packet of a connection goes through the daemon, so the mark should be
+// saved to the connection and restored on the next packets with a system
+// rule, like: -t mangle OUTPUT -j CONNMARK --restore-mark, and
+// -t mangle POSTROUTING -m mark --mark <mark> -j CONNMARK --save-mark
+type Redirect struct {
+	Queue uint16 `json:"queue,omitempty"`
+	Mark  uint32 `json:"mark,omitempty"`
+}
+
+// Compile validates the redirection parameters for the given action.
+func (rd *Redirect) Compile(action Action) error {
+	switch action {
+	case Requeue:
+		if rd.Mark != 0 {
+			return fmt.Errorf("Requeue rules can't set a mark")
+		}
+	case Mark:
+		if rd.Queue != 0 {
+			return fmt.Errorf("Mark rules can't set a queue")
+		}
+		if rd.Mark == 0 || marks.IsReserved(rd.Mark) {
+			return fmt.Errorf("Invalid mark 0x%x", rd.Mark)
+		}
+	default:
+		return fmt.Errorf("Only requeue and mark rules can redirect connections, not %s", action)
+	}
+	return nil
+}
+
+func (rd *Redirect) String() string {
+	if rd.Mark != 0 {
+		return fmt.Sprintf("mark 0x%x", rd.Mark)
+	}
+	return fmt.Sprintf("queue %d", rd.Queue)
+}
@@ -0,0 +1,72 @@
+package rule
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRedirect(t *testing.T) {
+	t.Log("Test rules redirect")
+	var list []Operator
+	op, _ := NewOperator(Simple, false, OpTrue, "", list)
+
+	tests := []struct {
+		action   Action
+		redirect *Redirect
+		valid    bool
+	}{
+		{Requeue, &Redirect{Queue: 1}, true},
+		{Requeue, &Redirect{}, true},
+		{Requeue, nil, false},
+		{Requeue, &Redirect{Queue: 1, Mark: 1}, false},
+		{Mark, &Redirect{Mark: 0x100}, true},
+		{Mark, &Redirect{}, false},
+		{Mark, nil, false},
+		{Mark, &Redirect{Mark: 0x18BA5}, false},
+		{Mark, &Redirect{Mark: 0x100, Queue: 1}, false},
+		{Allow, &Redirect{Mark: 0x100}, false},
+		{Deny, &Redirect{Queue: 1}, false},
+		{Allow, nil, true},
+	}
+	for _, test := range tests {
+		r := Create("000-redirect", true, false, test.action, Restart, op)
+		r.Redirect = test.redirect
+		if err := r.Compile(); (err == nil) != test.valid {
+			t.Error("Rule Compile() unexpected result:", test.action, test.redirect, err)
+		}
+	}
+}
+
+func TestRedirectSerialize(t *testing.T) {
+	t.Log("Test rules redirect serialization")
+	var list []Operator
+	op, _ := NewOperator(Simple, false, OpTrue, "", list)
+	r := Create("000-redirect", true, false, Mark, Restart, op)
+	r.Redirect = &Redirect{Mark: 0x100}
+
+	r2, err := Deserialize(r.Serialize())
+	if err != nil {
+		t.Fatal("Deserialize() error:", err)
+	}
+	if r2.Action != Mark || r2.Redirect == nil || *r2.Redirect != *r.Redirect {
+		t.Error("Redirect not deserialized:", r2)
+	}
+
+	r.Action = Requeue
+	if _, err := Deserialize(r.Serialize()); err == nil {
+		t.Error("Deserialize() of a requeue rule with a mark should fail")
+	}
+
+	var r3 Rule
+	raw := `{"name": "000-requeue", "enabled": true, "action": "requeue", "duration": "always",
+		"operator": {"type": "simple", "operand": "true"}, "redirect": {"queue": 5}}`
+	if err := json.Unmarshal([]byte(raw), &r3); err != nil {
+		t.Fatal("Error parsing rule:", err)
+	}
+	if err := r3.Compile(); err != nil {
+		t.Fatal("Rule Compile() error:", err)
+	}
+	if r3.Redirect == nil || r3.Redirect.Queue != 5 {
+		t.Error("Redirect not parsed from JSON:", r3.Redirect)
+	}
+}
@@ -14,8 +14,11 @@ type Action string
 
 // Actions of rules
 const (
-	Allow = Action("allow")
-	Deny  = Action("deny")
+	Allow   = Action("allow")
+	Deny    = Action("deny")
+	Reject  = Action("reject")
+	Requeue = Action("requeue")
+	Mark    = Action("mark")
 )
 
 // Duration of a rule
@@ -31,15 +34,34 @@ const (
 // Rule represents an action on a connection.
 // The fields match the ones saved as json to disk.
 // If a .json rule file is modified on disk, it's reloaded automatically.
+//
+// Rules are evaluated from the lowest to the highest Priority. Rules with
+// the same Priority are evaluated in alphabetical order of their names.
+// By default all the rules have Priority 0.
+// The lowest Priority with a rule matching decides the verdict: within it,
+// the first rule that matches other than Allow, or with Precedence, wins, otherwise the last
+// Allow rule that matched. Rules of higher priorities are not evaluated.
+//
+// If a Schedule is defined, the rule is only evaluated while it's active.
+// Allow rules may have a Limit of usage.
+// Requeue and mark rules need the parameters of the Redirect.
 type Rule struct {
 	Created    time.Time `json:"created"`
 	Updated    time.Time `json:"updated"`
 	Name       string    `json:"name"`
 	Enabled    bool      `json:"enabled"`
 	Precedence bool      `json:"precedence"`
+	Priority   int       `json:"priority,omitempty"`
 	Action     Action    `json:"action"`
 	Duration   Duration  `json:"duration"`
 	Operator   Operator  `json:"operator"`
+	Schedule   string    `json:"schedule,omitempty"`
+	Limit      *Limit    `json:"limit,omitempty"`
+	Redirect   *Redirect `json:"redirect,omitempty"`
+
+	schedule *schedule
+	// limits of the Loader of the rule, where its traffic is accounted
+	limits *limiter
 }
 
 // Create creates a new rule object with the specified parameters.
@@ -56,9 +78,73 @@ func Create(name string, enabled bool, precedence bool, action Action, duration
 }
 
 func (r *Rule) String() string {
+	if r.Redirect != nil {
+		return fmt.Sprintf("%s: if(%s){ %s %s %s }", r.Name, r.Operator.String(), r.Action, r.Redirect, r.Duration)
+	}
+	if r.Limit != nil {
+		return fmt.Sprintf("%s: if(%s){ %s %s, limit %s }", r.Name, r.Operator.String(), r.Action, r.Duration, r.Limit)
+	}
 	return fmt.Sprintf("%s: if(%s){ %s %s }", r.Name, r.Operator.String(), r.Action, r.Duration)
 }
 
+// Compile compiles the operator, the schedule, the limit and the redirection
+// of the rule.
+func (r *Rule) Compile() error {
+	if err := r.Operator.Compile(); err != nil {
+		return err
+	}
+	if err := r.compileSchedule(); err != nil {
+		return err
+	}
+	if err := r.compileLimit(); err != nil {
+		return err
+	}
+	return r.compileRedirect()
+}
+
+func (r *Rule) compileRedirect() error {
+	if r.Redirect == nil {
+		if r.Action == Requeue || r.Action == Mark {
+			return fmt.Errorf("Rule %s is %s, but it has no redirect parameters", r.Name, r.Action)
+		}
+		return nil
+	}
+	return r.Redirect.Compile(r.Action)
+}
+
+func (r *Rule) compileLimit() error {
+	if r.Limit == nil {
+		return nil
+	}
+	if r.Action != Allow {
+		return fmt.Errorf("Only allow rules can have limits, rule %s is %s", r.Name, r.Action)
+	}
+	return r.Limit.Compile()
+}
+
+func (r *Rule) compileSchedule() error {
+	r.schedule = nil
+	if r.Schedule == "" {
+		return nil
+	}
+	sc, err := parseSchedule(r.Schedule)
+	if err != nil {
+		return err
+	}
+	r.schedule = sc
+	return nil
+}
+
+// IsActive returns true if the rule has no schedule, or the schedule is
+// active at the given time.
+func (r *Rule) IsActive(t time.Time) bool {
+	if r.Schedule == "" {
+		return true
+	}
+	// a schedule not compiled, or not valid, is never active
+	return r.schedule != nil && r.schedule.active(t)
+}
+
 // Match performs on a connection the checks a Rule has, to determine if it
 // must be allowed or denied.
 func (r *Rule) Match(con *conman.Connection) bool {
@@ -76,21 +162,51 @@ func Deserialize(reply *protocol.Rule) (*Rule, error) {
 		Sensitive(reply.Operator.Sensitive),
 		Operand(reply.Operator.Operand),
 		reply.Operator.Data,
-		make([]Operator, 0),
+		deserializeList(reply.Operator.List),
 	)
 	if err != nil {
 		log.Warning("Deserialize rule, NewOperator() error: %s", err)
 		return nil, err
 	}
 
-	return Create(
+	r := Create(
 		reply.Name,
 		reply.Enabled,
 		reply.Precedence,
 		Action(reply.Action),
 		Duration(reply.Duration),
 		operator,
-	), nil
+	)
+	r.Priority = int(reply.Priority)
+	r.Schedule = reply.Schedule
+	if err := r.compileSchedule(); err != nil {
+		log.Warning("Deserialize rule, schedule error: %s", err)
+		return nil, err
+	}
+	if reply.Limit != nil {
+		r.Limit = &Limit{
+			Connections: int(reply.Limit.Connections),
+			Interval:    reply.Limit.Interval,
+			BytesPerDay: reply.Limit.BytesPerDay,
+			Exceeded:    Action(reply.Limit.Exceeded),
+		}
+		if err := r.compileLimit(); err != nil {
+			log.Warning("Deserialize rule, limit error: %s", err)
+			return nil, err
+		}
+	}
+	if reply.Redirect != nil {
+		r.Redirect = &Redirect{
+			Queue: uint16(reply.Redirect.Queue),
+			Mark:  reply.Redirect.Mark,
+		}
+	}
+	if err := r.compileRedirect(); err != nil {
+		log.Warning("Deserialize rule, redirect error: %s", err)
+		return nil, err
+	}
+
+	return r, nil
 }
 
 // Serialize translates a Rule to the protocol object
@@ -98,17 +214,47 @@ func (r *Rule) Serialize() *protocol.Rule {
 	if r == nil {
 		return nil
 	}
+	var limit *protocol.RuleLimit
+	if r.Limit != nil {
+		limit = &protocol.RuleLimit{
+			Connections: uint32(r.Limit.Connections),
+			Interval:    r.Limit.Interval,
+			BytesPerDay: r.Limit.BytesPerDay,
+			Exceeded:    string(r.Limit.Exceeded),
+		}
+	}
+	var redirect *protocol.RuleRedirect
+	if r.Redirect != nil {
+		redirect = &protocol.RuleRedirect{
+			Queue: uint32(r.Redirect.Queue),
+			Mark:  r.Redirect.Mark,
+		}
+	}
 	return &protocol.Rule{
 		Name:       string(r.Name),
 		Enabled:    bool(r.Enabled),
 		Precedence: bool(r.Precedence),
+		Priority:   int32(r.Priority),
 		Action:     string(r.Action),
 		Duration:   string(r.Duration),
-		Operator: &protocol.Operator{
-			Type:      string(r.Operator.Type),
-			Sensitive: bool(r.Operator.Sensitive),
-			Operand:   string(r.Operator.Operand),
-			Data:      string(r.Operator.Data),
-		},
+		Operator:   r.Operator.Serialize(),
+		Schedule:   r.Schedule,
+		Limit:      limit,
+		Redirect:   redirect,
+	}
+}
+
+// deserializeList translates back the children of a boolean operator.
+func deserializeList(list []*protocol.Operator) []Operator {
+	ops := make([]Operator, 0, len(list))
+	for _, op := range list {
+		ops = append(ops, Operator{
+			Type:      Type(op.Type),
+			Sensitive: Sensitive(op.Sensitive),
+			Operand:   Operand(op.Operand),
+			Data:      op.Data,
+			List:      deserializeList(op.List),
+		})
 	}
+	return ops
 }
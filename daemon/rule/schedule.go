@@ -0,0 +1,282 @@
+package rule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var dayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// schedule defines when a rule is active, in local time.
+//
+// It's a list of windows separated by ";", and the rule is active if any of
+// them is. A window is either:
+//   - a list of days and an optional time range: "weekdays 09:00-18:00",
+//     "mon,wed,fri 08:00-12:00", "sat-sun", "daily 22:00-07:00" or "13:00-14:00".
+//     Time ranges that end before they start span midnight.
+//   - a cron-like expression "minute hour day-of-month month day-of-week",
+//     active during the minutes it matches: "* 9-17 * * 1-5".
+type schedule struct {
+	windows []window
+}
+
+type window interface {
+	active(t time.Time) bool
+}
+
+// dayWindow is active the given days, from start to end (minutes of the day).
+type dayWindow struct {
+	days  [7]bool
+	start int
+	end   int
+}
+
+// cronWindow is active the minutes matched by a cron expression.
+type cronWindow struct {
+	minutes [60]bool
+	hours   [24]bool
+	dom     [32]bool
+	months  [13]bool
+	dow     [7]bool
+	anyDom  bool
+	anyDow  bool
+}
+
+func parseSchedule(s string) (*schedule, error) {
+	sc := &schedule{}
+	for _, w := range strings.Split(s, ";") {
+		fields := strings.Fields(strings.ToLower(w))
+		var win window
+		var err error
+		switch len(fields) {
+		case 0:
+			continue
+		case 5:
+			win, err = parseCronWindow(fields)
+		case 1, 2:
+			win, err = parseDayWindow(fields)
+		default:
+			err = fmt.Errorf("expected \"days [hh:mm-hh:mm]\" or \"min hour dom month dow\"")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Invalid schedule '%s': %s", strings.TrimSpace(w), err)
+		}
+		sc.windows = append(sc.windows, win)
+	}
+	if len(sc.windows) == 0 {
+		return nil, fmt.Errorf("Invalid schedule '%s': no time windows", s)
+	}
+	return sc, nil
+}
+
+// active returns true if any of the windows of the schedule is active.
+func (sc *schedule) active(t time.Time) bool {
+	for _, w := range sc.windows {
+		if w.active(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseDayWindow(fields []string) (*dayWindow, error) {
+	w := &dayWindow{start: 0, end: 24 * 60}
+	daysSpec := fields[0]
+	timeSpec := ""
+	if len(fields) == 2 {
+		timeSpec = fields[1]
+	} else if strings.Contains(fields[0], ":") {
+		daysSpec = "daily"
+		timeSpec = fields[0]
+	}
+
+	if err := parseDays(daysSpec, &w.days); err != nil {
+		return nil, err
+	}
+	if timeSpec == "" {
+		return w, nil
+	}
+
+	parts := strings.Split(timeSpec, "-")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid time range %s, expected hh:mm-hh:mm", timeSpec)
+	}
+	var err error
+	if w.start, err = parseTimeOfDay(parts[0]); err != nil {
+		return nil, err
+	}
+	if w.end, err = parseTimeOfDay(parts[1]); err != nil {
+		return nil, err
+	}
+	if w.start == w.end {
+		return nil, fmt.Errorf("empty time range %s", timeSpec)
+	}
+	return w, nil
+}
+
+func parseDays(spec string, days *[7]bool) error {
+	for _, item := range strings.Split(spec, ",") {
+		switch item {
+		case "daily", "everyday", "*":
+			for d := range days {
+				days[d] = true
+			}
+			continue
+		case "weekdays":
+			for d := time.Monday; d <= time.Friday; d++ {
+				days[d] = true
+			}
+			continue
+		case "weekends", "weekend":
+			days[time.Saturday] = true
+			days[time.Sunday] = true
+			continue
+		}
+
+		bounds := strings.Split(item, "-")
+		if len(bounds) > 2 {
+			return fmt.Errorf("invalid days %s", item)
+		}
+		first, found := dayNames[bounds[0]]
+		if !found {
+			return fmt.Errorf("invalid day %s", bounds[0])
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, found = dayNames[bounds[1]]; !found {
+				return fmt.Errorf("invalid day %s", bounds[1])
+			}
+		}
+		// ranges may wrap around the end of the week: fri-mon
+		for d := first; ; d = (d + 1) % 7 {
+			days[d] = true
+			if d == last {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// parseTimeOfDay returns the minutes of the day of hh:mm. 24:00 is the end of the day.
+func parseTimeOfDay(s string) (int, error) {
+	var h, m int
+	if n, err := fmt.Sscanf(s, "%d:%d", &h, &m); err != nil || n != 2 || len(s) > 5 {
+		return 0, fmt.Errorf("invalid time %s, expected hh:mm", s)
+	}
+	if h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("invalid time %s", s)
+	}
+	return h*60 + m, nil
+}
+
+func (w *dayWindow) active(t time.Time) bool {
+	day := t.Weekday()
+	min := t.Hour()*60 + t.Minute()
+	if w.start < w.end {
+		return w.days[day] && min >= w.start && min < w.end
+	}
+	// the window started the day before
+	yesterday := (day + 6) % 7
+	return (w.days[day] && min >= w.start) || (w.days[yesterday] && min < w.end)
+}
+
+func parseCronWindow(fields []string) (*cronWindow, error) {
+	w := &cronWindow{
+		anyDom: fields[2] == "*",
+		anyDow: fields[4] == "*",
+	}
+	if err := parseCronField(fields[0], 0, 59, w.minutes[:]); err != nil {
+		return nil, err
+	}
+	if err := parseCronField(fields[1], 0, 23, w.hours[:]); err != nil {
+		return nil, err
+	}
+	if err := parseCronField(fields[2], 1, 31, w.dom[:]); err != nil {
+		return nil, err
+	}
+	if err := parseCronField(fields[3], 1, 12, w.months[:]); err != nil {
+		return nil, err
+	}
+	// 0 and 7 are both sunday
+	var dow [8]bool
+	if err := parseCronField(fields[4], 0, 7, dow[:]); err != nil {
+		return nil, err
+	}
+	copy(w.dow[:], dow[:7])
+	w.dow[0] = w.dow[0] || dow[7]
+
+	return w, nil
+}
+
+// parseCronField parses a field of a cron expression: *, */n, a, a/n (a-max/n),
+// a-b, a-b/n, and lists of them separated by commas.
+func parseCronField(field string, min, max int, values []bool) error {
+	for _, item := range strings.Split(field, ",") {
+		step, hasStep := 1, false
+		if pos := strings.IndexByte(item, '/'); pos != -1 {
+			var err error
+			if step, err = strconv.Atoi(item[pos+1:]); err != nil || step <= 0 {
+				return fmt.Errorf("invalid step %s", item)
+			}
+			item = item[:pos]
+			hasStep = true
+		}
+
+		first, last := min, max
+		if item != "*" {
+			bounds := strings.Split(item, "-")
+			if len(bounds) > 2 {
+				return fmt.Errorf("invalid range %s", item)
+			}
+			var err error
+			if first, err = strconv.Atoi(bounds[0]); err != nil {
+				return fmt.Errorf("invalid value %s", item)
+			}
+			last = first
+			if hasStep {
+				last = max
+			}
+			if len(bounds) == 2 {
+				if last, err = strconv.Atoi(bounds[1]); err != nil {
+					return fmt.Errorf("invalid value %s", item)
+				}
+			}
+		}
+		if first < min || last > max || first > last {
+			return fmt.Errorf("value %s out of range %d-%d", item, min, max)
+		}
+		for v := first; v <= last; v += step {
+			values[v] = true
+		}
+	}
+	return nil
+}
+
+func (w *cronWindow) active(t time.Time) bool {
+	if !w.minutes[t.Minute()] || !w.hours[t.Hour()] || !w.months[t.Month()] {
+		return false
+	}
+	// like cron, if both days are restricted, any of them matches
+	dom := w.dom[t.Day()]
+	dow := w.dow[t.Weekday()]
+	switch {
+	case w.anyDom && w.anyDow:
+		return true
+	case w.anyDom:
+		return dow
+	case w.anyDow:
+		return dom
+	}
+	return dom || dow
+}
@@ -0,0 +1,146 @@
+package rule
+
+import (
+	"testing"
+	"time"
+)
+
+// 2021-03-01 is a monday
+func at(day int, hour, min int) time.Time {
+	return time.Date(2021, time.March, day, hour, min, 0, 0, time.Local)
+}
+
+func TestSchedule(t *testing.T) {
+	t.Log("Test rules schedules")
+
+	tests := []struct {
+		schedule string
+		t        time.Time
+		active   bool
+	}{
+		{"weekdays 09:00-18:00", at(1, 9, 0), true},
+		{"weekdays 09:00-18:00", at(1, 17, 59), true},
+		{"weekdays 09:00-18:00", at(1, 18, 0), false},
+		{"weekdays 09:00-18:00", at(1, 8, 59), false},
+		{"weekdays 09:00-18:00", at(6, 10, 0), false},
+		{"WEEKENDS", at(7, 23, 59), true},
+		{"weekends", at(5, 23, 59), false},
+		{"mon,wed,fri 08:00-12:00", at(3, 8, 30), true},
+		{"mon,wed,fri 08:00-12:00", at(2, 8, 30), false},
+		{"sat-mon", at(1, 12, 0), true},
+		{"sat-mon", at(2, 12, 0), false},
+		{"tue-thu", at(4, 12, 0), true},
+		{"13:00-14:00", at(7, 13, 30), true},
+		{"daily 22:00-07:00", at(1, 23, 0), true},
+		{"daily 22:00-07:00", at(2, 6, 59), true},
+		{"daily 22:00-07:00", at(2, 7, 0), false},
+		{"fri 22:00-02:00", at(5, 23, 0), true},
+		{"fri 22:00-02:00", at(6, 1, 0), true},
+		{"fri 22:00-02:00", at(5, 1, 0), false},
+		{"daily 20:00-24:00", at(1, 23, 59), true},
+		{"mon 09:00-10:00; tue 11:00-12:00", at(2, 11, 30), true},
+		{"mon 09:00-10:00; tue 11:00-12:00", at(2, 9, 30), false},
+		{"* 9-17 * * 1-5", at(1, 17, 59), true},
+		{"* 9-17 * * 1-5", at(1, 18, 0), false},
+		{"* 9-17 * * 1-5", at(6, 10, 0), false},
+		{"*/15 * * * *", at(1, 10, 45), true},
+		{"*/15 * * * *", at(1, 10, 46), false},
+		{"5/15 * * * *", at(1, 10, 50), true},
+		{"5/15 * * * *", at(1, 10, 5), true},
+		{"5/15 * * * *", at(1, 10, 0), false},
+		{"5/15 * * * *", at(1, 10, 15), false},
+		{"* * * * 0", at(7, 0, 0), true},
+		{"* * * * 7", at(7, 0, 0), true},
+		{"* * 1 * 3", at(1, 0, 0), true},
+		{"* * 1 * 3", at(3, 0, 0), true},
+		{"* * 1 * 3", at(2, 0, 0), false},
+		{"0-29 * * 3,4 *", at(2, 12, 29), true},
+		{"0-29 * * 4 *", at(2, 12, 29), false},
+	}
+	for _, test := range tests {
+		sc, err := parseSchedule(test.schedule)
+		if err != nil {
+			t.Error("parseSchedule() error:", test.schedule, err)
+			continue
+		}
+		if sc.active(test.t) != test.active {
+			t.Error("Schedule unexpected result:", test.schedule, test.t, !test.active)
+		}
+	}
+
+	invalid := []string{
+		"",
+		" ; ",
+		"someday",
+		"mon 9-18",
+		"mon 09:00-09:00",
+		"mon 25:00-26:00",
+		"mon 09:60-10:00",
+		"mon-tue-wed",
+		"mon 09:00-10:00 extra",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+	}
+	for _, s := range invalid {
+		if _, err := parseSchedule(s); err == nil {
+			t.Error("parseSchedule() should fail:", s)
+		}
+	}
+}
+
+func TestRuleSchedule(t *testing.T) {
+	t.Log("Test rules with schedule")
+	var list []Operator
+	op, _ := NewOperator(Simple, false, OpTrue, "", list)
+
+	r := Create("000-schedule", true, false, Deny, Restart, op)
+	if !r.IsActive(at(1, 0, 0)) {
+		t.Error("Rule without schedule should be active")
+	}
+
+	r.Schedule = "weekdays 09:00-18:00"
+	if r.IsActive(at(1, 10, 0)) {
+		t.Error("Rule with a schedule not compiled should not be active")
+	}
+	if err := r.Compile(); err != nil {
+		t.Fatal("Rule Compile() error:", err)
+	}
+	if !r.IsActive(at(1, 10, 0)) || r.IsActive(at(6, 10, 0)) {
+		t.Error("Rule schedule not valid")
+	}
+
+	r2, err := Deserialize(r.Serialize())
+	if err != nil {
+		t.Fatal("Deserialize() error:", err)
+	}
+	if r2.Schedule != r.Schedule || !r2.IsActive(at(1, 10, 0)) || r2.IsActive(at(6, 10, 0)) {
+		t.Error("Rule schedule not deserialized:", r2.Schedule)
+	}
+
+	r.Schedule = "never"
+	if _, err := Deserialize(r.Serialize()); err == nil {
+		t.Error("Deserialize() of an invalid schedule should fail")
+	}
+
+	l, err := NewLoader(false)
+	if err != nil {
+		t.Fatal("NewLoader() error:", err)
+	}
+	always := Create("001-schedule-always", true, false, Deny, Restart, op)
+	always.Schedule = "daily"
+	never := Create("000-schedule-never", true, false, Deny, Restart, op)
+	never.Schedule = "* * 31 2 *"
+	always.Compile()
+	never.Compile()
+	l.Add(always, false)
+	l.Add(never, false)
+	if match := l.FindFirstMatch(conn); match == nil || match.Name != always.Name {
+		t.Error("Inactive rule not skipped:", match)
+	}
+}
@@ -0,0 +1,62 @@
+package sink
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/evilsocket/opensnitch/daemon/conman"
+	"github.com/evilsocket/opensnitch/daemon/rule"
+)
+
+// Event is a connection decision, as it's exported to the sinks.
+// Rule is empty if no rule matched (the GUI didn't reply), and Action is then
+// the default action. If the rule matched is disabled, Action is also the
+// default action.
+type Event struct {
+	Time     time.Time `json:"time"`
+	Action   string    `json:"action"`
+	Rule     string    `json:"rule,omitempty"`
+	Process  string    `json:"process"`
+	PID      int       `json:"pid"`
+	UID      int       `json:"uid"`
+	Protocol string    `json:"protocol"`
+	SrcIP    string    `json:"src_ip"`
+	SrcPort  uint      `json:"src_port"`
+	DstIP    string    `json:"dst_ip"`
+	DstHost  string    `json:"dst_host,omitempty"`
+	DstPort  uint      `json:"dst_port"`
+}
+
+// NewEvent creates the event of a connection, the rule matched, if any, and
+// the action applied to it.
+func NewEvent(con *conman.Connection, match *rule.Rule, action rule.Action) *Event {
+	e := &Event{
+		Time:     time.Now(),
+		Action:   string(action),
+		PID:      -1,
+		UID:      -1,
+		Protocol: con.Protocol,
+		SrcIP:    con.SrcIP.String(),
+		SrcPort:  con.SrcPort,
+		DstIP:    con.DstIP.String(),
+		DstHost:  con.DstHost,
+		DstPort:  con.DstPort,
+	}
+	if con.Entry != nil {
+		e.UID = con.Entry.UserId
+	}
+	if con.Process != nil {
+		e.PID = con.Process.ID
+		e.Process = con.Process.Path
+	}
+	if match != nil {
+		e.Rule = match.Name
+	}
+	return e
+}
+
+// JSON returns the event as a single line of JSON.
+func (e *Event) JSON() []byte {
+	raw, _ := json.Marshal(e)
+	return raw
+}
@@ -0,0 +1,34 @@
+package sink
+
+import (
+	"fmt"
+	"os"
+)
+
+const defaultFile = "/var/log/opensnitchd-events.json"
+
+// fileWriter appends the events to a file, one JSON object per line.
+type fileWriter struct {
+	file *os.File
+}
+
+func newFileWriter(conf Config) (Writer, error) {
+	path := conf.Address
+	if path == "" {
+		path = defaultFile
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("Error opening %s: %s", path, err)
+	}
+	return &fileWriter{file: f}, nil
+}
+
+func (w *fileWriter) Write(e *Event) error {
+	_, err := w.file.Write(append(e.JSON(), '\n'))
+	return err
+}
+
+func (w *fileWriter) Close() error {
+	return w.file.Close()
+}
@@ -0,0 +1,76 @@
+package sink
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+)
+
+const defaultJournald = "/run/systemd/journal/socket"
+
+// journaldWriter sends the events to journald with its native protocol, with
+// the fields of the event as OPENSNITCH_* fields, and the event as JSON in
+// the message.
+type journaldWriter struct {
+	con *net.UnixConn
+}
+
+func newJournaldWriter(conf Config) (Writer, error) {
+	path := conf.Address
+	if path == "" {
+		path = defaultJournald
+	}
+	con, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return nil, fmt.Errorf("Error connecting to journald %s: %s", path, err)
+	}
+	return &journaldWriter{con: con}, nil
+}
+
+// writeField appends a field to a journal entry. Values with new lines are
+// serialized as binary data: the name, the length of the value as a little
+// endian 64 bit integer, and the value.
+func writeField(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(name)
+	if strings.ContainsRune(value, '\n') {
+		buf.WriteByte('\n')
+		binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	} else {
+		buf.WriteByte('=')
+	}
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+func (w *journaldWriter) format(e *Event) []byte {
+	priority := severityInfo
+	if e.Action != "" && e.Action != "allow" {
+		priority = severityNotice
+	}
+
+	buf := &bytes.Buffer{}
+	writeField(buf, "MESSAGE", string(e.JSON()))
+	writeField(buf, "PRIORITY", fmt.Sprint(priority))
+	writeField(buf, "SYSLOG_IDENTIFIER", appName)
+	writeField(buf, "OPENSNITCH_ACTION", e.Action)
+	writeField(buf, "OPENSNITCH_RULE", e.Rule)
+	writeField(buf, "OPENSNITCH_PROCESS", e.Process)
+	writeField(buf, "OPENSNITCH_PID", fmt.Sprint(e.PID))
+	writeField(buf, "OPENSNITCH_UID", fmt.Sprint(e.UID))
+	writeField(buf, "OPENSNITCH_PROTOCOL", e.Protocol)
+	writeField(buf, "OPENSNITCH_DST_IP", e.DstIP)
+	writeField(buf, "OPENSNITCH_DST_HOST", e.DstHost)
+	writeField(buf, "OPENSNITCH_DST_PORT", fmt.Sprint(e.DstPort))
+	return buf.Bytes()
+}
+
+func (w *journaldWriter) Write(e *Event) error {
+	_, err := w.con.Write(w.format(e))
+	return err
+}
+
+func (w *journaldWriter) Close() error {
+	return w.con.Close()
+}
@@ -0,0 +1,157 @@
+// Package sink exports the connection decisions as structured events, to be
+// ingested by other tools (a SIEM for example): JSON lines to a file, RFC5424
+// messages to syslog, or entries with structured fields to journald.
+//
+// The sinks are configured in the EventSinks list of the configuration:
+//
+//	"EventSinks": [
+//	    {"Type": "file", "Address": "/var/log/opensnitchd-events.json"},
+//	    {"Type": "syslog", "Address": "udp://siem.lan:514"},
+//	    {"Type": "journald"}
+//	]
+package sink
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+
+	"github.com/evilsocket/opensnitch/daemon/conman"
+	"github.com/evilsocket/opensnitch/daemon/log"
+	"github.com/evilsocket/opensnitch/daemon/rule"
+)
+
+// max number of events waiting to be written, events beyond it are lost.
+const maxPending = 1024
+
+// Config is the configuration of a sink. The meaning of Address depends on
+// the Type of the sink, and it's optional for the types with a default.
+type Config struct {
+	Type    string `json:"Type"`
+	Address string `json:"Address,omitempty"`
+	// syslog facility (daemon, local0...), daemon by default
+	Facility string `json:"Facility,omitempty"`
+}
+
+// Writer writes the events to a destination.
+type Writer interface {
+	Write(e *Event) error
+	Close() error
+}
+
+// Constructor creates a Writer from its configuration.
+type Constructor func(conf Config) (Writer, error)
+
+var (
+	lock         sync.RWMutex
+	constructors = map[string]Constructor{}
+	configs      []Config
+	writers      []Writer
+
+	pending = make(chan *Event, maxPending)
+	dropped uint64
+)
+
+func init() {
+	Register("file", newFileWriter)
+	Register("syslog", newSyslogWriter)
+	Register("journald", newJournaldWriter)
+
+	go worker()
+}
+
+// Register adds a type of sink.
+func Register(sinkType string, c Constructor) {
+	lock.Lock()
+	defer lock.Unlock()
+	constructors[sinkType] = c
+}
+
+// Configure replaces the sinks by the ones configured. If the configuration
+// hasn't changed, the sinks are left untouched.
+func Configure(confs []Config) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	if reflect.DeepEqual(confs, configs) {
+		return
+	}
+	closeWriters()
+	configs = confs
+
+	for _, conf := range confs {
+		w, err := newWriter(conf)
+		if err != nil {
+			log.Error("Error configuring event sink %s: %s", conf.Type, err)
+			continue
+		}
+		log.Info("Exporting events to %s %s", conf.Type, conf.Address)
+		writers = append(writers, w)
+	}
+}
+
+func newWriter(conf Config) (Writer, error) {
+	c, found := constructors[conf.Type]
+	if !found {
+		return nil, fmt.Errorf("Unknown sink type %s", conf.Type)
+	}
+	return c(conf)
+}
+
+// Close closes the sinks.
+func Close() {
+	lock.Lock()
+	defer lock.Unlock()
+	closeWriters()
+	configs = nil
+}
+
+func closeWriters() {
+	for _, w := range writers {
+		w.Close()
+	}
+	writers = nil
+}
+
+// Enabled returns true if there's any sink configured.
+func Enabled() bool {
+	lock.RLock()
+	defer lock.RUnlock()
+	return len(writers) > 0
+}
+
+// OnConnectionEvent exports a connection decision: the rule matched, nil if
+// none, and the action applied. It never blocks: if the sinks can't keep up,
+// the event is lost.
+func OnConnectionEvent(con *conman.Connection, match *rule.Rule, action rule.Action) {
+	if !Enabled() {
+		return
+	}
+	select {
+	case pending <- NewEvent(con, match, action):
+	default:
+		atomic.AddUint64(&dropped, 1)
+	}
+}
+
+func worker() {
+	for e := range pending {
+		// the writers may take a while (reconnecting to a syslog server),
+		// so the lock is not held meanwhile, to not block Configure.
+		// Writing to a writer closed meanwhile just fails.
+		lock.RLock()
+		current := writers
+		lock.RUnlock()
+
+		for _, w := range current {
+			if err := w.Write(e); err != nil {
+				log.Warning("Error exporting event: %s", err)
+			}
+		}
+
+		if n := atomic.SwapUint64(&dropped, 0); n > 0 {
+			log.Warning("%d events not exported, too many connections", n)
+		}
+	}
+}
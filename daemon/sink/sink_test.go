@@ -0,0 +1,226 @@
+package sink
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/evilsocket/opensnitch/daemon/conman"
+	"github.com/evilsocket/opensnitch/daemon/rule"
+)
+
+var testEvent = &Event{
+	Time:     time.Date(2020, 6, 1, 10, 30, 0, 0, time.UTC),
+	Action:   "deny",
+	Rule:     `deny-"curl"`,
+	Process:  "/usr/bin/curl",
+	PID:      1234,
+	UID:      1000,
+	Protocol: "tcp",
+	SrcIP:    "192.168.1.10",
+	SrcPort:  50000,
+	DstIP:    "1.1.1.1",
+	DstHost:  "one.one.one.one",
+	DstPort:  443,
+}
+
+func TestEventJSON(t *testing.T) {
+	var e Event
+	if err := json.Unmarshal(testEvent.JSON(), &e); err != nil {
+		t.Fatal("Error parsing event JSON:", err)
+	}
+	if e != *testEvent {
+		t.Errorf("Event changed after JSON round trip: %+v", e)
+	}
+	if bytes.ContainsRune(testEvent.JSON(), '\n') {
+		t.Error("Event JSON must be a single line")
+	}
+}
+
+func TestFileWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sink")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "events.json")
+
+	w, err := newFileWriter(Config{Type: "file", Address: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(testEvent)
+	w.Write(testEvent)
+	w.Close()
+
+	raw, _ := ioutil.ReadFile(path)
+	lines := strings.Split(strings.TrimSpace(string(raw)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d: %s", len(lines), raw)
+	}
+	if lines[0] != string(testEvent.JSON()) {
+		t.Errorf("Unexpected line: %s", lines[0])
+	}
+}
+
+func TestSyslogFormat(t *testing.T) {
+	w := &syslogWriter{facility: facilities["local0"], hostname: "host"}
+	msg := string(w.format(testEvent))
+
+	// local0 (16) * 8 + notice (5)
+	prefix := fmt.Sprintf("<133>1 2020-06-01T10:30:00.000000Z host opensnitchd %d connection [opensnitch@32473 ", os.Getpid())
+	if !strings.HasPrefix(msg, prefix) {
+		t.Errorf("Unexpected header: %s", msg)
+	}
+	if !strings.Contains(msg, `rule="deny-\"curl\""`) {
+		t.Errorf("Structured data not escaped: %s", msg)
+	}
+	if !strings.HasSuffix(msg, "] "+string(testEvent.JSON())) {
+		t.Errorf("The message must be the event JSON: %s", msg)
+	}
+}
+
+func TestSyslogConfig(t *testing.T) {
+	if _, err := newSyslogWriter(Config{Type: "syslog", Address: "/dev/log"}); err == nil {
+		t.Error("Address without network accepted")
+	}
+	if _, err := newSyslogWriter(Config{Type: "syslog", Address: "udp://127.0.0.1:514", Facility: "nope"}); err == nil {
+		t.Error("Unknown facility accepted")
+	}
+}
+
+func TestJournaldWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sink")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "journal.sock")
+
+	l, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	w, err := newJournaldWriter(Config{Type: "journald", Address: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if err := w.Write(testEvent); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 4096)
+	l.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := l.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := string(buf[:n])
+	for _, field := range []string{
+		"MESSAGE=" + string(testEvent.JSON()) + "\n",
+		"PRIORITY=5\n",
+		"OPENSNITCH_PROCESS=/usr/bin/curl\n",
+		"OPENSNITCH_DST_PORT=443\n",
+	} {
+		if !strings.Contains(entry, field) {
+			t.Errorf("Field %q not found in entry: %s", field, entry)
+		}
+	}
+}
+
+func TestJournaldBinaryField(t *testing.T) {
+	buf := &bytes.Buffer{}
+	writeField(buf, "FIELD", "a\nb")
+	expected := "FIELD\n\x03\x00\x00\x00\x00\x00\x00\x00a\nb\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected binary field: %q", buf.String())
+	}
+}
+
+func TestConfigureUnknownType(t *testing.T) {
+	Configure([]Config{{Type: "nope"}})
+	defer Close()
+	if Enabled() {
+		t.Error("Unknown sink type configured")
+	}
+}
+
+func TestNewEventAction(t *testing.T) {
+	con := &conman.Connection{Protocol: "udp", SrcIP: net.ParseIP("127.0.0.1"), DstIP: net.ParseIP("9.9.9.9")}
+	e := NewEvent(con, nil, rule.Reject)
+	if e.Action != string(rule.Reject) || e.Rule != "" {
+		t.Error("Default action not exported:", e.Action, e.Rule)
+	}
+}
+
+func TestSyslogClose(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	w, err := newSyslogWriter(Config{Type: "syslog", Address: "tcp://" + l.Addr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(testEvent); err != nil {
+		t.Error("Error writing:", err)
+	}
+	w.Close()
+	// a writer closed doesn't connect again
+	if err := w.Write(testEvent); err == nil {
+		t.Error("Event written after closing")
+	}
+}
+
+// blockingWriter blocks the writes until it's released.
+type blockingWriter struct {
+	writing chan bool
+	release chan bool
+}
+
+func (w *blockingWriter) Write(e *Event) error {
+	w.writing <- true
+	<-w.release
+	return nil
+}
+
+func (w *blockingWriter) Close() error {
+	return nil
+}
+
+func TestConfigureWhileWriting(t *testing.T) {
+	w := &blockingWriter{writing: make(chan bool, 1), release: make(chan bool)}
+	Register("blocking", func(conf Config) (Writer, error) {
+		return w, nil
+	})
+	Configure([]Config{{Type: "blocking"}})
+	defer Close()
+
+	pending <- testEvent
+	<-w.writing
+
+	done := make(chan bool)
+	go func() {
+		Configure(nil)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("Configure blocked by a write in progress")
+	}
+	close(w.release)
+	<-done
+}
@@ -0,0 +1,196 @@
+package sink
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/evilsocket/opensnitch/daemon/core"
+)
+
+const (
+	defaultSyslog = "unix:///dev/log"
+	appName       = "opensnitchd"
+	// structured data ID, with the enterprise number reserved for
+	// documentation (RFC 5612).
+	sdID = "opensnitch@32473"
+
+	severityInfo   = 6
+	severityNotice = 5
+
+	// RFC5424 timestamps have up to 6 digits of fractions of a second.
+	timeFormat = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+var facilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5,
+	"lpr": 6, "news": 7, "uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// syslogWriter sends the events as RFC5424 messages, with the fields of the
+// event as structured data, and the event as JSON in the message.
+// The address is unix:///path/to/socket, udp://host:port or tcp://host:port.
+// TCP messages are framed with octet counting (RFC6587).
+type syslogWriter struct {
+	sync.Mutex
+	network  string
+	address  string
+	facility int
+	hostname string
+	con      net.Conn
+	closed   bool
+}
+
+func newSyslogWriter(conf Config) (Writer, error) {
+	address := conf.Address
+	if address == "" {
+		address = defaultSyslog
+	}
+	parts := strings.SplitN(address, "://", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("Invalid syslog address %s, expected unix://, udp:// or tcp://", address)
+	}
+	w := &syslogWriter{
+		network:  parts[0],
+		address:  parts[1],
+		facility: facilities["daemon"],
+		hostname: core.GetHostname(),
+	}
+	switch w.network {
+	case "unix":
+		w.network = "unixgram"
+	case "udp", "tcp":
+	default:
+		return nil, fmt.Errorf("Invalid syslog network %s, expected unix, udp or tcp", parts[0])
+	}
+	if conf.Facility != "" {
+		facility, found := facilities[conf.Facility]
+		if !found {
+			return nil, fmt.Errorf("Unknown syslog facility %s", conf.Facility)
+		}
+		w.facility = facility
+	}
+	if w.hostname == "" {
+		w.hostname = "-"
+	}
+
+	if _, err := w.conn(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// conn returns the connection to the server, connecting to it if needed.
+// The lock is not held while connecting, so Close doesn't wait for it.
+func (w *syslogWriter) conn() (net.Conn, error) {
+	w.Lock()
+	con, closed := w.con, w.closed
+	w.Unlock()
+	if closed {
+		return nil, fmt.Errorf("The syslog sink %s is closed", w.address)
+	}
+	if con != nil {
+		return con, nil
+	}
+
+	con, err := net.DialTimeout(w.network, w.address, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	w.Lock()
+	defer w.Unlock()
+	if w.closed {
+		con.Close()
+		return nil, fmt.Errorf("The syslog sink %s is closed", w.address)
+	}
+	w.con = con
+	return con, nil
+}
+
+// disconnect closes a connection that failed, to connect again on the next write.
+func (w *syslogWriter) disconnect(con net.Conn) {
+	w.Lock()
+	if w.con == con {
+		w.con = nil
+	}
+	w.Unlock()
+	con.Close()
+}
+
+// sdEscape escapes the characters not allowed in structured data values.
+func sdEscape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(value)
+}
+
+// format returns the event as a RFC5424 message.
+func (w *syslogWriter) format(e *Event) []byte {
+	severity := severityInfo
+	if e.Action != "" && e.Action != "allow" {
+		severity = severityNotice
+	}
+
+	params := []string{
+		fmt.Sprintf(`action="%s"`, sdEscape(e.Action)),
+		fmt.Sprintf(`rule="%s"`, sdEscape(e.Rule)),
+		fmt.Sprintf(`process="%s"`, sdEscape(e.Process)),
+		fmt.Sprintf(`pid="%d"`, e.PID),
+		fmt.Sprintf(`uid="%d"`, e.UID),
+		fmt.Sprintf(`protocol="%s"`, sdEscape(e.Protocol)),
+		fmt.Sprintf(`dst_ip="%s"`, sdEscape(e.DstIP)),
+		fmt.Sprintf(`dst_host="%s"`, sdEscape(e.DstHost)),
+		fmt.Sprintf(`dst_port="%d"`, e.DstPort),
+	}
+
+	return []byte(fmt.Sprintf("<%d>1 %s %s %s %d connection [%s %s] %s",
+		w.facility*8+severity,
+		e.Time.Format(timeFormat),
+		w.hostname,
+		appName,
+		os.Getpid(),
+		sdID,
+		strings.Join(params, " "),
+		e.JSON()))
+}
+
+func (w *syslogWriter) Write(e *Event) error {
+	msg := w.format(e)
+	if w.network == "tcp" {
+		msg = append([]byte(fmt.Sprintf("%d ", len(msg))), msg...)
+	}
+
+	con, err := w.conn()
+	if err != nil {
+		return err
+	}
+	if _, err := con.Write(msg); err != nil {
+		// the syslog server may have been restarted, try again once
+		w.disconnect(con)
+		if con, err = w.conn(); err != nil {
+			return err
+		}
+		if _, err = con.Write(msg); err != nil {
+			w.disconnect(con)
+		}
+		return err
+	}
+	return nil
+}
+
+func (w *syslogWriter) Close() error {
+	w.Lock()
+	defer w.Unlock()
+
+	w.closed = true
+	if w.con == nil {
+		return nil
+	}
+	err := w.con.Close()
+	w.con = nil
+	return err
+}
@@ -15,10 +15,90 @@ import (
 const (
 	// max number of events to keep in the buffer
 	maxEvents = 100
-	// max number of entries for each By* map
-	maxStats = 25
+	// default number of entries for each By* map
+	defaultMaxStats = 25
+	// number of processes and rules whose traffic is kept
+	maxTrafficStats = 100
 )
 
+// Config holds the options of the By* stats: how many entries they keep,
+// and the half-life of their counts (1h, 24h...), empty to count the
+// connections since the daemon started.
+//
+// With a half-life, a connection counts half after one half-life, a quarter
+// after two, etc. It's not a window: the connections older than it still
+// count, but the recent ones weigh more, so with "1h" the top entries are
+// roughly the ones of the last hour or two.
+type Config struct {
+	MaxStats int    `json:"MaxStats,omitempty"`
+	HalfLife string `json:"HalfLife,omitempty"`
+}
+
+// Traffic is the data transferred by the connections of a process or a rule,
+// accounted once they're closed.
+type Traffic struct {
+	Connections uint64
+	BytesIn     uint64
+	BytesOut    uint64
+	Duration    time.Duration
+}
+
+// TrafficTop keeps the traffic of the keys (processes, rules) which have
+// transferred the most bytes, with a fixed number of entries, so the
+// processes seen once don't make the stats grow forever. The keys are ranked
+// by a TopN, counting their bytes. It's not safe for concurrent use.
+type TrafficTop struct {
+	top     *TopN
+	traffic map[string]*Traffic
+}
+
+// NewTrafficTop creates a TrafficTop of size entries.
+func NewTrafficTop(size int) *TrafficTop {
+	return &TrafficTop{
+		top:     NewTopN(size, 0),
+		traffic: make(map[string]*Traffic),
+	}
+}
+
+// Add accounts a connection closed to the key.
+func (t *TrafficTop) Add(key string, bytesIn, bytesOut uint64, duration time.Duration) {
+	if evicted, found := t.top.addN(key, float64(bytesIn+bytesOut), time.Now()); found {
+		delete(t.traffic, evicted)
+	}
+	tr, found := t.traffic[key]
+	if !found {
+		tr = &Traffic{}
+		t.traffic[key] = tr
+	}
+	tr.Connections++
+	tr.BytesIn += bytesIn
+	tr.BytesOut += bytesOut
+	tr.Duration += duration
+}
+
+// Serialize returns the traffic of the keys tracked, to be sent to the GUI.
+func (t *TrafficTop) Serialize() map[string]*protocol.Traffic {
+	traffic := make(map[string]*protocol.Traffic, len(t.traffic))
+	for key, tr := range t.traffic {
+		traffic[key] = &protocol.Traffic{
+			Connections: tr.Connections,
+			BytesIn:     tr.BytesIn,
+			BytesOut:    tr.BytesOut,
+			Duration:    uint64(tr.Duration.Seconds()),
+		}
+	}
+	return traffic
+}
+
+// Traffic returns a copy of the traffic of the keys tracked.
+func (t *TrafficTop) Traffic() map[string]Traffic {
+	traffic := make(map[string]Traffic, len(t.traffic))
+	for key, tr := range t.traffic {
+		traffic[key] = *tr
+	}
+	return traffic
+}
+
 type conEvent struct {
 	con       *conman.Connection
 	match     *rule.Rule
@@ -34,35 +114,49 @@ type Statistics struct {
 	Ignored      int
 	Accepted     int
 	Dropped      int
+	Requeued     int
 	RuleHits     int
 	RuleMisses   int
 	Events       []*Event
-	ByProto      map[string]uint64
-	ByAddress    map[string]uint64
-	ByHost       map[string]uint64
-	ByPort       map[string]uint64
-	ByUID        map[string]uint64
-	ByExecutable map[string]uint64
+	ByProto      *TopN
+	ByAddress    *TopN
+	ByHost       *TopN
+	ByPort       *TopN
+	ByUID        *TopN
+	ByExecutable *TopN
+	// connections by protocol since the daemon started, not reset when
+	// the By* stats are reconfigured. There are only a few protocols.
+	ConnectionsByProto map[string]uint64
+	// bytes and duration of the connections closed, of the processes and
+	// rules with the most traffic
+	TrafficByExecutable *TrafficTop
+	TrafficByRule       *TrafficTop
 
-	rules *rule.Loader
-	jobs  chan conEvent
+	config    Config
+	rules     *rule.Loader
+	jobs      chan conEvent
+	listeners map[chan *Event]bool
+	quit      chan bool
+	closeOnce sync.Once
 }
 
 func New(rules *rule.Loader) (stats *Statistics) {
 	stats = &Statistics{
-		Started:      time.Now(),
-		Events:       make([]*Event, 0),
-		ByProto:      make(map[string]uint64),
-		ByAddress:    make(map[string]uint64),
-		ByHost:       make(map[string]uint64),
-		ByPort:       make(map[string]uint64),
-		ByUID:        make(map[string]uint64),
-		ByExecutable: make(map[string]uint64),
+		Started: time.Now(),
+		Events:  make([]*Event, 0),
+
+		ConnectionsByProto:  make(map[string]uint64),
+		TrafficByExecutable: NewTrafficTop(maxTrafficStats),
+		TrafficByRule:       NewTrafficTop(maxTrafficStats),
 
-		rules: rules,
-		jobs:  make(chan conEvent),
+		rules:     rules,
+		jobs:      make(chan conEvent),
+		listeners: make(map[chan *Event]bool),
+		quit:      make(chan bool),
 	}
 
+	stats.resetTops(defaultMaxStats, 0)
+
 	go stats.eventWorker(0)
 	go stats.eventWorker(1)
 	go stats.eventWorker(2)
@@ -85,30 +179,47 @@ func (s *Statistics) OnIgnored() {
 	s.Accepted++
 }
 
-func (s *Statistics) incMap(m *map[string]uint64, key string) {
-	if val, found := (*m)[key]; found == false {
-		// do we have enough space left?
-		nElems := len(*m)
-		if nElems >= maxStats {
-			// find the element with less hits
-			nMin := uint64(9999999999)
-			minKey := ""
-			for k, v := range *m {
-				if v < nMin {
-					minKey = k
-					nMin = v
-				}
-			}
-			// remove it
-			if minKey != "" {
-				delete(*m, minKey)
-			}
+// OnConnectionClosed accounts the bytes received and sent by a connection
+// allowed, and how long it lasted, to its process and rule.
+func (s *Statistics) OnConnectionClosed(process, rule string, bytesIn, bytesOut uint64, duration time.Duration) {
+	s.Lock()
+	defer s.Unlock()
+	s.TrafficByExecutable.Add(process, bytesIn, bytesOut, duration)
+	s.TrafficByRule.Add(rule, bytesIn, bytesOut, duration)
+}
+
+// SetConfig changes the size and half-life of the By* stats. If they change,
+// the stats are reset.
+func (s *Statistics) SetConfig(conf Config) error {
+	maxStats := conf.MaxStats
+	if maxStats <= 0 {
+		maxStats = defaultMaxStats
+	}
+	var halfLife time.Duration
+	if conf.HalfLife != "" {
+		var err error
+		if halfLife, err = time.ParseDuration(conf.HalfLife); err != nil {
+			return fmt.Errorf("Invalid stats half-life %s: %s", conf.HalfLife, err)
 		}
+	}
 
-		(*m)[key] = 1
-	} else {
-		(*m)[key] = val + 1
+	s.Lock()
+	defer s.Unlock()
+	if conf == s.config {
+		return nil
 	}
+	s.config = conf
+	s.resetTops(maxStats, halfLife)
+	return nil
+}
+
+func (s *Statistics) resetTops(maxStats int, halfLife time.Duration) {
+	s.ByProto = NewTopN(maxStats, halfLife)
+	s.ByAddress = NewTopN(maxStats, halfLife)
+	s.ByHost = NewTopN(maxStats, halfLife)
+	s.ByPort = NewTopN(maxStats, halfLife)
+	s.ByUID = NewTopN(maxStats, halfLife)
+	s.ByExecutable = NewTopN(maxStats, halfLife)
 }
 
 func (s *Statistics) eventWorker(id int) {
@@ -118,10 +229,20 @@ func (s *Statistics) eventWorker(id int) {
 		select {
 		case job := <-s.jobs:
 			s.onConnection(job.con, job.match, job.wasMissed)
+		case <-s.quit:
+			return
 		}
 	}
 }
 
+// Close stops the workers of the statistics. The events received
+// afterwards are discarded.
+func (s *Statistics) Close() {
+	s.closeOnce.Do(func() {
+		close(s.quit)
+	})
+}
+
 func (s *Statistics) onConnection(con *conman.Connection, match *rule.Rule, wasMissed bool) {
 	s.Lock()
 	defer s.Unlock()
@@ -134,20 +255,23 @@ func (s *Statistics) onConnection(con *conman.Connection, match *rule.Rule, wasM
 		s.RuleHits++
 	}
 
-	if wasMissed == false && match.Action == rule.Allow {
+	if wasMissed == false && (match.Action == rule.Allow || match.Action == rule.Mark) {
 		s.Accepted++
+	} else if wasMissed == false && match.Action == rule.Requeue {
+		s.Requeued++
 	} else {
 		s.Dropped++
 	}
 
-	s.incMap(&s.ByProto, con.Protocol)
-	s.incMap(&s.ByAddress, con.DstIP.String())
+	s.ConnectionsByProto[con.Protocol]++
+	s.ByProto.Add(con.Protocol)
+	s.ByAddress.Add(con.DstIP.String())
 	if con.DstHost != "" {
-		s.incMap(&s.ByHost, con.DstHost)
+		s.ByHost.Add(con.DstHost)
 	}
-	s.incMap(&s.ByPort, fmt.Sprintf("%d", con.DstPort))
-	s.incMap(&s.ByUID, fmt.Sprintf("%d", con.Entry.UserId))
-	s.incMap(&s.ByExecutable, con.Process.Path)
+	s.ByPort.Add(fmt.Sprintf("%d", con.DstPort))
+	s.ByUID.Add(fmt.Sprintf("%d", con.Entry.UserId))
+	s.ByExecutable.Add(con.Process.Path)
 
 	// if we reached the limit, shift everything back
 	// by one position
@@ -158,14 +282,42 @@ func (s *Statistics) onConnection(con *conman.Connection, match *rule.Rule, wasM
 	if wasMissed {
 		return
 	}
-	s.Events = append(s.Events, NewEvent(con, match))
+	event := NewEvent(con, match)
+	s.Events = append(s.Events, event)
+	for ch := range s.listeners {
+		// slow listeners lose events, but never block the workers
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// AddListener returns a channel where the events are sent as they're
+// recorded. It must be released with RemoveListener.
+func (s *Statistics) AddListener() chan *Event {
+	s.Lock()
+	defer s.Unlock()
+	ch := make(chan *Event, maxEvents)
+	s.listeners[ch] = true
+	return ch
+}
+
+// RemoveListener stops sending the events to the channel.
+func (s *Statistics) RemoveListener(ch chan *Event) {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.listeners, ch)
 }
 
 func (s *Statistics) OnConnectionEvent(con *conman.Connection, match *rule.Rule, wasMissed bool) {
-	s.jobs <- conEvent{
+	select {
+	case s.jobs <- conEvent{
 		con:       con,
 		match:     match,
 		wasMissed: wasMissed,
+	}:
+	case <-s.quit:
 	}
 }
 
@@ -193,14 +345,18 @@ func (s *Statistics) Serialize() *protocol.Statistics {
 		Ignored:       uint64(s.Ignored),
 		Accepted:      uint64(s.Accepted),
 		Dropped:       uint64(s.Dropped),
+		Requeued:      uint64(s.Requeued),
 		RuleHits:      uint64(s.RuleHits),
 		RuleMisses:    uint64(s.RuleMisses),
 		Events:        s.serializeEvents(),
-		ByProto:       s.ByProto,
-		ByAddress:     s.ByAddress,
-		ByHost:        s.ByHost,
-		ByPort:        s.ByPort,
-		ByUid:         s.ByUID,
-		ByExecutable:  s.ByExecutable,
+		ByProto:       s.ByProto.Counts(),
+		ByAddress:     s.ByAddress.Counts(),
+		ByHost:        s.ByHost.Counts(),
+		ByPort:        s.ByPort.Counts(),
+		ByUid:         s.ByUID.Counts(),
+		ByExecutable:  s.ByExecutable.Counts(),
+
+		TrafficByExecutable: s.TrafficByExecutable.Serialize(),
+		TrafficByRule:       s.TrafficByRule.Serialize(),
 	}
 }
@@ -0,0 +1,47 @@
+package statistics
+
+import (
+	"net"
+	"testing"
+
+	"github.com/evilsocket/opensnitch/daemon/conman"
+	"github.com/evilsocket/opensnitch/daemon/netstat"
+	"github.com/evilsocket/opensnitch/daemon/procmon"
+	"github.com/evilsocket/opensnitch/daemon/rule"
+)
+
+func TestConnectionVerdicts(t *testing.T) {
+	rules, _ := rule.NewLoader(false)
+	stats := New(rules)
+	con := &conman.Connection{
+		Protocol: "tcp",
+		DstIP:    net.ParseIP("1.1.1.1"),
+		DstPort:  443,
+		Entry:    &netstat.Entry{},
+		Process:  procmon.NewProcess(1234, "/usr/bin/curl"),
+	}
+	op := &rule.Operator{}
+
+	for _, action := range []rule.Action{rule.Allow, rule.Mark, rule.Requeue, rule.Deny, rule.Reject} {
+		stats.onConnection(con, rule.Create(string(action), true, false, action, rule.Once, op), false)
+	}
+	stats.onConnection(con, nil, true)
+
+	if stats.Accepted != 2 || stats.Requeued != 1 || stats.Dropped != 3 {
+		t.Errorf("Unexpected verdicts: accepted %d, requeued %d, dropped %d", stats.Accepted, stats.Requeued, stats.Dropped)
+	}
+	if st := stats.Serialize(); st.Requeued != 1 {
+		t.Errorf("Requeued not serialized: %d", st.Requeued)
+	}
+
+	// the connections by protocol are counters, never reset
+	if err := stats.SetConfig(Config{MaxStats: 10, HalfLife: "1h"}); err != nil {
+		t.Fatal("SetConfig():", err)
+	}
+	if n := stats.ConnectionsByProto["tcp"]; n != 6 {
+		t.Errorf("Unexpected connections by protocol: %d", n)
+	}
+	if err := stats.SetConfig(Config{HalfLife: "1 hour"}); err == nil {
+		t.Error("Invalid half-life accepted")
+	}
+}
@@ -0,0 +1,160 @@
+package statistics
+
+import (
+	"container/heap"
+	"math"
+	"time"
+)
+
+// rescale the counters before the weights of the forward decay overflow.
+const maxWeight = 1e100
+
+// TopN keeps the most frequent keys of a stream of hits, with a fixed number
+// of counters, using the Space-Saving algorithm: when a new key arrives and
+// there's no counter left, it replaces the key with the fewest hits, and
+// inherits its count. The keys with more hits than the evicted counts are
+// never evicted, so the top keys are always right, and their counts are
+// overestimated by at most the count of the key they replaced.
+//
+// With a half-life, the hits decay exponentially: a hit counts half after one
+// half-life, a quarter after two, etc., so the top keys are the recent ones
+// (roughly the ones of the last half-life or two) rather than the ones since
+// the daemon started. The decay is applied with forward decay: new hits weigh
+// more, instead of decaying all the counters on every hit, which keeps the
+// order of the counters, and so the Space-Saving guarantees.
+//
+// It's not safe for concurrent use.
+type TopN struct {
+	size     int
+	halfLife time.Duration
+	lambda   float64
+	start    time.Time
+	entries  map[string]*topEntry
+	heap     topHeap
+}
+
+type topEntry struct {
+	key string
+	// hits, weighted by the forward decay
+	count float64
+	index int
+}
+
+// topHeap is a min-heap of the counters, by count.
+type topHeap []*topEntry
+
+func (h topHeap) Len() int           { return len(h) }
+func (h topHeap) Less(i, j int) bool { return h[i].count < h[j].count }
+func (h topHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *topHeap) Push(x interface{}) {
+	e := x.(*topEntry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *topHeap) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
+
+// NewTopN creates a TopN with size counters. A halfLife of 0 disables the
+// decay, counting the hits since it was created.
+func NewTopN(size int, halfLife time.Duration) *TopN {
+	t := &TopN{
+		size:     size,
+		halfLife: halfLife,
+		start:    time.Now(),
+		entries:  make(map[string]*topEntry),
+		heap:     make(topHeap, 0, size),
+	}
+	if halfLife > 0 {
+		t.lambda = math.Ln2 / halfLife.Seconds()
+	}
+	return t
+}
+
+// weight returns how much a hit at the given time counts.
+func (t *TopN) weight(now time.Time) float64 {
+	if t.lambda == 0 {
+		return 1
+	}
+	return math.Exp(t.lambda * now.Sub(t.start).Seconds())
+}
+
+// rescale moves the landmark of the forward decay to now, so the weights
+// start again from 1.
+func (t *TopN) rescale(now time.Time) {
+	w := t.weight(now)
+	for _, e := range t.heap {
+		e.count /= w
+	}
+	t.start = now
+}
+
+// Add counts a hit of the key.
+func (t *TopN) Add(key string) {
+	t.add(key, time.Now())
+}
+
+func (t *TopN) add(key string, now time.Time) {
+	t.addN(key, 1, now)
+}
+
+// addN counts n hits of the key, and returns the key evicted to make room
+// for it, if any.
+func (t *TopN) addN(key string, n float64, now time.Time) (evicted string, found bool) {
+	if t.size <= 0 {
+		return "", false
+	}
+	w := t.weight(now)
+	if w > maxWeight {
+		t.rescale(now)
+		w = 1
+	}
+
+	if e, found := t.entries[key]; found {
+		e.count += n * w
+		heap.Fix(&t.heap, e.index)
+		return "", false
+	}
+	if len(t.heap) < t.size {
+		e := &topEntry{key: key, count: n * w}
+		heap.Push(&t.heap, e)
+		t.entries[key] = e
+		return "", false
+	}
+
+	// replace the key with the fewest hits
+	e := t.heap[0]
+	evicted = e.key
+	delete(t.entries, e.key)
+	e.key = key
+	e.count += n * w
+	t.entries[key] = e
+	heap.Fix(&t.heap, 0)
+	return evicted, true
+}
+
+// Counts returns the keys tracked, and their hits, decayed to now.
+// The keys whose hits have decayed below 1 are not returned.
+func (t *TopN) Counts() map[string]uint64 {
+	return t.counts(time.Now())
+}
+
+func (t *TopN) counts(now time.Time) map[string]uint64 {
+	w := t.weight(now)
+	counts := make(map[string]uint64, len(t.heap))
+	for _, e := range t.heap {
+		if n := uint64(math.Round(e.count / w)); n > 0 {
+			counts[e.key] = n
+		}
+	}
+	return counts
+}
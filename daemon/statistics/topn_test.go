@@ -0,0 +1,117 @@
+package statistics
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestTopNCounts(t *testing.T) {
+	top := NewTopN(3, 0)
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		top.add("a", now)
+	}
+	top.add("b", now)
+	top.add("b", now)
+	top.add("c", now)
+
+	counts := top.counts(now)
+	if counts["a"] != 5 || counts["b"] != 2 || counts["c"] != 1 {
+		t.Errorf("Unexpected counts: %v", counts)
+	}
+}
+
+func TestTopNEviction(t *testing.T) {
+	top := NewTopN(3, 0)
+	now := time.Now()
+	// a few heavy hitters, and a lot of keys seen once, which used to evict
+	// arbitrary keys
+	for i := 0; i < 100; i++ {
+		top.add("heavy1", now)
+		top.add("heavy2", now)
+		top.add(fmt.Sprintf("noise%d", i), now)
+	}
+
+	counts := top.counts(now)
+	if len(counts) != 3 {
+		t.Errorf("Expected 3 keys, got %d: %v", len(counts), counts)
+	}
+	if counts["heavy1"] != 100 || counts["heavy2"] != 100 {
+		t.Errorf("Heavy hitters lost: %v", counts)
+	}
+	// the last key replaced the previous one, and inherited its count
+	if counts["noise99"] != 100 {
+		t.Errorf("Evicted count not inherited: %v", counts)
+	}
+}
+
+func TestTopNDecay(t *testing.T) {
+	top := NewTopN(2, time.Hour)
+	now := time.Now()
+	for i := 0; i < 100; i++ {
+		top.add("old", now)
+	}
+	later := now.Add(2 * time.Hour)
+	for i := 0; i < 40; i++ {
+		top.add("new", later)
+	}
+
+	counts := top.counts(later)
+	// 100 hits two half-lives ago count a quarter
+	if counts["old"] != 25 || counts["new"] != 40 {
+		t.Errorf("Unexpected decayed counts: %v", counts)
+	}
+
+	// a third key evicts the one with fewer recent hits, the old one
+	top.add("newer", later)
+	if _, found := top.counts(later)["old"]; found {
+		t.Errorf("Old key not evicted: %v", top.counts(later))
+	}
+}
+
+func TestTopNRescale(t *testing.T) {
+	top := NewTopN(2, time.Second)
+	now := time.Now()
+	top.add("a", now)
+	top.add("a", now)
+	// weights beyond maxWeight are rescaled, instead of overflowing
+	later := now.Add(400 * time.Second)
+	top.add("b", later)
+	top.add("b", later)
+
+	counts := top.counts(later)
+	if counts["b"] != 2 {
+		t.Errorf("Unexpected counts after rescaling: %v", counts)
+	}
+	if _, found := counts["a"]; found {
+		t.Errorf("Decayed key still counted: %v", counts)
+	}
+}
+
+func TestTrafficTop(t *testing.T) {
+	top := NewTrafficTop(2)
+	top.Add("/usr/bin/curl", 1000, 100, time.Second)
+	top.Add("/usr/bin/curl", 1000, 100, time.Second)
+	top.Add("/usr/bin/wget", 10, 10, time.Second)
+	// the process with the least traffic is evicted
+	top.Add("/usr/bin/nc", 5, 5, time.Second)
+
+	traffic := top.Traffic()
+	if len(traffic) != 2 {
+		t.Fatalf("Expected 2 processes, got %d: %v", len(traffic), traffic)
+	}
+	if tr := traffic["/usr/bin/curl"]; tr.Connections != 2 || tr.BytesIn != 2000 || tr.BytesOut != 200 || tr.Duration != 2*time.Second {
+		t.Errorf("Unexpected traffic: %+v", tr)
+	}
+	if _, found := traffic["/usr/bin/wget"]; found {
+		t.Errorf("Evicted process still tracked: %v", traffic)
+	}
+	if tr := traffic["/usr/bin/nc"]; tr.Connections != 1 || tr.BytesIn != 5 {
+		t.Errorf("Traffic of the evicted process inherited: %+v", tr)
+	}
+
+	if st := top.Serialize()["/usr/bin/curl"]; st == nil || st.Connections != 2 || st.BytesIn != 2000 || st.BytesOut != 200 || st.Duration != 2 {
+		t.Errorf("Traffic not serialized: %v", st)
+	}
+}
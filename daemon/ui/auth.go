@@ -0,0 +1,130 @@
+package ui
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+
+	"github.com/evilsocket/opensnitch/daemon/log"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// tlsOptions holds the certificates used to connect to the UI services
+// listening on a TCP address. Unix sockets don't use them.
+//
+// The certificate of the server is verified against CACert, which is pinned:
+// the CAs of the system are not used if it's set. If ClientCert and ClientKey
+// are set, the daemon authenticates itself to the server with them, so the
+// server can verify which daemons connect to it (mutual TLS).
+type tlsOptions struct {
+	CACert     string `json:"CACert,omitempty"`
+	ClientCert string `json:"ClientCert,omitempty"`
+	ClientKey  string `json:"ClientKey,omitempty"`
+	// name expected in the certificate of the server, if it's not the host
+	// of the address.
+	ServerName string `json:"ServerName,omitempty"`
+}
+
+func (o *tlsOptions) config() (*tls.Config, error) {
+	conf := &tls.Config{
+		ServerName: o.ServerName,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if o.CACert != "" {
+		raw, err := ioutil.ReadFile(o.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("Error loading CA certificate %s: %s", o.CACert, err)
+		}
+		pool := x509.NewCertPool()
+		if pool.AppendCertsFromPEM(raw) == false {
+			return nil, fmt.Errorf("No valid certificates found in %s", o.CACert)
+		}
+		conf.RootCAs = pool
+	}
+
+	if o.ClientCert != "" || o.ClientKey != "" {
+		if o.ClientCert == "" || o.ClientKey == "" {
+			return nil, fmt.Errorf("Both ClientCert and ClientKey are needed to authenticate to the server")
+		}
+		cert, err := tls.LoadX509KeyPair(o.ClientCert, o.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("Error loading client certificate %s: %s", o.ClientCert, err)
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+
+	return conf, nil
+}
+
+// serverConfig returns the configuration of a service listening on a TCP
+// address, the other end of config(): the service presents the certificate
+// ClientCert and ClientKey, and the clients must present a certificate signed
+// by CACert, as anyone connected to the service can manage the daemon.
+func (o *tlsOptions) serverConfig() (*tls.Config, error) {
+	if o.CACert == "" || o.ClientCert == "" || o.ClientKey == "" {
+		return nil, fmt.Errorf("The CA of the clients, and the certificate and key of the service are needed")
+	}
+	conf, err := o.config()
+	if err != nil {
+		return nil, err
+	}
+	conf.ClientCAs = conf.RootCAs
+	conf.RootCAs = nil
+	conf.ClientAuth = tls.RequireAndVerifyClientCert
+	return conf, nil
+}
+
+// setTLS changes the TLS options of the TCP connections. The connections
+// already established with other options are closed, to be reconnected with
+// the new ones.
+func (c *Client) setTLS(opts *tlsOptions, allowPlaintext bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	if reflect.DeepEqual(opts, c.tls) && allowPlaintext == c.allowPlaintext {
+		return
+	}
+	if opts == nil && allowPlaintext {
+		log.Warning("Plaintext connections to the UI services listening on TCP addresses are allowed")
+	}
+	if opts != nil {
+		// don't share the options with the global configuration
+		o := *opts
+		opts = &o
+	}
+	c.tls = opts
+	c.allowPlaintext = allowPlaintext
+
+	for _, srv := range c.servers {
+		if !srv.isUnixSocket {
+			srv.disconnect()
+		}
+	}
+}
+
+// transportOption returns the dial option for the UI services listening on
+// TCP addresses: TLS if it's configured, otherwise plaintext only if it has
+// been explicitly allowed.
+func (c *Client) transportOption() (grpc.DialOption, error) {
+	c.RLock()
+	opts, allowPlaintext := c.tls, c.allowPlaintext
+	c.RUnlock()
+
+	if opts == nil {
+		if !allowPlaintext {
+			return nil, fmt.Errorf("Refusing to connect to a TCP address without TLS, configure Server.TLS or set Server.AllowPlaintext")
+		}
+		return grpc.WithInsecure(), nil
+	}
+
+	conf, err := opts.config()
+	if err != nil {
+		return nil, err
+	}
+	return grpc.WithTransportCredentials(credentials.NewTLS(conf)), nil
+}
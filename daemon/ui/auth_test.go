@@ -0,0 +1,191 @@
+package ui
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/evilsocket/opensnitch/daemon/rule"
+	"github.com/evilsocket/opensnitch/daemon/statistics"
+	"github.com/evilsocket/opensnitch/daemon/ui/protocol"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// writeCert writes a self signed certificate and its key to dir.
+func writeCert(t *testing.T, dir string) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "opensnitch-ui"},
+		DNSNames:              []string{"opensnitch-ui"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawKey, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: rawKey}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestTLSOptionsConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "opensnitch-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFile, keyFile := writeCert(t, dir)
+	invalid := filepath.Join(dir, "invalid.pem")
+	if err := ioutil.WriteFile(invalid, []byte("not a certificate"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	conf, err := (&tlsOptions{CACert: certFile, ClientCert: certFile, ClientKey: keyFile, ServerName: "ui"}).config()
+	if err != nil {
+		t.Fatal("Error loading the TLS options:", err)
+	}
+	if conf.RootCAs == nil || len(conf.Certificates) != 1 || conf.ServerName != "ui" {
+		t.Error("TLS options not applied:", conf)
+	}
+
+	for name, opts := range map[string]*tlsOptions{
+		"missing CA":          {CACert: filepath.Join(dir, "missing.pem")},
+		"invalid CA":          {CACert: invalid},
+		"cert without key":    {ClientCert: certFile},
+		"key without cert":    {ClientKey: keyFile},
+		"invalid client cert": {ClientCert: invalid, ClientKey: keyFile},
+	} {
+		if _, err := opts.config(); err == nil {
+			t.Error("Error expected with", name)
+		}
+	}
+}
+
+func TestServiceTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "opensnitch-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeCert(t, dir)
+
+	rules, _ := rule.NewLoader(false)
+	c := &Client{stats: statistics.New(rules)}
+	s := NewService("127.0.0.1:0", c, nil)
+	if err := s.Serve(); err == nil {
+		s.Stop()
+		t.Fatal("TCP address without TLS not refused")
+	}
+	s.SetTLS("", certFile, keyFile)
+	if err := s.Serve(); err == nil {
+		s.Stop()
+		t.Fatal("TCP address without the CA of the clients not refused")
+	}
+	s.SetTLS(certFile, certFile, keyFile)
+	if err := s.Serve(); err != nil {
+		t.Fatal("Error serving with TLS:", err)
+	}
+	defer s.Stop()
+
+	// the certificate signs itself, so it's the CA, the server and the client
+	getStatistics := func(opts *tlsOptions) error {
+		conf, err := opts.config()
+		if err != nil {
+			t.Fatal(err)
+		}
+		con, err := grpc.Dial(s.listener.Addr().String(), grpc.WithTransportCredentials(credentials.NewTLS(conf)))
+		if err != nil {
+			return err
+		}
+		defer con.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, err = protocol.NewDaemonClient(con).GetStatistics(ctx, &protocol.Notification{})
+		return err
+	}
+	if err := getStatistics(&tlsOptions{CACert: certFile, ServerName: "opensnitch-ui"}); err == nil {
+		t.Error("Client without certificate accepted")
+	}
+	if err := getStatistics(&tlsOptions{CACert: certFile, ClientCert: certFile, ClientKey: keyFile, ServerName: "opensnitch-ui"}); err != nil {
+		t.Error("Client with a valid certificate refused:", err)
+	}
+}
+
+func TestTransportOption(t *testing.T) {
+	dir, err := ioutil.TempDir("", "opensnitch-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certFile, _ := writeCert(t, dir)
+
+	c := &Client{userID: -1, stats: statistics.New(nil)}
+	c.clientCtx, c.clientCancel = context.WithCancel(context.Background())
+	defer c.Close()
+
+	if _, err := c.transportOption(); err == nil {
+		t.Error("Plaintext connections not refused")
+	}
+	c.setTLS(nil, true)
+	if opt, err := c.transportOption(); err != nil || opt == nil {
+		t.Error("Plaintext connections not allowed:", err)
+	}
+	c.setTLS(&tlsOptions{CACert: filepath.Join(dir, "missing.pem")}, false)
+	if _, err := c.transportOption(); err == nil {
+		t.Error("Invalid TLS options not refused")
+	}
+
+	// the options removed from the configuration stop applying on reload
+	if !c.loadConfiguration([]byte(`{"Server": {"Address": "127.0.0.1:50051", "TLS": {"CACert": "` + certFile + `"}}}`)) {
+		t.Fatal("Configuration not loaded")
+	}
+	if c.tls == nil || c.tls.CACert != certFile {
+		t.Error("TLS options not loaded:", c.tls)
+	}
+	if opt, err := c.transportOption(); err != nil || opt == nil {
+		t.Error("TLS options not applied:", err)
+	}
+	if !c.loadConfiguration([]byte(`{"Server": {"Address": "127.0.0.1:50051", "AllowPlaintext": true}}`)) {
+		t.Fatal("Configuration not reloaded")
+	}
+	if c.tls != nil || !c.allowPlaintext {
+		t.Error("TLS options removed still applied:", c.tls)
+	}
+	if !c.loadConfiguration([]byte(`{"Server": {"Address": "127.0.0.1:50051"}}`)) {
+		t.Fatal("Configuration not reloaded")
+	}
+	if _, err := c.transportOption(); err == nil {
+		t.Error("Plaintext connections still allowed after removing AllowPlaintext")
+	}
+}
@@ -1,21 +1,20 @@
 package ui
 
 import (
-	"fmt"
-	"net"
 	"sync"
 	"time"
 
 	"github.com/evilsocket/opensnitch/daemon/conman"
+	"github.com/evilsocket/opensnitch/daemon/firewall"
 	"github.com/evilsocket/opensnitch/daemon/log"
+	"github.com/evilsocket/opensnitch/daemon/procmon"
 	"github.com/evilsocket/opensnitch/daemon/rule"
+	"github.com/evilsocket/opensnitch/daemon/sink"
 	"github.com/evilsocket/opensnitch/daemon/statistics"
 	"github.com/evilsocket/opensnitch/daemon/ui/protocol"
 
 	"github.com/fsnotify/fsnotify"
 	"golang.org/x/net/context"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/connectivity"
 )
 
 var (
@@ -26,44 +25,67 @@ var (
 	config                 Config
 )
 
+// serverConfig holds the addresses of the UI services to connect to.
+// Address is the main UI, and Addresses any other UIs the daemon talks to at
+// the same time. Stats and events are sent to all of them.
+// The connections are asked to the Primary UI if it's connected, otherwise
+// to all of them, applying the rule of the first one that replies.
+// The UIs listening on TCP addresses are connected with TLS, plaintext
+// connections are only used if AllowPlaintext is set.
 type serverConfig struct {
-	Address string `json:"Address"`
-	LogFile string `json:"LogFile"`
+	Address        string      `json:"Address"`
+	Addresses      []string    `json:"Addresses,omitempty"`
+	Primary        string      `json:"Primary,omitempty"`
+	TLS            *tlsOptions `json:"TLS,omitempty"`
+	AllowPlaintext bool        `json:"AllowPlaintext,omitempty"`
+	LogFile        string      `json:"LogFile"`
 }
 
 // Config holds the values loaded from configFile
 type Config struct {
 	sync.RWMutex
-	Server            serverConfig `json:"Server"`
-	DefaultAction     string       `json:"DefaultAction"`
-	DefaultDuration   string       `json:"DefaultDuration"`
-	InterceptUnknown  bool         `json:"InterceptUnknown"`
-	ProcMonitorMethod string       `json:"ProcMonitorMethod"`
-	LogLevel          *uint32      `json:"LogLevel"`
+	Server            serverConfig      `json:"Server"`
+	DefaultAction     string            `json:"DefaultAction"`
+	DefaultDuration   string            `json:"DefaultDuration"`
+	InterceptUnknown  bool              `json:"InterceptUnknown"`
+	ProcMonitorMethod string            `json:"ProcMonitorMethod"`
+	LogLevel          *uint32           `json:"LogLevel"`
+	Firewall          string            `json:"Firewall"`
+	EventSinks        []sink.Config     `json:"EventSinks,omitempty"`
+	Stats             statistics.Config `json:"Stats"`
 }
 
-// Client holds the connection information of a client.
+// Client holds the connections to the UI services.
 type Client struct {
 	sync.RWMutex
 	clientCtx    context.Context
 	clientCancel context.CancelFunc
 
-	stats               *statistics.Statistics
-	rules               *rule.Loader
-	socketPath          string
-	isUnixSocket        bool
-	con                 *grpc.ClientConn
-	client              protocol.UIClient
-	configWatcher       *fsnotify.Watcher
-	streamNotifications protocol.UI_NotificationsClient
+	stats         *statistics.Statistics
+	rules         *rule.Loader
+	fw            firewall.Firewall
+	configWatcher *fsnotify.Watcher
+	servers       []*serverConn
+	primary       string
+	// TLS options of the TCP connections
+	tls            *tlsOptions
+	allowPlaintext bool
+	// address passed from the command line, it replaces Server.Address
+	socketPath string
+
+	// uid of the user whose GUI this client connects to,
+	// -1 for the GUI of the system.
+	userID int
 }
 
 // NewClient creates and configures a new client.
-func NewClient(socketPath string, stats *statistics.Statistics, rules *rule.Loader) *Client {
+func NewClient(socketPath string, stats *statistics.Statistics, rules *rule.Loader, fw firewall.Firewall) *Client {
 	c := &Client{
-		stats:        stats,
-		rules:        rules,
-		isUnixSocket: false,
+		stats:      stats,
+		rules:      rules,
+		fw:         fw,
+		socketPath: socketPath,
+		userID:     -1,
 	}
 	c.clientCtx, c.clientCancel = context.WithCancel(context.Background())
 
@@ -71,19 +93,91 @@ func NewClient(socketPath string, stats *statistics.Statistics, rules *rule.Load
 		c.configWatcher = watcher
 	}
 	c.loadDiskConfiguration(false)
-	if socketPath != "" {
-		c.setSocketPath(c.getSocketPath(socketPath))
+	if socketPath != "" && len(c.servers) == 0 {
+		c.setServers([]string{socketPath}, "")
 	}
 
-	go c.poller()
 	return c
 }
 
-// Close cancels the running tasks: pinging the server and (re)connection poller.
+// newUserClient creates a client which connects to the GUI of a user.
+// It only manages the rules of that user, and never changes the configuration
+// of the daemon.
+func newUserClient(uid int, socketPath string, stats *statistics.Statistics, rules *rule.Loader, fw firewall.Firewall) *Client {
+	c := &Client{
+		stats:  stats,
+		rules:  rules,
+		fw:     fw,
+		userID: uid,
+	}
+	c.clientCtx, c.clientCancel = context.WithCancel(context.Background())
+	c.setServers([]string{socketPath}, "")
+
+	return c
+}
+
+// IsUserClient returns true if the client connects to the GUI of a user,
+// instead of the GUI of the system.
+func (c *Client) IsUserClient() bool {
+	return c.userID != -1
+}
+
+// Close cancels the running tasks: pinging the servers and (re)connection pollers.
 func (c *Client) Close() {
 	c.clientCancel()
 }
 
+// setServers connects to the given addresses, keeping the connections already
+// established, and closing the ones not in the list.
+func (c *Client) setServers(addresses []string, primary string) {
+	c.Lock()
+	defer c.Unlock()
+
+	current := make(map[string]*serverConn)
+	for _, srv := range c.servers {
+		current[srv.address] = srv
+	}
+	seen := make(map[string]bool)
+	servers := make([]*serverConn, 0, len(addresses))
+	for _, addr := range addresses {
+		if addr == "" || seen[addr] {
+			continue
+		}
+		seen[addr] = true
+		srv, found := current[addr]
+		if !found {
+			srv = newServerConn(c, addr)
+			go srv.poller()
+		}
+		delete(current, addr)
+		servers = append(servers, srv)
+	}
+	for addr, srv := range current {
+		log.Info("Disconnecting from the UI service %s", addr)
+		srv.Close()
+	}
+	c.servers = servers
+	c.primary = primary
+}
+
+// connectedServers returns the servers connected, or only the primary if
+// it's connected.
+func (c *Client) connectedServers() []*serverConn {
+	c.RLock()
+	defer c.RUnlock()
+
+	connected := make([]*serverConn, 0, len(c.servers))
+	for _, srv := range c.servers {
+		if srv.Connected() {
+			if srv.address == c.primary {
+				return []*serverConn{srv}
+			}
+			connected = append(connected, srv)
+		}
+	}
+	return connected
+}
+
 // ProcMonitorMethod returns the monitor method configured.
 // If it's not present in the config file, it'll return an empty string.
 func (c *Client) ProcMonitorMethod() string {
@@ -101,180 +195,68 @@ func (c *Client) InterceptUnknown() bool {
 
 // DefaultAction returns the default configured action for
 func (c *Client) DefaultAction() rule.Action {
-	c.RLock()
-	defer c.RUnlock()
+	config.RLock()
+	defer config.RUnlock()
 	return clientDisconnectedRule.Action
 }
 
 // DefaultDuration returns the default duration configured for a rule.
 // For example it can be: once, always, "until restart".
 func (c *Client) DefaultDuration() rule.Duration {
-	c.RLock()
-	defer c.RUnlock()
+	config.RLock()
+	defer config.RUnlock()
 	return clientDisconnectedRule.Duration
 }
 
-// Connected checks if the client has established a connection with the server.
+// Connected checks if the client has established a connection with any server.
 func (c *Client) Connected() bool {
-	c.Lock()
-	defer c.Unlock()
-	if c.con == nil || c.con.GetState() != connectivity.Ready {
-		return false
-	}
-	return true
-}
-
-func (c *Client) poller() {
-	log.Debug("UI service poller started for socket %s", c.socketPath)
-	wasConnected := false
-	for {
-		select {
-		case <-c.clientCtx.Done():
-			log.Info("Client.poller() exit, Done()")
-			goto Exit
-		default:
-			isConnected := c.Connected()
-			if wasConnected != isConnected {
-				c.onStatusChange(isConnected)
-				wasConnected = isConnected
-			}
-
-			if c.Connected() == false {
-				// connect and create the client if needed
-				if err := c.connect(); err != nil {
-					log.Warning("Error while connecting to UI service: %s", err)
-				}
-			}
-			if c.Connected() == true {
-				// if the client is connected and ready, send a ping
-				if err := c.ping(time.Now()); err != nil {
-					log.Warning("Error while pinging UI service: %s", err)
-				}
-			}
-
-			time.Sleep(1 * time.Second)
-		}
-	}
-Exit:
-	log.Info("uiClient exit")
+	return len(c.connectedServers()) > 0
 }
 
-func (c *Client) onStatusChange(connected bool) {
-	if connected {
-		log.Info("Connected to the UI service on %s", c.socketPath)
-		go c.Subscribe()
-	} else {
-		log.Error("Connection to the UI service lost.")
-		c.disconnect()
-	}
-}
-
-func (c *Client) connect() (err error) {
-	if c.Connected() {
-		return
-	}
-
-	if c.con != nil {
-		if c.con.GetState() == connectivity.TransientFailure || c.con.GetState() == connectivity.Shutdown {
-			c.disconnect()
-		} else {
-			return
-		}
-	}
-
-	if err := c.openSocket(); err != nil {
-		c.disconnect()
-		return err
-	}
-
-	if c.client == nil {
-		c.client = protocol.NewUIClient(c.con)
-	}
-	return nil
-}
-
-func (c *Client) openSocket() (err error) {
-	c.Lock()
-	defer c.Unlock()
-
-	if c.isUnixSocket {
-		c.con, err = grpc.Dial(c.socketPath, grpc.WithInsecure(),
-			grpc.WithDialer(func(addr string, timeout time.Duration) (net.Conn, error) {
-				return net.DialTimeout("unix", addr, timeout)
-			}))
-	} else {
-		c.con, err = grpc.Dial(c.socketPath, grpc.WithInsecure())
-	}
-
-	return err
-}
-
-func (c *Client) disconnect() {
-	c.Lock()
-	defer c.Unlock()
-
-	c.client = nil
-	if c.con != nil {
-		c.con.Close()
-		c.con = nil
-		log.Debug("client.disconnect()")
-	}
-}
-
-func (c *Client) ping(ts time.Time) (err error) {
-	if c.Connected() == false {
-		return fmt.Errorf("service is not connected")
-	}
-
-	c.Lock()
-	defer c.Unlock()
-
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
-	defer cancel()
-	reqID := uint64(ts.UnixNano())
-
-	pReq := &protocol.PingRequest{
-		Id:    reqID,
-		Stats: c.stats.Serialize(),
-	}
-	c.stats.RLock()
-	pong, err := c.client.Ping(ctx, pReq)
-	c.stats.RUnlock()
-	if err != nil {
-		return err
-	}
-
-	if pong.Id != reqID {
-		return fmt.Errorf("Expected pong with id 0x%x, got 0x%x", reqID, pong.Id)
-	}
-
-	return nil
-}
-
-// Ask sends a request to the server, with the values of a connection to be
-// allowed or denied.
+// Ask sends a request to the servers, with the values of a connection to be
+// allowed or denied. If the primary server is connected, only that one is
+// asked. Otherwise all the servers are asked, and the first reply wins.
 func (c *Client) Ask(con *conman.Connection) (*rule.Rule, bool) {
-	if c.Connected() == false {
+	servers := c.connectedServers()
+	if len(servers) == 0 {
 		return clientDisconnectedRule, false
 	}
 
-	c.Lock()
-	defer c.Unlock()
-
 	// FIXME: if timeout is fired, the rule is not added to the list in the GUI
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*120)
+	ctx, cancel := context.WithTimeout(c.clientCtx, time.Second*120)
 	defer cancel()
-	reply, err := c.client.AskRule(ctx, con.Serialize())
-	if err != nil {
-		log.Warning("Error while asking for rule: %s - %v", err, con)
-		return nil, false
+
+	// the sha256 is always shown, so the rule created can pin the exact binary,
+	// besides the checksums used by the rules
+	if err := con.Process.ReadChecksums(procmon.HashSHA256); err != nil {
+		log.Debug("Unable to compute the checksum of %s: %s", con.Process.Path, err)
+	}
+	pcon := con.Serialize()
+	replies := make(chan *protocol.Rule, len(servers))
+	for _, srv := range servers {
+		go func(srv *serverConn) {
+			reply, err := srv.askRule(ctx, pcon)
+			if err != nil {
+				log.Warning("Error while asking %s for rule: %s - %v", srv.address, err, con)
+			}
+			replies <- reply
+		}(srv)
 	}
 
-	r, err := rule.Deserialize(reply)
-	if err != nil {
-		return nil, false
+	for range servers {
+		reply := <-replies
+		if reply == nil {
+			continue
+		}
+		// the other servers don't need to reply anymore
+		cancel()
+		r, err := rule.Deserialize(reply)
+		if err != nil {
+			return nil, false
+		}
+		return r, true
 	}
-	return r, true
+	return nil, false
 }
 
 func (c *Client) monitorConfigWorker() {
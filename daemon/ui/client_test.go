@@ -0,0 +1,137 @@
+package ui
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/evilsocket/opensnitch/daemon/conman"
+	"github.com/evilsocket/opensnitch/daemon/netstat"
+	"github.com/evilsocket/opensnitch/daemon/procmon"
+	"github.com/evilsocket/opensnitch/daemon/rule"
+	"github.com/evilsocket/opensnitch/daemon/ui/protocol"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+// fakeUI replies to the connections asked with a rule named after it,
+// after the given delay.
+type fakeUI struct {
+	protocol.UnimplementedUIServer
+	name      string
+	delay     time.Duration
+	asked     chan bool
+	cancelled chan bool
+	// the last connection asked, read after receiving from asked
+	con *protocol.Connection
+}
+
+func (f *fakeUI) AskRule(ctx context.Context, con *protocol.Connection) (*protocol.Rule, error) {
+	f.con = con
+	f.asked <- true
+	select {
+	case <-time.After(f.delay):
+	case <-ctx.Done():
+		f.cancelled <- true
+		return nil, ctx.Err()
+	}
+	return &protocol.Rule{
+		Name:     f.name,
+		Enabled:  true,
+		Action:   string(rule.Deny),
+		Duration: string(rule.Once),
+		Operator: &protocol.Operator{Type: string(rule.Simple), Operand: string(rule.OpTrue)},
+	}, nil
+}
+
+// startUI serves a fakeUI on a unix socket, and connects a server of the
+// client to it.
+func startUI(t *testing.T, c *Client, dir, name string, delay time.Duration) (*fakeUI, *serverConn) {
+	path := filepath.Join(dir, name+".sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal("Error listening:", err)
+	}
+	ui := &fakeUI{name: name, delay: delay, asked: make(chan bool, 1), cancelled: make(chan bool, 1)}
+	s := grpc.NewServer()
+	protocol.RegisterUIServer(s, ui)
+	go s.Serve(l)
+
+	srv := newServerConn(c, "unix://"+path)
+	if err := srv.connect(); err != nil {
+		t.Fatal("Error connecting:", err)
+	}
+	for i := 0; i < 100 && !srv.Connected(); i++ {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if !srv.Connected() {
+		t.Fatal("Not connected to", name)
+	}
+	return ui, srv
+}
+
+func TestClientAsk(t *testing.T) {
+	dir, err := ioutil.TempDir("", "opensnitch-client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := &Client{userID: -1}
+	c.clientCtx, c.clientCancel = context.WithCancel(context.Background())
+	defer c.Close()
+
+	con := &conman.Connection{
+		Protocol: "tcp",
+		SrcIP:    net.ParseIP("127.0.0.1"),
+		DstIP:    net.ParseIP("127.0.0.1"),
+		Entry:    &netstat.Entry{UserId: 1000},
+		Process:  procmon.NewProcess(os.Getpid(), "/usr/bin/curl"),
+	}
+
+	if r, ok := c.Ask(con); ok || r != clientDisconnectedRule {
+		t.Error("Default rule not applied without servers:", r)
+	}
+
+	slow, slowSrv := startUI(t, c, dir, "slow", 10*time.Second)
+	fast, fastSrv := startUI(t, c, dir, "fast", 0)
+	c.servers = []*serverConn{slowSrv, fastSrv}
+
+	// the first reply wins, and the other servers are not waited for
+	if r, ok := c.Ask(con); !ok || r.Name != "fast" {
+		t.Error("First reply not applied:", r)
+	}
+	<-slow.asked
+	<-fast.asked
+	if len(fast.con.ProcessChecksums[procmon.HashSHA256]) != 64 {
+		t.Error("Checksum of the process not sent:", fast.con.ProcessChecksums)
+	}
+	select {
+	case <-slow.cancelled:
+	case <-time.After(5 * time.Second):
+		t.Error("Request to the other servers not cancelled")
+	}
+
+	// only the primary is asked if it's connected, even if it's slower
+	slow.delay = 100 * time.Millisecond
+	c.primary = slowSrv.address
+	if r, ok := c.Ask(con); !ok || r.Name != "slow" {
+		t.Error("Reply of the primary not applied:", r)
+	}
+	<-slow.asked
+	select {
+	case <-fast.asked:
+		t.Error("Server asked besides the primary")
+	default:
+	}
+
+	// the others are asked if the primary is not connected
+	slowSrv.disconnect()
+	if r, ok := c.Ask(con); !ok || r.Name != "fast" {
+		t.Error("Reply of the other servers not applied:", r)
+	}
+}
@@ -4,31 +4,21 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"strings"
 
 	"github.com/evilsocket/opensnitch/daemon/log"
 	"github.com/evilsocket/opensnitch/daemon/procmon"
 	"github.com/evilsocket/opensnitch/daemon/rule"
+	"github.com/evilsocket/opensnitch/daemon/sink"
 )
 
-func (c *Client) getSocketPath(socketPath string) string {
-	c.Lock()
-	defer c.Unlock()
-
-	if strings.HasPrefix(socketPath, "unix://") == true {
-		c.isUnixSocket = true
-		return socketPath[7:]
+// serverAddresses returns the addresses of the UI services configured.
+// The address passed from the command line replaces Server.Address.
+func (c *Client) serverAddresses(conf *serverConfig) []string {
+	addresses := []string{conf.Address}
+	if c.socketPath != "" {
+		addresses[0] = c.socketPath
 	}
-
-	c.isUnixSocket = false
-	return socketPath
-}
-
-func (c *Client) setSocketPath(socketPath string) {
-	c.Lock()
-	defer c.Unlock()
-
-	c.socketPath = socketPath
+	return append(addresses, conf.Addresses...)
 }
 
 func (c *Client) isProcMonitorEqual(newMonitorMethod string) bool {
@@ -38,15 +28,32 @@ func (c *Client) isProcMonitorEqual(newMonitorMethod string) bool {
 	return newMonitorMethod == config.ProcMonitorMethod
 }
 
-func (c *Client) parseConf(rawConfig string) (conf Config, err error) {
-	err = json.Unmarshal([]byte(rawConfig), &conf)
+// isFirewallEqual checks if the firewall configured is the one in use. Without
+// Firewall, the one in use is kept.
+func (c *Client) isFirewallEqual(newFirewall string) bool {
+	if newFirewall == "" {
+		return true
+	}
+	config.RLock()
+	current := config.Firewall
+	config.RUnlock()
+
+	if current == "" {
+		current = c.fw.Name()
+	}
+	return newFirewall == current
+}
+
+func (c *Client) parseConf(rawConfig string) (conf *Config, err error) {
+	conf = &Config{}
+	err = json.Unmarshal([]byte(rawConfig), conf)
 	return conf, err
 }
 
 func (c *Client) loadDiskConfiguration(reload bool) {
 	raw, err := ioutil.ReadFile(configFile)
 	if err != nil {
-		fmt.Errorf("Error loading disk configuration %s: %s", configFile, err)
+		log.Error("Error loading disk configuration %s: %s", configFile, err)
 	}
 
 	if ok := c.loadConfiguration(raw); ok {
@@ -63,14 +70,34 @@ func (c *Client) loadDiskConfiguration(reload bool) {
 	go c.monitorConfigWorker()
 }
 
-func (c *Client) loadConfiguration(rawConfig []byte) bool {
-	config.Lock()
-	defer config.Unlock()
+// set replaces the values loaded with the ones of newConfig, so the options
+// removed from the configuration don't keep their previous values. It must be
+// called with the lock held.
+func (c *Config) set(newConfig *Config) {
+	c.Server = newConfig.Server
+	c.DefaultAction = newConfig.DefaultAction
+	c.DefaultDuration = newConfig.DefaultDuration
+	c.InterceptUnknown = newConfig.InterceptUnknown
+	c.ProcMonitorMethod = newConfig.ProcMonitorMethod
+	c.LogLevel = newConfig.LogLevel
+	c.Firewall = newConfig.Firewall
+	c.EventSinks = newConfig.EventSinks
+	c.Stats = newConfig.Stats
+}
 
-	if err := json.Unmarshal(rawConfig, &config); err != nil {
+func (c *Client) loadConfiguration(rawConfig []byte) bool {
+	// parsed apart, so an invalid configuration doesn't replace the current one
+	newConfig := &Config{}
+	if err := json.Unmarshal(rawConfig, newConfig); err != nil {
 		log.Error("Error parsing configuration %s: %s", configFile, err)
 		return false
 	}
+
+	config.Lock()
+	defer config.Unlock()
+
+	// the options removed from the configuration must be reset
+	config.set(newConfig)
 	// firstly load config level, to detect further errors if any
 	if config.LogLevel != nil {
 		log.SetLogLevel(int(*config.LogLevel))
@@ -80,25 +107,28 @@ func (c *Client) loadConfiguration(rawConfig []byte) bool {
 		log.OpenFile(config.Server.LogFile)
 	}
 
-	if config.Server.Address != "" {
-		tempSocketPath := c.getSocketPath(config.Server.Address)
-		if tempSocketPath != c.socketPath {
-			// disconnect, and let the connection poller reconnect to the new address
-			c.disconnect()
-		}
-		c.setSocketPath(tempSocketPath)
-	}
+	c.setTLS(config.Server.TLS, config.Server.AllowPlaintext)
+	// connect to the new addresses, and disconnect from the ones removed
+	c.setServers(c.serverAddresses(&config.Server), config.Server.Primary)
+	action, duration := rule.Allow, rule.Once
 	if config.DefaultAction != "" {
-		clientDisconnectedRule.Action = rule.Action(config.DefaultAction)
-		clientErrorRule.Action = rule.Action(config.DefaultAction)
+		action = rule.Action(config.DefaultAction)
 	}
 	if config.DefaultDuration != "" {
-		clientDisconnectedRule.Duration = rule.Duration(config.DefaultDuration)
-		clientErrorRule.Duration = rule.Duration(config.DefaultDuration)
+		duration = rule.Duration(config.DefaultDuration)
 	}
+	clientDisconnectedRule.Action, clientErrorRule.Action = action, action
+	clientDisconnectedRule.Duration, clientErrorRule.Duration = duration, duration
 	if config.ProcMonitorMethod != "" {
 		procmon.SetMonitorMethod(config.ProcMonitorMethod)
 	}
+	if config.Firewall != "" {
+		c.fw.SetType(config.Firewall)
+	}
+	sink.Configure(config.EventSinks)
+	if err := c.stats.SetConfig(config.Stats); err != nil {
+		log.Warning("%s", err)
+	}
 
 	return true
 }
@@ -109,7 +139,7 @@ func (c *Client) saveConfiguration(rawConfig string) (err error) {
 	}
 
 	if err = ioutil.WriteFile(configFile, []byte(rawConfig), 0644); err != nil {
-		log.Error("writing configuration to disk: ", err)
+		log.Error("writing configuration to disk: %s", err)
 		return err
 	}
 	return nil
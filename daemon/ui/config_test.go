@@ -0,0 +1,84 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/evilsocket/opensnitch/daemon/firewall"
+	"github.com/evilsocket/opensnitch/daemon/rule"
+	"github.com/evilsocket/opensnitch/daemon/statistics"
+
+	"golang.org/x/net/context"
+)
+
+func TestLoadConfigurationReset(t *testing.T) {
+	c := &Client{userID: -1, stats: statistics.New(nil)}
+	c.clientCtx, c.clientCancel = context.WithCancel(context.Background())
+	defer c.Close()
+
+	if !c.loadConfiguration([]byte(`{
+		"Server": {
+			"Address": "unix:///tmp/osui.sock",
+			"Addresses": ["unix:///tmp/osui-2.sock"],
+			"Primary": "unix:///tmp/osui-2.sock"
+		},
+		"DefaultAction": "deny",
+		"DefaultDuration": "always",
+		"InterceptUnknown": true
+	}`)) {
+		t.Fatal("Configuration not loaded")
+	}
+	if len(c.servers) != 2 || c.primary != "unix:///tmp/osui-2.sock" {
+		t.Error("Servers not configured:", len(c.servers), c.primary)
+	}
+	if c.DefaultAction() != rule.Deny || c.DefaultDuration() != rule.Always || !c.InterceptUnknown() {
+		t.Error("Defaults not configured:", c.DefaultAction(), c.DefaultDuration())
+	}
+
+	// the options removed are reset
+	if !c.loadConfiguration([]byte(`{"Server": {"Address": "unix:///tmp/osui.sock"}}`)) {
+		t.Fatal("Configuration not reloaded")
+	}
+	if len(c.servers) != 1 || c.primary != "" {
+		t.Error("Servers removed still configured:", len(c.servers), c.primary)
+	}
+	if c.DefaultAction() != rule.Allow || c.DefaultDuration() != rule.Once || c.InterceptUnknown() {
+		t.Error("Defaults removed still configured:", c.DefaultAction(), c.DefaultDuration())
+	}
+
+	// an invalid configuration doesn't replace the current one
+	if !c.loadConfiguration([]byte(`{"Server": {"Address": "unix:///tmp/osui.sock"}, "DefaultAction": "deny"}`)) {
+		t.Fatal("Configuration not reloaded")
+	}
+	if c.loadConfiguration([]byte(`{"Server": {"Address": "unix:///tmp/osui-3.sock"}, "DefaultAc`)) {
+		t.Error("Invalid configuration loaded")
+	}
+	config.RLock()
+	address := config.Server.Address
+	config.RUnlock()
+	if address != "unix:///tmp/osui.sock" || c.DefaultAction() != rule.Deny {
+		t.Error("Configuration replaced by an invalid one:", address, c.DefaultAction())
+	}
+}
+
+func TestIsFirewallEqual(t *testing.T) {
+	c := &Client{userID: -1, stats: statistics.New(nil), fw: firewall.NewWithBackend(firewall.NewRecorder())}
+	c.clientCtx, c.clientCancel = context.WithCancel(context.Background())
+	defer c.Close()
+
+	if !c.loadConfiguration([]byte(`{"Server": {"Address": "unix:///tmp/osui.sock"}}`)) {
+		t.Fatal("Configuration not loaded")
+	}
+	if !c.isFirewallEqual("") || !c.isFirewallEqual(c.fw.Name()) {
+		t.Error("Firewall in use reported as changed")
+	}
+	if c.isFirewallEqual(firewall.NFTABLES) {
+		t.Error("Firewall change not detected")
+	}
+
+	if !c.loadConfiguration([]byte(`{"Server": {"Address": "unix:///tmp/osui.sock"}, "Firewall": "nftables"}`)) {
+		t.Fatal("Configuration not reloaded")
+	}
+	if !c.isFirewallEqual("") || !c.isFirewallEqual(firewall.NFTABLES) || c.isFirewallEqual(firewall.IPTABLES) {
+		t.Error("Firewall configured not compared")
+	}
+}
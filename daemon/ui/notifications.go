@@ -10,7 +10,6 @@ import (
 	"time"
 
 	"github.com/evilsocket/opensnitch/daemon/core"
-	"github.com/evilsocket/opensnitch/daemon/firewall"
 	"github.com/evilsocket/opensnitch/daemon/log"
 	"github.com/evilsocket/opensnitch/daemon/procmon"
 	"github.com/evilsocket/opensnitch/daemon/rule"
@@ -18,7 +17,11 @@ import (
 	"golang.org/x/net/context"
 )
 
-var stopMonitoringProcess = make(chan int)
+// replyStream is where the replies to the notifications are sent: the
+// notifications stream of a UI service, or the reply of a Daemon service call.
+type replyStream interface {
+	Send(*protocol.NotificationReply) error
+}
 
 // NewReply constructs a new protocol notification reply
 func NewReply(rID uint64, replyCode protocol.NotificationReplyCode, data string) *protocol.NotificationReply {
@@ -45,20 +48,20 @@ func (c *Client) getClientConfig() *protocol.ClientConfig {
 		Id:                uint64(ts.UnixNano()),
 		Name:              nodeName,
 		Version:           nodeVersion,
-		IsFirewallRunning: firewall.IsRunning(),
+		IsFirewallRunning: c.fw.IsRunning(),
 		Config:            strings.Replace(string(raw), "\n", "", -1),
 		LogLevel:          uint32(log.MinLevel),
 		Rules:             ruleList,
 	}
 }
 
-func (c *Client) monitorProcessDetails(pid int, stream protocol.UI_NotificationsClient, notification *protocol.Notification) {
+func (c *Client) monitorProcessDetails(srv *serverConn, pid int, stream replyStream, notification *protocol.Notification) {
 	p := procmon.NewProcess(pid, "")
 	ticker := time.NewTicker(2 * time.Second)
 
 	for {
 		select {
-		case _pid := <-stopMonitoringProcess:
+		case _pid := <-srv.stopMonitoringProcess:
 			if _pid != pid {
 				continue
 			}
@@ -81,7 +84,7 @@ Exit:
 	ticker.Stop()
 }
 
-func (c *Client) handleActionChangeConfig(stream protocol.UI_NotificationsClient, notification *protocol.Notification) {
+func (c *Client) handleActionChangeConfig(stream replyStream, notification *protocol.Notification) {
 	log.Info("[notification] Reloading configuration")
 	// Parse received configuration first, to get the new proc monitor method.
 	newConf, err := c.parseConf(notification.Data)
@@ -97,72 +100,138 @@ func (c *Client) handleActionChangeConfig(stream protocol.UI_NotificationsClient
 	if procMonitorEqual == false {
 		procmon.End()
 	}
+	// the same applies to the firewall, if it was running.
+	fwEqual := c.isFirewallEqual(newConf.Firewall)
+	fwRunning := c.fw.IsRunning()
+	if fwEqual == false && fwRunning {
+		c.fw.Stop()
+	}
 
 	// this save operation triggers a re-loadConfiguration()
 	err = c.saveConfiguration(notification.Data)
 	if err != nil {
-		log.Warning("[notification] CHANGE_CONFIG not applied", err)
+		log.Warning("[notification] CHANGE_CONFIG not applied: %s", err)
 	} else if err == nil && procMonitorEqual == false {
 		procmon.Init()
 	}
+	if fwEqual == false && fwRunning {
+		c.fw.Init(nil)
+	}
 
 	c.sendNotificationReply(stream, notification.Id, "", err)
 }
 
-func (c *Client) handleActionEnableRule(stream protocol.UI_NotificationsClient, notification *protocol.Notification) {
+func (c *Client) handleActionEnableRule(stream replyStream, notification *protocol.Notification) {
 	var err error
 	for _, rul := range notification.Rules {
-		log.Info("[notification] enable rule: ", rul.Name)
-		// protocol.Rule(protobuf) != rule.Rule(json)
-		r, _ := rule.Deserialize(rul)
-		r.Enabled = true
+		log.Info("[notification] enable rule: %s", rul.Name)
+		r := c.toggleRule(rul, true)
+		if r == nil {
+			err = fmt.Errorf("Invalid rule %s", rul.Name)
+			continue
+		}
 		// save to disk only if the duration is rule.Always
 		err = c.rules.Replace(r, r.Duration == rule.Always)
 	}
 	c.sendNotificationReply(stream, notification.Id, "", err)
 }
 
-func (c *Client) handleActionDisableRule(stream protocol.UI_NotificationsClient, notification *protocol.Notification) {
+func (c *Client) handleActionDisableRule(stream replyStream, notification *protocol.Notification) {
 	var err error
 	for _, rul := range notification.Rules {
-		log.Info("[notification] disable rule: ", rul)
-		r, _ := rule.Deserialize(rul)
-		r.Enabled = false
+		log.Info("[notification] disable rule: %s", rul.Name)
+		r := c.toggleRule(rul, false)
+		if r == nil {
+			err = fmt.Errorf("Invalid rule %s", rul.Name)
+			continue
+		}
 		err = c.rules.Replace(r, r.Duration == rule.Always)
 	}
 	c.sendNotificationReply(stream, notification.Id, "", err)
 }
 
-func (c *Client) handleActionChangeRule(stream protocol.UI_NotificationsClient, notification *protocol.Notification) {
+// toggleRule returns a copy of the rule loaded with the given state, so the
+// fields not sent by the GUI are kept. If the rule is not loaded, the one
+// received is used.
+func (c *Client) toggleRule(rul *protocol.Rule, enabled bool) *rule.Rule {
+	var r *rule.Rule
+	if loaded := c.rules.Get(rul.Name); loaded != nil {
+		dup := *loaded
+		r = &dup
+	} else if r, _ = rule.Deserialize(rul); r == nil {
+		return nil
+	}
+	r.Enabled = enabled
+	return r
+}
+
+func (c *Client) handleActionChangeRule(stream replyStream, notification *protocol.Notification) {
 	var rErr error
 	for _, rul := range notification.Rules {
-		r, err := rule.Deserialize(rul)
+		r, err := rule.Deserialize(mergeRule(rul, c.rules.Get(rul.Name)))
 		if r == nil {
 			rErr = fmt.Errorf("Invalid rule, %s", err)
 			continue
 		}
-		log.Info("[notification] change rule: ", r, notification.Id)
+		log.Info("[notification] change rule: %s %d", r, notification.Id)
 		if err := c.rules.Replace(r, r.Duration == rule.Always); err != nil {
-			log.Warning("[notification] Error changing rule: ", err, r)
+			log.Warning("[notification] Error changing rule: %s %s", err, r)
 			rErr = err
 		}
 	}
 	c.sendNotificationReply(stream, notification.Id, "", rErr)
 }
 
-func (c *Client) handleActionDeleteRule(stream protocol.UI_NotificationsClient, notification *protocol.Notification) {
+// mergeRule completes a rule received with the fields of the rule loaded
+// which the GUI doesn't know about, so they're not lost when the rule is
+// changed: the priority, schedule, limit and redirect, and the children of
+// a boolean operator not sent back.
+func mergeRule(rul *protocol.Rule, loaded *rule.Rule) *protocol.Rule {
+	if loaded == nil {
+		return rul
+	}
+	prev := loaded.Serialize()
+	merged := *rul
+	if merged.Priority == 0 {
+		merged.Priority = prev.Priority
+	}
+	if merged.Schedule == "" {
+		merged.Schedule = prev.Schedule
+	}
+	// limits only apply to allow rules, and redirects to requeue and mark ones
+	if merged.Limit == nil && rule.Action(merged.Action) == rule.Allow {
+		merged.Limit = prev.Limit
+	}
+	if merged.Redirect == nil && (rule.Action(merged.Action) == rule.Requeue || rule.Action(merged.Action) == rule.Mark) {
+		merged.Redirect = prev.Redirect
+	}
+	if op := merged.Operator; op == nil || (isBooleanOperator(op) && op.Data == "" && len(op.List) == 0) {
+		merged.Operator = prev.Operator
+	}
+	return &merged
+}
+
+func isBooleanOperator(op *protocol.Operator) bool {
+	switch rule.Type(op.Type) {
+	case rule.List, rule.And, rule.Or, rule.Not:
+		return true
+	}
+	return false
+}
+
+func (c *Client) handleActionDeleteRule(stream replyStream, notification *protocol.Notification) {
 	var err error
 	for _, rul := range notification.Rules {
-		log.Info("[notification] delete rule: ", rul.Name, notification.Id)
+		log.Info("[notification] delete rule: %s %d", rul.Name, notification.Id)
 		err = c.rules.Delete(rul.Name)
 		if err != nil {
-			log.Error("[notification] Error deleting rule: ", err, rul)
+			log.Error("[notification] Error deleting rule: %s %s", err, rul.Name)
 		}
 	}
 	c.sendNotificationReply(stream, notification.Id, "", err)
 }
 
-func (c *Client) handleActionMonitorProcess(stream protocol.UI_NotificationsClient, notification *protocol.Notification) {
+func (c *Client) handleActionMonitorProcess(srv *serverConn, stream replyStream, notification *protocol.Notification) {
 	pid, err := strconv.Atoi(notification.Data)
 	if err != nil {
 		log.Error("parsing PID to monitor")
@@ -172,39 +241,55 @@ func (c *Client) handleActionMonitorProcess(stream protocol.UI_NotificationsClie
 		c.sendNotificationReply(stream, notification.Id, "", fmt.Errorf("The process is no longer running"))
 		return
 	}
-	go c.monitorProcessDetails(pid, stream, notification)
+	// users can only see the details of their own processes
+	if c.IsUserClient() && checkOwner(fmt.Sprint("/proc/", pid), c.userID) != nil {
+		c.sendNotificationReply(stream, notification.Id, "", fmt.Errorf("The process %d is not owned by the user %d", pid, c.userID))
+		return
+	}
+	go c.monitorProcessDetails(srv, pid, stream, notification)
 }
 
-func (c *Client) handleActionStopMonitorProcess(stream protocol.UI_NotificationsClient, notification *protocol.Notification) {
+func (c *Client) handleActionStopMonitorProcess(srv *serverConn, stream replyStream, notification *protocol.Notification) {
 	pid, err := strconv.Atoi(notification.Data)
 	if err != nil {
 		log.Error("parsing PID to stop monitor")
-		c.sendNotificationReply(stream, notification.Id, "", fmt.Errorf("Error stopping monitor: ", notification.Data))
+		c.sendNotificationReply(stream, notification.Id, "", fmt.Errorf("Error stopping monitor: %s", notification.Data))
 		return
 	}
-	stopMonitoringProcess <- pid
+	srv.stopMonitoringProcess <- pid
 	c.sendNotificationReply(stream, notification.Id, "", nil)
 }
 
-func (c *Client) handleNotification(stream protocol.UI_NotificationsClient, notification *protocol.Notification) {
+// handleNotification applies a notification received from a server, and
+// replies to it on the stream of that server.
+func (c *Client) handleNotification(srv *serverConn, stream replyStream, notification *protocol.Notification) {
+	// the GUIs of the users can only change their own rules
+	if c.IsUserClient() && (notification.Type == protocol.Action_CHANGE_CONFIG ||
+		notification.Type == protocol.Action_LOAD_FIREWALL ||
+		notification.Type == protocol.Action_UNLOAD_FIREWALL) {
+		log.Warning("[notification] action %s not allowed to the user %d", notification.Type, c.userID)
+		c.sendNotificationReply(stream, notification.Id, "", fmt.Errorf("Action not allowed: %s", notification.Type))
+		return
+	}
+
 	switch {
 	case notification.Type == protocol.Action_MONITOR_PROCESS:
-		c.handleActionMonitorProcess(stream, notification)
+		c.handleActionMonitorProcess(srv, stream, notification)
 
 	case notification.Type == protocol.Action_STOP_MONITOR_PROCESS:
-		c.handleActionStopMonitorProcess(stream, notification)
+		c.handleActionStopMonitorProcess(srv, stream, notification)
 
 	case notification.Type == protocol.Action_CHANGE_CONFIG:
 		c.handleActionChangeConfig(stream, notification)
 
 	case notification.Type == protocol.Action_LOAD_FIREWALL:
 		log.Info("[notification] starting firewall")
-		firewall.Init(nil)
+		c.fw.Init(nil)
 		c.sendNotificationReply(stream, notification.Id, "", nil)
 
 	case notification.Type == protocol.Action_UNLOAD_FIREWALL:
 		log.Info("[notification] stopping firewall")
-		firewall.Stop(nil)
+		c.fw.Stop()
 		c.sendNotificationReply(stream, notification.Id, "", nil)
 
 	// ENABLE_RULE just replaces the rule on disk
@@ -223,14 +308,14 @@ func (c *Client) handleNotification(stream protocol.UI_NotificationsClient, noti
 	}
 }
 
-func (c *Client) sendNotificationReply(stream protocol.UI_NotificationsClient, nID uint64, data string, err error) error {
+func (c *Client) sendNotificationReply(stream replyStream, nID uint64, data string, err error) error {
 	reply := NewReply(nID, protocol.NotificationReplyCode_OK, data)
 	if err != nil {
 		reply.Code = protocol.NotificationReplyCode_ERROR
 		reply.Data = fmt.Sprint(err)
 	}
 	if err := stream.Send(reply); err != nil {
-		log.Error("Error replying to notification:", err, reply.Id)
+		log.Error("Error replying to notification: %s %d", err, reply.Id)
 		return err
 	}
 
@@ -240,41 +325,45 @@ func (c *Client) sendNotificationReply(stream protocol.UI_NotificationsClient, n
 // Subscribe opens a connection with the server (UI), to start
 // receiving notifications.
 // It firstly sends the daemon status and configuration.
-func (c *Client) Subscribe() {
-	ctx, cancel := context.WithCancel(context.Background())
+func (s *serverConn) Subscribe() {
+	ctx, cancel := context.WithCancel(s.ctx)
 	defer cancel()
 
-	if _, err := c.client.Subscribe(ctx, c.getClientConfig()); err != nil {
-		log.Error("Subscribing to GUI", err)
+	client := s.getClient()
+	if client == nil {
+		return
+	}
+	if _, err := client.Subscribe(ctx, s.owner.getClientConfig()); err != nil {
+		log.Error("Subscribing to GUI %s: %s", s.address, err)
 		return
 	}
-	c.listenForNotifications()
+	s.listenForNotifications(client)
 }
 
 // Notifications is the channel where the daemon receives messages from the server.
 // It consists of 2 grpc streams (send/receive) that are never closed,
 // this way we can share messages in realtime.
 // If the GUI is closed, we'll receive an error reading from the channel.
-func (c *Client) listenForNotifications() {
-	ctx, cancel := context.WithCancel(context.Background())
+func (s *serverConn) listenForNotifications(client protocol.UIClient) {
+	ctx, cancel := context.WithCancel(s.ctx)
 	defer cancel()
 
 	// open the stream channel
 	streamReply := &protocol.NotificationReply{Id: 0, Code: protocol.NotificationReplyCode_OK}
-	notisStream, err := c.client.Notifications(ctx)
+	notisStream, err := client.Notifications(ctx)
 	if err != nil {
-		log.Error("establishing notifications channel", err)
+		log.Error("establishing notifications channel: %s", err)
 		return
 	}
 	// send the first notification
 	if err := notisStream.Send(streamReply); err != nil {
-		log.Error("sending notification HELLO", err)
+		log.Error("sending notification HELLO: %s", err)
 		return
 	}
-	log.Info("Start receiving notifications")
+	log.Info("Start receiving notifications from %s", s.address)
 	for {
 		select {
-		case <-c.clientCtx.Done():
+		case <-s.ctx.Done():
 			goto Exit
 		default:
 			noti, err := notisStream.Recv()
@@ -283,13 +372,13 @@ func (c *Client) listenForNotifications() {
 				goto Exit
 			}
 			if err != nil {
-				log.Error("getting notifications: ", err, noti)
+				log.Error("getting notifications: %s %v", err, noti)
 				goto Exit
 			}
-			c.handleNotification(notisStream, noti)
+			s.owner.handleNotification(s, notisStream, noti)
 		}
 	}
 Exit:
 	notisStream.CloseSend()
-	log.Info("Stop receiving notifications")
+	log.Info("Stop receiving notifications from %s", s.address)
 }
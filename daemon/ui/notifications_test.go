@@ -0,0 +1,63 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/evilsocket/opensnitch/daemon/rule"
+	"github.com/evilsocket/opensnitch/daemon/ui/protocol"
+)
+
+func TestMergeRule(t *testing.T) {
+	op, err := rule.NewOperator(rule.And, false, rule.OpList, "", []rule.Operator{
+		{Type: rule.Simple, Operand: rule.OpProcessPath, Data: "/usr/bin/curl"},
+		{Type: rule.Simple, Operand: rule.OpDstPort, Data: "443"},
+	})
+	if err != nil {
+		t.Fatal("Error creating operator:", err)
+	}
+	loaded := rule.Create("allow-curl", true, false, rule.Allow, rule.Always, op)
+	loaded.Priority = -5
+	loaded.Schedule = "weekdays 09:00-18:00"
+	loaded.Limit = &rule.Limit{Connections: 10, Interval: "1m"}
+
+	// as sent by the GUI, which doesn't know about the new fields
+	received := &protocol.Rule{
+		Name:     "allow-curl",
+		Enabled:  false,
+		Action:   string(rule.Allow),
+		Duration: string(rule.Always),
+		Operator: &protocol.Operator{Type: string(rule.And), Operand: string(rule.OpList)},
+	}
+	merged := mergeRule(received, loaded)
+	if merged.Enabled {
+		t.Error("Fields received not kept:", merged)
+	}
+	if merged.Priority != -5 || merged.Schedule != loaded.Schedule || merged.Limit == nil || merged.Limit.Connections != 10 {
+		t.Error("Fields of the rule loaded not merged:", merged)
+	}
+	if merged.Operator == nil || len(merged.Operator.List) != 2 {
+		t.Error("Operators of the rule loaded not merged:", merged.Operator)
+	}
+	if received.Priority != 0 || received.Limit != nil {
+		t.Error("Rule received modified:", received)
+	}
+	if _, err := rule.Deserialize(merged); err != nil {
+		t.Error("Error deserializing merged rule:", err)
+	}
+
+	// the fields sent win, and the limit is not kept by other actions
+	received.Action = string(rule.Deny)
+	received.Priority = 3
+	received.Operator = &protocol.Operator{Type: string(rule.Simple), Operand: string(rule.OpProcessPath), Data: "/usr/bin/wget"}
+	merged = mergeRule(received, loaded)
+	if merged.Priority != 3 || merged.Operator.Data != "/usr/bin/wget" || merged.Limit != nil {
+		t.Error("Fields received overwritten:", merged)
+	}
+	if _, err := rule.Deserialize(merged); err != nil {
+		t.Error("Error deserializing merged rule:", err)
+	}
+
+	if mergeRule(received, nil) != received {
+		t.Error("Rule not loaded merged")
+	}
+}
@@ -164,6 +164,12 @@ type Statistics struct {
 	ByUid         map[string]uint64 `protobuf:"bytes,15,rep,name=by_uid,json=byUid,proto3" json:"by_uid,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
 	ByExecutable  map[string]uint64 `protobuf:"bytes,16,rep,name=by_executable,json=byExecutable,proto3" json:"by_executable,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
 	Events        []*Event          `protobuf:"bytes,17,rep,name=events,proto3" json:"events,omitempty"`
+	// connections handed over to another netfilter queue by the requeue rules
+	Requeued uint64 `protobuf:"varint,18,opt,name=requeued,proto3" json:"requeued,omitempty"`
+	// data transferred by the connections closed, of the processes and rules
+	// with the most traffic. Only with the daemon option -traffic-accounting
+	TrafficByExecutable map[string]*Traffic `protobuf:"bytes,19,rep,name=traffic_by_executable,json=trafficByExecutable,proto3" json:"traffic_by_executable,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	TrafficByRule       map[string]*Traffic `protobuf:"bytes,20,rep,name=traffic_by_rule,json=trafficByRule,proto3" json:"traffic_by_rule,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
 }
 
 func (m *Statistics) Reset()         { *m = Statistics{} }
@@ -292,6 +298,27 @@ func (m *Statistics) GetEvents() []*Event {
 	return nil
 }
 
+func (m *Statistics) GetRequeued() uint64 {
+	if m != nil {
+		return m.Requeued
+	}
+	return 0
+}
+
+func (m *Statistics) GetTrafficByExecutable() map[string]*Traffic {
+	if m != nil {
+		return m.TrafficByExecutable
+	}
+	return nil
+}
+
+func (m *Statistics) GetTrafficByRule() map[string]*Traffic {
+	if m != nil {
+		return m.TrafficByRule
+	}
+	return nil
+}
+
 type PingRequest struct {
 	Id    uint64      `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
 	Stats *Statistics `protobuf:"bytes,2,opt,name=stats,proto3" json:"stats,omitempty"`
@@ -349,6 +376,10 @@ type Connection struct {
 	ProcessCwd  string            `protobuf:"bytes,10,opt,name=process_cwd,json=processCwd,proto3" json:"process_cwd,omitempty"`
 	ProcessArgs []string          `protobuf:"bytes,11,rep,name=process_args,json=processArgs,proto3" json:"process_args,omitempty"`
 	ProcessEnv  map[string]string `protobuf:"bytes,12,rep,name=process_env,json=processEnv,proto3" json:"process_env,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// checksums of the process executable, by algorithm (md5, sha256)
+	ProcessChecksums map[string]string `protobuf:"bytes,13,rep,name=process_checksums,json=processChecksums,proto3" json:"process_checksums,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// ancestors of the process, from the parent to the init process
+	ProcessTree []*ProcessParent `protobuf:"bytes,14,rep,name=process_tree,json=processTree,proto3" json:"process_tree,omitempty"`
 }
 
 func (m *Connection) Reset()         { *m = Connection{} }
@@ -442,11 +473,27 @@ func (m *Connection) GetProcessEnv() map[string]string {
 	return nil
 }
 
+func (m *Connection) GetProcessChecksums() map[string]string {
+	if m != nil {
+		return m.ProcessChecksums
+	}
+	return nil
+}
+
+func (m *Connection) GetProcessTree() []*ProcessParent {
+	if m != nil {
+		return m.ProcessTree
+	}
+	return nil
+}
+
 type Operator struct {
 	Type      string `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
 	Operand   string `protobuf:"bytes,2,opt,name=operand,proto3" json:"operand,omitempty"`
 	Data      string `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
 	Sensitive bool   `protobuf:"varint,4,opt,name=sensitive,proto3" json:"sensitive,omitempty"`
+	// children of the and, or, not and list operators
+	List []*Operator `protobuf:"bytes,5,rep,name=list,proto3" json:"list,omitempty"`
 }
 
 func (m *Operator) Reset()         { *m = Operator{} }
@@ -484,6 +531,13 @@ func (m *Operator) GetSensitive() bool {
 	return false
 }
 
+func (m *Operator) GetList() []*Operator {
+	if m != nil {
+		return m.List
+	}
+	return nil
+}
+
 type Rule struct {
 	Name       string    `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
 	Enabled    bool      `protobuf:"varint,2,opt,name=enabled,proto3" json:"enabled,omitempty"`
@@ -491,6 +545,12 @@ type Rule struct {
 	Action     string    `protobuf:"bytes,4,opt,name=action,proto3" json:"action,omitempty"`
 	Duration   string    `protobuf:"bytes,5,opt,name=duration,proto3" json:"duration,omitempty"`
 	Operator   *Operator `protobuf:"bytes,6,opt,name=operator,proto3" json:"operator,omitempty"`
+	Priority   int32     `protobuf:"varint,7,opt,name=priority,proto3" json:"priority,omitempty"`
+	// when the rule is active: "weekdays 09:00-18:00", "* 9-17 * * 1-5"
+	Schedule string     `protobuf:"bytes,8,opt,name=schedule,proto3" json:"schedule,omitempty"`
+	Limit    *RuleLimit `protobuf:"bytes,9,opt,name=limit,proto3" json:"limit,omitempty"`
+	// parameters of the requeue and mark actions
+	Redirect *RuleRedirect `protobuf:"bytes,10,opt,name=redirect,proto3" json:"redirect,omitempty"`
 }
 
 func (m *Rule) Reset()         { *m = Rule{} }
@@ -542,6 +602,34 @@ func (m *Rule) GetOperator() *Operator {
 	return nil
 }
 
+func (m *Rule) GetPriority() int32 {
+	if m != nil {
+		return m.Priority
+	}
+	return 0
+}
+
+func (m *Rule) GetSchedule() string {
+	if m != nil {
+		return m.Schedule
+	}
+	return ""
+}
+
+func (m *Rule) GetLimit() *RuleLimit {
+	if m != nil {
+		return m.Limit
+	}
+	return nil
+}
+
+func (m *Rule) GetRedirect() *RuleRedirect {
+	if m != nil {
+		return m.Redirect
+	}
+	return nil
+}
+
 // client configuration sent on Subscribe()
 type ClientConfig struct {
 	Id                uint64 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
@@ -705,6 +793,209 @@ func (m *NotificationReply) GetData() string {
 	return ""
 }
 
+// an ancestor of the process of a connection
+type ProcessParent struct {
+	Pid  uint32 `protobuf:"varint,1,opt,name=pid,proto3" json:"pid,omitempty"`
+	Path string `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`
+}
+
+func (m *ProcessParent) Reset()         { *m = ProcessParent{} }
+func (m *ProcessParent) String() string { return proto.CompactTextString(m) }
+func (*ProcessParent) ProtoMessage()    {}
+func (*ProcessParent) Descriptor() ([]byte, []int) {
+	return fileDescriptor_63867a62624c1283, []int{10}
+}
+
+func (m *ProcessParent) GetPid() uint32 {
+	if m != nil {
+		return m.Pid
+	}
+	return 0
+}
+
+func (m *ProcessParent) GetPath() string {
+	if m != nil {
+		return m.Path
+	}
+	return ""
+}
+
+// max usage of an allow rule by a process, then deny or ask
+type RuleLimit struct {
+	// new connections per interval ("1m")
+	Connections uint32 `protobuf:"varint,1,opt,name=connections,proto3" json:"connections,omitempty"`
+	Interval    string `protobuf:"bytes,2,opt,name=interval,proto3" json:"interval,omitempty"`
+	BytesPerDay uint64 `protobuf:"varint,3,opt,name=bytes_per_day,json=bytesPerDay,proto3" json:"bytes_per_day,omitempty"`
+	Exceeded    string `protobuf:"bytes,4,opt,name=exceeded,proto3" json:"exceeded,omitempty"`
+}
+
+func (m *RuleLimit) Reset()         { *m = RuleLimit{} }
+func (m *RuleLimit) String() string { return proto.CompactTextString(m) }
+func (*RuleLimit) ProtoMessage()    {}
+func (*RuleLimit) Descriptor() ([]byte, []int) {
+	return fileDescriptor_63867a62624c1283, []int{11}
+}
+
+func (m *RuleLimit) GetConnections() uint32 {
+	if m != nil {
+		return m.Connections
+	}
+	return 0
+}
+
+func (m *RuleLimit) GetInterval() string {
+	if m != nil {
+		return m.Interval
+	}
+	return ""
+}
+
+func (m *RuleLimit) GetBytesPerDay() uint64 {
+	if m != nil {
+		return m.BytesPerDay
+	}
+	return 0
+}
+
+func (m *RuleLimit) GetExceeded() string {
+	if m != nil {
+		return m.Exceeded
+	}
+	return ""
+}
+
+// netfilter queue of the requeue rules, or firewall mark of the mark rules
+type RuleRedirect struct {
+	Queue uint32 `protobuf:"varint,1,opt,name=queue,proto3" json:"queue,omitempty"`
+	Mark  uint32 `protobuf:"varint,2,opt,name=mark,proto3" json:"mark,omitempty"`
+}
+
+func (m *RuleRedirect) Reset()         { *m = RuleRedirect{} }
+func (m *RuleRedirect) String() string { return proto.CompactTextString(m) }
+func (*RuleRedirect) ProtoMessage()    {}
+func (*RuleRedirect) Descriptor() ([]byte, []int) {
+	return fileDescriptor_63867a62624c1283, []int{12}
+}
+
+func (m *RuleRedirect) GetQueue() uint32 {
+	if m != nil {
+		return m.Queue
+	}
+	return 0
+}
+
+func (m *RuleRedirect) GetMark() uint32 {
+	if m != nil {
+		return m.Mark
+	}
+	return 0
+}
+
+// filters of the connections history, empty fields match any connection
+type HistoryQuery struct {
+	Process string `protobuf:"bytes,1,opt,name=process,proto3" json:"process,omitempty"`
+	// host name or IP of the destination
+	Host string `protobuf:"bytes,2,opt,name=host,proto3" json:"host,omitempty"`
+	Rule string `protobuf:"bytes,3,opt,name=rule,proto3" json:"rule,omitempty"`
+	// time range, in unix nanoseconds
+	From  int64  `protobuf:"varint,4,opt,name=from,proto3" json:"from,omitempty"`
+	To    int64  `protobuf:"varint,5,opt,name=to,proto3" json:"to,omitempty"`
+	Limit uint32 `protobuf:"varint,6,opt,name=limit,proto3" json:"limit,omitempty"`
+}
+
+func (m *HistoryQuery) Reset()         { *m = HistoryQuery{} }
+func (m *HistoryQuery) String() string { return proto.CompactTextString(m) }
+func (*HistoryQuery) ProtoMessage()    {}
+func (*HistoryQuery) Descriptor() ([]byte, []int) {
+	return fileDescriptor_63867a62624c1283, []int{13}
+}
+
+func (m *HistoryQuery) GetProcess() string {
+	if m != nil {
+		return m.Process
+	}
+	return ""
+}
+
+func (m *HistoryQuery) GetHost() string {
+	if m != nil {
+		return m.Host
+	}
+	return ""
+}
+
+func (m *HistoryQuery) GetRule() string {
+	if m != nil {
+		return m.Rule
+	}
+	return ""
+}
+
+func (m *HistoryQuery) GetFrom() int64 {
+	if m != nil {
+		return m.From
+	}
+	return 0
+}
+
+func (m *HistoryQuery) GetTo() int64 {
+	if m != nil {
+		return m.To
+	}
+	return 0
+}
+
+func (m *HistoryQuery) GetLimit() uint32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+// data transferred by the connections closed of a process or a rule
+type Traffic struct {
+	Connections uint64 `protobuf:"varint,1,opt,name=connections,proto3" json:"connections,omitempty"`
+	BytesIn     uint64 `protobuf:"varint,2,opt,name=bytes_in,json=bytesIn,proto3" json:"bytes_in,omitempty"`
+	BytesOut    uint64 `protobuf:"varint,3,opt,name=bytes_out,json=bytesOut,proto3" json:"bytes_out,omitempty"`
+	// seconds the connections lasted
+	Duration uint64 `protobuf:"varint,4,opt,name=duration,proto3" json:"duration,omitempty"`
+}
+
+func (m *Traffic) Reset()         { *m = Traffic{} }
+func (m *Traffic) String() string { return proto.CompactTextString(m) }
+func (*Traffic) ProtoMessage()    {}
+func (*Traffic) Descriptor() ([]byte, []int) {
+	return fileDescriptor_63867a62624c1283, []int{14}
+}
+
+func (m *Traffic) GetConnections() uint64 {
+	if m != nil {
+		return m.Connections
+	}
+	return 0
+}
+
+func (m *Traffic) GetBytesIn() uint64 {
+	if m != nil {
+		return m.BytesIn
+	}
+	return 0
+}
+
+func (m *Traffic) GetBytesOut() uint64 {
+	if m != nil {
+		return m.BytesOut
+	}
+	return 0
+}
+
+func (m *Traffic) GetDuration() uint64 {
+	if m != nil {
+		return m.Duration
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterEnum("protocol.Action", Action_name, Action_value)
 	proto.RegisterEnum("protocol.NotificationReplyCode", NotificationReplyCode_name, NotificationReplyCode_value)
@@ -718,6 +1009,11 @@ func init() {
 	proto.RegisterType((*ClientConfig)(nil), "protocol.ClientConfig")
 	proto.RegisterType((*Notification)(nil), "protocol.Notification")
 	proto.RegisterType((*NotificationReply)(nil), "protocol.NotificationReply")
+	proto.RegisterType((*ProcessParent)(nil), "protocol.ProcessParent")
+	proto.RegisterType((*RuleLimit)(nil), "protocol.RuleLimit")
+	proto.RegisterType((*RuleRedirect)(nil), "protocol.RuleRedirect")
+	proto.RegisterType((*HistoryQuery)(nil), "protocol.HistoryQuery")
+	proto.RegisterType((*Traffic)(nil), "protocol.Traffic")
 }
 
 func init() {
@@ -725,91 +1021,123 @@ func init() {
 }
 
 var fileDescriptor_63867a62624c1283 = []byte{
-	// 1335 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x56, 0x5f, 0x73, 0xd3, 0x46,
-	0x10, 0x8f, 0x1c, 0x5b, 0x96, 0xd6, 0x76, 0xe2, 0x1c, 0x84, 0xaa, 0xa1, 0x85, 0x60, 0x68, 0x9b,
-	0xc9, 0x74, 0x32, 0x6d, 0x60, 0x3a, 0xc0, 0xc0, 0x74, 0x8c, 0x11, 0xe0, 0x62, 0x6c, 0xcf, 0x85,
-	0xd0, 0x47, 0x8d, 0xfe, 0x1c, 0xce, 0x15, 0x73, 0x52, 0x75, 0x67, 0x83, 0x3f, 0x46, 0xbf, 0x4d,
-	0x9f, 0xfa, 0xda, 0x97, 0x3e, 0xf6, 0xa9, 0x9f, 0xa4, 0x8f, 0x9d, 0xbb, 0x93, 0x2c, 0xc5, 0x49,
-	0xe8, 0xe4, 0xc9, 0xda, 0xdf, 0x6f, 0x7f, 0xab, 0xdd, 0xd5, 0xde, 0xad, 0xc1, 0x9a, 0xd1, 0x83,
-	0x24, 0x8d, 0x45, 0x8c, 0x2c, 0xf5, 0x13, 0xc6, 0xd3, 0xce, 0x6f, 0x06, 0xd4, 0xdc, 0x39, 0x61,
-	0x02, 0x21, 0xa8, 0x0a, 0xfa, 0x9e, 0x38, 0xc6, 0xae, 0xb1, 0x67, 0x63, 0xf5, 0x8c, 0xee, 0x01,
-	0x84, 0x31, 0x63, 0x24, 0x14, 0x34, 0x66, 0x4e, 0x65, 0xd7, 0xd8, 0x6b, 0x1c, 0x5e, 0x3d, 0xc8,
-	0xc5, 0x07, 0xbd, 0x25, 0x87, 0x4b, 0x7e, 0xa8, 0x03, 0xd5, 0x74, 0x36, 0x25, 0xce, 0xba, 0xf2,
-	0xdf, 0x28, 0xfc, 0xf1, 0x6c, 0x4a, 0xb0, 0xe2, 0xd0, 0x0e, 0x58, 0x33, 0x46, 0x3f, 0x32, 0x9f,
-	0xc5, 0x4e, 0x75, 0xd7, 0xd8, 0x5b, 0xc7, 0x4b, 0xbb, 0xf3, 0xa7, 0x05, 0x70, 0x24, 0x7c, 0x41,
-	0xb9, 0xa0, 0x21, 0x47, 0x5f, 0xc1, 0x46, 0xe4, 0x93, 0xf7, 0x31, 0xf3, 0xe6, 0x24, 0xe5, 0x32,
-	0x11, 0x9d, 0x62, 0x4b, 0xa3, 0x6f, 0x34, 0x88, 0xae, 0x42, 0x4d, 0x46, 0xe6, 0x2a, 0xcd, 0x2a,
-	0xd6, 0x06, 0xba, 0x06, 0xe6, 0x2c, 0x51, 0x75, 0xad, 0x2b, 0x38, 0xb3, 0xd0, 0x6d, 0x68, 0x45,
-	0x8c, 0x7b, 0x29, 0xe1, 0x49, 0xcc, 0x38, 0xe1, 0x2a, 0x89, 0x2a, 0x6e, 0x46, 0x8c, 0xe3, 0x1c,
-	0x43, 0xbb, 0xd0, 0x28, 0xca, 0xe2, 0x4e, 0x4d, 0xb9, 0x94, 0x21, 0xe4, 0x40, 0x9d, 0x4e, 0x58,
-	0x9c, 0x92, 0xc8, 0x31, 0x15, 0x9b, 0x9b, 0xb2, 0x40, 0x3f, 0x0c, 0x49, 0x22, 0x48, 0xe4, 0xd4,
-	0x15, 0xb5, 0xb4, 0xa5, 0x2a, 0x4a, 0xe3, 0x24, 0x21, 0x91, 0x63, 0x69, 0x55, 0x66, 0xa2, 0xeb,
-	0x60, 0xcb, 0xbc, 0xbd, 0x13, 0x2a, 0xb8, 0x63, 0x6b, 0x99, 0x04, 0x5e, 0x50, 0xc1, 0xd1, 0x4d,
-	0x68, 0x28, 0xf2, 0x3d, 0xe5, 0x32, 0x63, 0x50, 0x34, 0x48, 0xe8, 0x95, 0x42, 0xd0, 0x23, 0xb0,
-	0x82, 0x85, 0xa7, 0xda, 0xed, 0x34, 0x76, 0xd7, 0xf7, 0x1a, 0x87, 0xb7, 0x8a, 0xe6, 0x17, 0x1d,
-	0x3d, 0x78, 0xb2, 0x18, 0x4b, 0xd4, 0x65, 0x22, 0x5d, 0xe0, 0x7a, 0xa0, 0x2d, 0xf4, 0x04, 0x20,
-	0x58, 0x78, 0x7e, 0x14, 0xa5, 0x84, 0x73, 0xa7, 0xa9, 0xf4, 0xb7, 0x2f, 0xd0, 0x77, 0xb5, 0x97,
-	0x8e, 0x60, 0x07, 0xb9, 0x8d, 0x1e, 0x40, 0x3d, 0x58, 0x78, 0x27, 0x31, 0x17, 0x4e, 0x4b, 0x05,
-	0xd8, 0xbd, 0x20, 0xc0, 0x8b, 0x98, 0x0b, 0xad, 0x36, 0x03, 0x65, 0x64, 0xd2, 0x24, 0x4e, 0x85,
-	0xb3, 0xf1, 0x49, 0xe9, 0x38, 0x4e, 0x0b, 0xa9, 0x34, 0xd0, 0x0f, 0x60, 0x06, 0x0b, 0x6f, 0x46,
-	0x23, 0x67, 0x53, 0x29, 0x6f, 0x5e, 0xa0, 0x3c, 0xa6, 0x91, 0x16, 0xd6, 0x02, 0xf9, 0x8c, 0x5e,
-	0x42, 0x2b, 0x58, 0x78, 0xe4, 0x23, 0x09, 0x67, 0xc2, 0x0f, 0xa6, 0xc4, 0x69, 0x2b, 0xf9, 0xd7,
-	0x17, 0xc8, 0xdd, 0xa5, 0xa3, 0x8e, 0xd2, 0x0c, 0x4a, 0x10, 0xfa, 0x06, 0x4c, 0x22, 0x0f, 0x12,
-	0x77, 0xb6, 0x54, 0x94, 0xcd, 0x22, 0x8a, 0x3a, 0x60, 0x38, 0xa3, 0x77, 0x1e, 0x42, 0xb3, 0xfc,
-	0x01, 0x50, 0x1b, 0xd6, 0xdf, 0x91, 0x45, 0x36, 0xd4, 0xf2, 0x51, 0x8e, 0xf2, 0xdc, 0x9f, 0xce,
-	0x48, 0x3e, 0xca, 0xca, 0x78, 0x58, 0xb9, 0x6f, 0xec, 0x3c, 0x82, 0x8d, 0xd3, 0xcd, 0xbf, 0x94,
-	0xfa, 0x01, 0x34, 0x4a, 0x9d, 0xbf, 0xbc, 0x74, 0xd9, 0xf9, 0x4b, 0x49, 0xef, 0x03, 0x14, 0xad,
-	0xbf, 0x94, 0xf2, 0x47, 0xd8, 0x3a, 0xd3, 0xf5, 0xcb, 0x04, 0xe8, 0xf4, 0xa1, 0x31, 0xa6, 0x6c,
-	0x82, 0xc9, 0xaf, 0x33, 0xc2, 0x05, 0xda, 0x80, 0x0a, 0x8d, 0x94, 0xb2, 0x8a, 0x2b, 0x34, 0x42,
-	0xfb, 0x50, 0xe3, 0xc2, 0x17, 0xfc, 0xec, 0xcd, 0x56, 0x7c, 0x77, 0xac, 0x5d, 0x3a, 0xd7, 0xc1,
-	0xd6, 0xa1, 0x92, 0xe9, 0x62, 0x35, 0x50, 0xe7, 0xaf, 0x75, 0x80, 0xe2, 0x32, 0x94, 0x67, 0x3f,
-	0x8f, 0x94, 0xe5, 0xb9, 0xb4, 0xd1, 0x36, 0x98, 0x3c, 0x0d, 0x3d, 0x9a, 0xa8, 0x97, 0xda, 0xb8,
-	0xc6, 0xd3, 0xb0, 0x9f, 0xa0, 0xcf, 0xc1, 0x92, 0xb0, 0x1a, 0x7f, 0x79, 0x53, 0xb5, 0x70, 0x9d,
-	0xa7, 0xa1, 0x9a, 0xee, 0x6d, 0x30, 0x23, 0x2e, 0xa4, 0xa2, 0xaa, 0x15, 0x11, 0x17, 0x5a, 0x21,
-	0x61, 0x75, 0xd6, 0x6a, 0x8a, 0xa8, 0x47, 0x5c, 0xa8, 0xa3, 0x94, 0x51, 0x2a, 0x98, 0xa9, 0x83,
-	0x45, 0x5c, 0xa8, 0x60, 0x9f, 0x41, 0x7d, 0xc6, 0x49, 0xea, 0x51, 0x7d, 0x2b, 0xb5, 0xb0, 0x29,
-	0xcd, 0x7e, 0x84, 0xbe, 0x04, 0x48, 0xd2, 0x38, 0x24, 0x9c, 0x4b, 0xce, 0x52, 0x9c, 0x9d, 0x21,
-	0xfd, 0x08, 0xdd, 0x82, 0x66, 0x4e, 0x27, 0xbe, 0x38, 0x51, 0x77, 0x93, 0x8d, 0x1b, 0x19, 0x36,
-	0xf6, 0xc5, 0x89, 0xbc, 0x9e, 0x72, 0x97, 0xf0, 0x43, 0xa4, 0xae, 0x27, 0x1b, 0xe7, 0x41, 0x7b,
-	0x1f, 0x4e, 0xc5, 0xf0, 0xd3, 0x09, 0x57, 0x57, 0x54, 0x11, 0xa3, 0x9b, 0x4e, 0x38, 0x72, 0x8b,
-	0x18, 0x84, 0xcd, 0xb3, 0x4b, 0xe8, 0xce, 0x79, 0x1b, 0xe7, 0x60, 0xac, 0xfd, 0x5c, 0x36, 0xd7,
-	0xa7, 0x31, 0x7f, 0x93, 0xcb, 0xe6, 0x3b, 0x8f, 0x61, 0x73, 0x85, 0xfe, 0xbf, 0xb1, 0xb1, 0xcb,
-	0x63, 0xf3, 0x0b, 0x58, 0xa3, 0x84, 0xa4, 0xbe, 0x88, 0x53, 0xb5, 0x16, 0x17, 0x49, 0xb1, 0x16,
-	0x17, 0x09, 0x91, 0xf7, 0x77, 0x2c, 0x79, 0x16, 0x65, 0xda, 0xdc, 0x94, 0xde, 0x91, 0x2f, 0x7c,
-	0xf5, 0x09, 0x6d, 0xac, 0x9e, 0xd1, 0x17, 0x60, 0x73, 0xc2, 0x38, 0x15, 0x74, 0x4e, 0xd4, 0x27,
-	0xb4, 0x70, 0x01, 0x74, 0x7e, 0x37, 0xa0, 0x2a, 0xf7, 0xa2, 0x94, 0x32, 0xbf, 0xd8, 0xbf, 0xf2,
-	0x59, 0xbe, 0x88, 0x30, 0x39, 0xfa, 0xfa, 0x45, 0x16, 0xce, 0x4d, 0x74, 0x43, 0x7e, 0x2e, 0x12,
-	0x92, 0x88, 0xb0, 0x50, 0xef, 0x36, 0x0b, 0x97, 0x10, 0xb9, 0xf7, 0x7c, 0xbd, 0xb5, 0xf5, 0xd0,
-	0x64, 0x96, 0x1c, 0xcd, 0x68, 0x96, 0xfa, 0x8a, 0xd1, 0x53, 0xb3, 0xb4, 0xd1, 0x01, 0x58, 0x71,
-	0x56, 0xb6, 0x1a, 0x9b, 0xc6, 0x21, 0x2a, 0x3a, 0x9f, 0x37, 0x04, 0x2f, 0x7d, 0x3a, 0x7f, 0x1b,
-	0xd0, 0xec, 0x4d, 0x29, 0x61, 0xa2, 0x17, 0xb3, 0xb7, 0x74, 0x72, 0xe6, 0x7c, 0xe5, 0x25, 0x55,
-	0x4e, 0x97, 0x94, 0xaf, 0x71, 0xdd, 0xa4, 0xdc, 0x44, 0xdf, 0xc2, 0x16, 0xe5, 0xcf, 0x68, 0x4a,
-	0x3e, 0xf8, 0xd3, 0x29, 0x9e, 0x31, 0x46, 0xd9, 0x24, 0xeb, 0xd7, 0x59, 0x42, 0x16, 0x18, 0xaa,
-	0xb7, 0x66, 0x65, 0x64, 0x96, 0x2c, 0x70, 0x1a, 0x4f, 0x06, 0x64, 0x4e, 0xa6, 0xd9, 0xec, 0x2f,
-	0x6d, 0x74, 0x27, 0xff, 0x8b, 0x50, 0x57, 0x73, 0xb5, 0xfa, 0xcf, 0x44, 0x93, 0x9d, 0x3f, 0x0c,
-	0x68, 0x0e, 0x63, 0x41, 0xdf, 0xd2, 0x50, 0xf7, 0x65, 0xb5, 0xac, 0x1b, 0x00, 0xa1, 0x2a, 0x7b,
-	0x58, 0x14, 0x57, 0x42, 0x24, 0xcf, 0x49, 0x3a, 0x27, 0xa9, 0xe2, 0x75, 0x95, 0x25, 0x04, 0xdd,
-	0xc9, 0x46, 0x4a, 0xd6, 0xb6, 0x71, 0xd8, 0x2e, 0xb2, 0xe8, 0xea, 0xff, 0x52, 0x7a, 0xc8, 0xf2,
-	0x51, 0xaa, 0x95, 0x46, 0x69, 0x59, 0x80, 0xf9, 0xa9, 0x02, 0xa6, 0xb0, 0x55, 0xce, 0xff, 0xdc,
-	0x2b, 0x0b, 0xdd, 0x85, 0x6a, 0x18, 0x47, 0x3a, 0xfd, 0x8d, 0xf2, 0xc6, 0x3c, 0x23, 0xed, 0xc5,
-	0x11, 0xc1, 0xca, 0xf9, 0xbc, 0xf1, 0xde, 0xff, 0xc7, 0x00, 0x53, 0x27, 0x8e, 0x2c, 0xa8, 0x0e,
-	0x47, 0x43, 0xb7, 0xbd, 0x86, 0xb6, 0xa0, 0x35, 0x18, 0x75, 0x9f, 0x7a, 0xcf, 0xfa, 0xd8, 0xfd,
-	0xb9, 0x3b, 0x18, 0xb4, 0x0d, 0x74, 0x05, 0x36, 0x8f, 0x87, 0xa7, 0xc1, 0x8a, 0xf4, 0xeb, 0xbd,
-	0xe8, 0x0e, 0x9f, 0xbb, 0x5e, 0x6f, 0x34, 0x7c, 0xd6, 0x7f, 0xde, 0x5e, 0x47, 0x9b, 0xd0, 0x70,
-	0x87, 0xdd, 0x27, 0x03, 0xd7, 0xc3, 0xc7, 0x03, 0xb7, 0x5d, 0x45, 0x6d, 0x68, 0x3e, 0xed, 0x1f,
-	0x15, 0x48, 0x4d, 0xba, 0x3c, 0x75, 0x07, 0xee, 0xeb, 0x0c, 0x30, 0x25, 0x90, 0x85, 0x51, 0x40,
-	0x1d, 0xb5, 0xc0, 0x1e, 0x8c, 0x9e, 0x7b, 0x03, 0xf7, 0x8d, 0x3b, 0x68, 0x5b, 0x32, 0xb1, 0xa3,
-	0xd7, 0xa3, 0x71, 0xdb, 0x96, 0x59, 0xbc, 0x1a, 0x0d, 0xfb, 0xaf, 0x47, 0xd8, 0x1b, 0xe3, 0x51,
-	0xcf, 0x3d, 0x3a, 0x6a, 0x03, 0x72, 0xe0, 0xaa, 0xa4, 0xbd, 0x55, 0xa6, 0xb1, 0xbf, 0x0f, 0xdb,
-	0xe7, 0xf6, 0x03, 0x99, 0x50, 0x19, 0xbd, 0x6c, 0xaf, 0x21, 0x1b, 0x6a, 0x2e, 0xc6, 0x23, 0xdc,
-	0x36, 0x0e, 0xff, 0x35, 0xa0, 0x72, 0xdc, 0x47, 0xf7, 0xa0, 0x2a, 0x17, 0x05, 0xda, 0x2e, 0x5a,
-	0x5a, 0xda, 0x41, 0x3b, 0x57, 0x56, 0xe1, 0x64, 0xba, 0xe8, 0xac, 0xa1, 0xef, 0xa1, 0xde, 0xe5,
-	0xef, 0xd4, 0x45, 0x70, 0xee, 0x1f, 0xec, 0x9d, 0x95, 0x6f, 0xdd, 0x59, 0x43, 0x8f, 0xc1, 0x3e,
-	0x9a, 0x05, 0x3c, 0x4c, 0x69, 0x40, 0xd0, 0xb5, 0x92, 0xa8, 0x74, 0x24, 0x77, 0x2e, 0xc0, 0x3b,
-	0x6b, 0xe8, 0x27, 0x68, 0x95, 0x4b, 0xe3, 0xe8, 0xfa, 0x27, 0x66, 0xa0, 0x1c, 0xa7, 0x4c, 0x76,
-	0xd6, 0xf6, 0x8c, 0xef, 0x8c, 0xc0, 0x54, 0xe4, 0xdd, 0xff, 0x02, 0x00, 0x00, 0xff, 0xff, 0xb1,
-	0x02, 0xad, 0xc9, 0x62, 0x0c, 0x00, 0x00,
+	// 1856 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x58, 0xcb, 0x73, 0x23, 0x47,
+	0x19, 0xb7, 0x64, 0xbd, 0xe6, 0x93, 0x64, 0xcb, 0xed, 0x75, 0x32, 0xf1, 0x42, 0xe2, 0x4c, 0x96,
+	0xac, 0x71, 0x81, 0x2b, 0x38, 0x81, 0xda, 0x2c, 0x49, 0xa5, 0xbc, 0xf2, 0xac, 0x57, 0x44, 0xb1,
+	0x4c, 0xdb, 0x9b, 0x14, 0xa7, 0xa9, 0xd1, 0x4c, 0xdb, 0xee, 0x5a, 0x79, 0x66, 0xe8, 0xee, 0xd1,
+	0xae, 0x4e, 0x9c, 0xe1, 0x06, 0x7f, 0x04, 0x7f, 0x06, 0x07, 0x2e, 0x1c, 0xb9, 0x70, 0xe2, 0x2f,
+	0xe1, 0x48, 0xf5, 0x63, 0x1e, 0x92, 0x25, 0x53, 0x2e, 0x4e, 0xea, 0xef, 0xf5, 0x9b, 0xaf, 0xbb,
+	0xbf, 0x57, 0x0b, 0x5a, 0x29, 0x3d, 0x4c, 0x58, 0x2c, 0x62, 0xd4, 0x52, 0x3f, 0x41, 0x3c, 0x71,
+	0xfe, 0x5c, 0x81, 0xba, 0x3b, 0x25, 0x91, 0x40, 0x08, 0x6a, 0x82, 0xde, 0x12, 0xbb, 0xb2, 0x57,
+	0xd9, 0xb7, 0xb0, 0x5a, 0xa3, 0x2f, 0x00, 0x82, 0x38, 0x8a, 0x48, 0x20, 0x68, 0x1c, 0xd9, 0xd5,
+	0xbd, 0xca, 0x7e, 0xfb, 0xe8, 0xd1, 0x61, 0x66, 0x7c, 0xd8, 0xcf, 0x65, 0xb8, 0xa4, 0x87, 0x1c,
+	0xa8, 0xb1, 0x74, 0x42, 0xec, 0x75, 0xa5, 0xbf, 0x51, 0xe8, 0xe3, 0x74, 0x42, 0xb0, 0x92, 0xa1,
+	0x5d, 0x68, 0xa5, 0x11, 0x7d, 0x17, 0xf9, 0x51, 0x6c, 0xd7, 0xf6, 0x2a, 0xfb, 0xeb, 0x38, 0xa7,
+	0x9d, 0x7f, 0xb4, 0x01, 0x2e, 0x84, 0x2f, 0x28, 0x17, 0x34, 0xe0, 0xe8, 0x27, 0xb0, 0x11, 0xfa,
+	0xe4, 0x36, 0x8e, 0xbc, 0x29, 0x61, 0x5c, 0x3a, 0xa2, 0x5d, 0xec, 0x6a, 0xee, 0xf7, 0x9a, 0x89,
+	0x1e, 0x41, 0x5d, 0x22, 0x73, 0xe5, 0x66, 0x0d, 0x6b, 0x02, 0xbd, 0x07, 0x8d, 0x34, 0x51, 0xfb,
+	0x5a, 0x57, 0x6c, 0x43, 0xa1, 0x4f, 0xa0, 0x1b, 0x46, 0xdc, 0x63, 0x84, 0x27, 0x71, 0xc4, 0x09,
+	0x57, 0x4e, 0xd4, 0x70, 0x27, 0x8c, 0x38, 0xce, 0x78, 0x68, 0x0f, 0xda, 0xc5, 0xb6, 0xb8, 0x5d,
+	0x57, 0x2a, 0x65, 0x16, 0xb2, 0xa1, 0x49, 0xaf, 0xa3, 0x98, 0x91, 0xd0, 0x6e, 0x28, 0x69, 0x46,
+	0xca, 0x0d, 0xfa, 0x41, 0x40, 0x12, 0x41, 0x42, 0xbb, 0xa9, 0x44, 0x39, 0x2d, 0xad, 0x42, 0x16,
+	0x27, 0x09, 0x09, 0xed, 0x96, 0xb6, 0x32, 0x24, 0x7a, 0x0c, 0x96, 0xf4, 0xdb, 0xbb, 0xa1, 0x82,
+	0xdb, 0x96, 0x36, 0x93, 0x8c, 0x57, 0x54, 0x70, 0xf4, 0x11, 0xb4, 0x95, 0xf0, 0x96, 0x72, 0xe9,
+	0x31, 0x28, 0x31, 0x48, 0xd6, 0x77, 0x8a, 0x83, 0xbe, 0x82, 0xd6, 0x78, 0xe6, 0xa9, 0xe3, 0xb6,
+	0xdb, 0x7b, 0xeb, 0xfb, 0xed, 0xa3, 0x8f, 0x8b, 0xc3, 0x2f, 0x4e, 0xf4, 0xf0, 0xc5, 0xec, 0x5c,
+	0x72, 0xdd, 0x48, 0xb0, 0x19, 0x6e, 0x8e, 0x35, 0x85, 0x5e, 0x00, 0x8c, 0x67, 0x9e, 0x1f, 0x86,
+	0x8c, 0x70, 0x6e, 0x77, 0x94, 0xfd, 0x27, 0x2b, 0xec, 0x8f, 0xb5, 0x96, 0x46, 0xb0, 0xc6, 0x19,
+	0x8d, 0xbe, 0x84, 0xe6, 0x78, 0xe6, 0xdd, 0xc4, 0x5c, 0xd8, 0x5d, 0x05, 0xb0, 0xb7, 0x02, 0xe0,
+	0x55, 0xcc, 0x85, 0xb6, 0x6e, 0x8c, 0x15, 0x61, 0x4c, 0x93, 0x98, 0x09, 0x7b, 0xe3, 0x5e, 0xd3,
+	0xf3, 0x98, 0x15, 0xa6, 0x92, 0x40, 0xbf, 0x82, 0xc6, 0x78, 0xe6, 0xa5, 0x34, 0xb4, 0x37, 0x95,
+	0xe5, 0x47, 0x2b, 0x2c, 0x5f, 0xd3, 0x50, 0x1b, 0xd6, 0xc7, 0x72, 0x8d, 0xbe, 0x85, 0xee, 0x78,
+	0xe6, 0x91, 0x77, 0x24, 0x48, 0x85, 0x3f, 0x9e, 0x10, 0xbb, 0xa7, 0xcc, 0x3f, 0x5d, 0x61, 0xee,
+	0xe6, 0x8a, 0x1a, 0xa5, 0x33, 0x2e, 0xb1, 0xd0, 0x53, 0x68, 0x10, 0x99, 0x48, 0xdc, 0xde, 0x52,
+	0x28, 0x9b, 0x05, 0x8a, 0x4a, 0x30, 0x6c, 0xc4, 0x32, 0x32, 0x18, 0xf9, 0x7d, 0x4a, 0x52, 0x12,
+	0xda, 0xc8, 0x5c, 0xb1, 0xa1, 0x91, 0x0f, 0x3b, 0x82, 0xf9, 0x57, 0x57, 0x34, 0xf0, 0xe6, 0x3d,
+	0xdb, 0x56, 0x98, 0x3f, 0x5f, 0xea, 0xd9, 0xa5, 0xb6, 0xb8, 0xeb, 0xe0, 0xb6, 0xb8, 0x2b, 0x41,
+	0x23, 0xd8, 0x2c, 0x7d, 0x42, 0x25, 0xea, 0x23, 0x05, 0xfe, 0xf4, 0x7e, 0x70, 0x9c, 0x66, 0xb0,
+	0x5d, 0x51, 0xe6, 0xed, 0x3e, 0x87, 0x4e, 0x39, 0xa0, 0x50, 0x0f, 0xd6, 0xdf, 0x90, 0x99, 0x49,
+	0x52, 0xb9, 0x94, 0xa9, 0x39, 0xf5, 0x27, 0x29, 0xc9, 0x52, 0x53, 0x11, 0xcf, 0xab, 0xcf, 0x2a,
+	0xbb, 0x5f, 0xc1, 0xc6, 0x7c, 0x30, 0x3d, 0xc8, 0xfa, 0x4b, 0x68, 0x97, 0x22, 0xe9, 0xe1, 0xa6,
+	0x79, 0x24, 0x3d, 0xc8, 0xf4, 0x19, 0x40, 0x11, 0x4a, 0x0f, 0xb2, 0xfc, 0x06, 0xb6, 0xee, 0x5c,
+	0xd2, 0x83, 0x00, 0x7e, 0x07, 0xf6, 0xaa, 0xcb, 0x5e, 0x82, 0xf3, 0xb4, 0x8c, 0xd3, 0x3e, 0xda,
+	0x2a, 0xee, 0xd7, 0x80, 0x94, 0xa1, 0x2f, 0x00, 0xdd, 0xbd, 0xea, 0xff, 0x13, 0xd4, 0x19, 0x40,
+	0xfb, 0x9c, 0x46, 0xd7, 0x58, 0x86, 0x37, 0x17, 0x68, 0x03, 0xaa, 0x34, 0x54, 0x60, 0x35, 0x5c,
+	0xa5, 0x21, 0x3a, 0x80, 0x3a, 0x17, 0xbe, 0xe0, 0x77, 0x3b, 0x4b, 0x11, 0x80, 0x58, 0xab, 0x38,
+	0x8f, 0xc1, 0xd2, 0x50, 0xc9, 0x64, 0xb6, 0x08, 0xe4, 0xfc, 0xb5, 0x0e, 0x50, 0x34, 0x23, 0x99,
+	0x61, 0x19, 0x92, 0x71, 0x3d, 0xa7, 0xd1, 0x0e, 0x34, 0x38, 0x0b, 0x3c, 0x9a, 0xa8, 0x8f, 0x5a,
+	0xb8, 0xce, 0x59, 0x30, 0x48, 0xd0, 0x07, 0xd0, 0x92, 0x6c, 0x55, 0x7e, 0x64, 0xa7, 0xe8, 0xe2,
+	0x26, 0x67, 0x81, 0xaa, 0x2e, 0x3b, 0xd0, 0x08, 0xb9, 0x90, 0x16, 0x35, 0x6d, 0x11, 0x72, 0xa1,
+	0x2d, 0x24, 0x5b, 0xd5, 0xba, 0xba, 0x12, 0x34, 0x43, 0x2e, 0x54, 0x29, 0x33, 0x22, 0x05, 0xd6,
+	0xd0, 0x60, 0x21, 0x17, 0x0a, 0xec, 0x7d, 0x68, 0xa6, 0x9c, 0x30, 0x8f, 0xea, 0xae, 0xd0, 0xc5,
+	0x0d, 0x49, 0x0e, 0x42, 0xf4, 0x63, 0x80, 0x84, 0xc5, 0x01, 0xe1, 0xdc, 0xa3, 0xba, 0x2d, 0x74,
+	0xb1, 0x65, 0x38, 0x83, 0x10, 0x7d, 0x0c, 0x9d, 0x4c, 0x9c, 0xf8, 0xe2, 0x46, 0xf5, 0x06, 0x0b,
+	0xb7, 0x0d, 0xef, 0xdc, 0x17, 0x37, 0xb2, 0x3d, 0x64, 0x2a, 0xc1, 0xdb, 0x50, 0xb5, 0x07, 0x0b,
+	0x67, 0xa0, 0xfd, 0xb7, 0x73, 0x18, 0x3e, 0xbb, 0xe6, 0xaa, 0x45, 0x14, 0x18, 0xc7, 0xec, 0x9a,
+	0x23, 0xb7, 0xc0, 0x20, 0xd1, 0xd4, 0x34, 0x81, 0x27, 0xcb, 0x3a, 0xfe, 0xe1, 0xb9, 0xd6, 0x73,
+	0xa3, 0xa9, 0xae, 0x0a, 0xd9, 0x97, 0xdc, 0x68, 0x8a, 0x7e, 0x80, 0xad, 0xdc, 0x95, 0x1b, 0x12,
+	0xbc, 0xe1, 0xe9, 0x2d, 0x37, 0x0d, 0xe1, 0xe0, 0x3e, 0xb0, 0x7e, 0xa6, 0xac, 0x21, 0x7b, 0xc9,
+	0x02, 0x1b, 0x3d, 0x2f, 0xb6, 0x20, 0x18, 0x21, 0xa6, 0x53, 0xbc, 0x5f, 0x60, 0x9e, 0x67, 0x07,
+	0xc2, 0x64, 0xc9, 0xcd, 0x36, 0x73, 0xc9, 0x08, 0xd9, 0xfd, 0x1a, 0x36, 0x17, 0x7c, 0xfe, 0x5f,
+	0xb9, 0x67, 0x95, 0x13, 0xa4, 0x0f, 0x3b, 0x4b, 0xbd, 0x7c, 0x08, 0x88, 0xf3, 0x97, 0x0a, 0xb4,
+	0x46, 0x09, 0x61, 0xbe, 0x88, 0x99, 0x9a, 0xb8, 0x66, 0x49, 0x31, 0x71, 0xcd, 0x12, 0x22, 0x47,
+	0x83, 0x58, 0xca, 0xa3, 0xd0, 0x18, 0x67, 0xa4, 0xd4, 0x0e, 0x7d, 0xe1, 0xab, 0xe8, 0xb4, 0xb0,
+	0x5a, 0xa3, 0x1f, 0x81, 0xc5, 0x49, 0xc4, 0xa9, 0xa0, 0x53, 0xa2, 0xa2, 0xb3, 0x85, 0x0b, 0x06,
+	0xfa, 0x14, 0x6a, 0x13, 0xaa, 0xa2, 0x53, 0x1e, 0x12, 0x2a, 0x0e, 0x29, 0xf3, 0x00, 0x2b, 0xb9,
+	0xf3, 0xcf, 0x2a, 0xd4, 0x64, 0xca, 0xcb, 0x4f, 0x44, 0x7e, 0x31, 0x02, 0xca, 0xb5, 0x74, 0x88,
+	0x44, 0xb2, 0xca, 0x68, 0x87, 0x5a, 0x38, 0x23, 0xd1, 0x87, 0x32, 0x62, 0x49, 0x40, 0x42, 0x12,
+	0x05, 0x7a, 0xbc, 0x6a, 0xe1, 0x12, 0x47, 0x8e, 0x5e, 0xbe, 0x1e, 0x1c, 0x75, 0xde, 0x34, 0xfc,
+	0x3c, 0x3b, 0xc3, 0x94, 0xf9, 0x4a, 0xa2, 0x13, 0x27, 0xa7, 0xd1, 0x21, 0xb4, 0x62, 0xe3, 0x9c,
+	0xca, 0x9c, 0xe5, 0x6e, 0xe7, 0x3a, 0x3a, 0xd3, 0x69, 0xcc, 0xa8, 0x98, 0xa9, 0x7c, 0xaa, 0xe3,
+	0x9c, 0x96, 0x32, 0x1e, 0xdc, 0x90, 0x50, 0x76, 0xb8, 0x96, 0xfe, 0x4e, 0x46, 0xa3, 0x9f, 0x42,
+	0x7d, 0x42, 0x6f, 0xa9, 0x50, 0x79, 0xd4, 0x3e, 0xda, 0x9e, 0x9f, 0x51, 0x87, 0x52, 0x84, 0xb5,
+	0x06, 0x3a, 0x92, 0xed, 0x3a, 0xa4, 0x8c, 0x04, 0x42, 0xe5, 0x54, 0xfb, 0xe8, 0xbd, 0x79, 0x6d,
+	0x6c, 0xa4, 0x38, 0xd7, 0x73, 0xfe, 0x55, 0x81, 0x4e, 0x7f, 0x42, 0x49, 0x24, 0xfa, 0x71, 0x74,
+	0x45, 0xaf, 0xef, 0x54, 0xbe, 0xec, 0xa4, 0xab, 0xf3, 0x27, 0x9d, 0x0d, 0xb8, 0xfa, 0x8e, 0x33,
+	0x12, 0xfd, 0x0c, 0xb6, 0x28, 0x7f, 0x49, 0x19, 0x79, 0xeb, 0x4f, 0x26, 0x38, 0x8d, 0x22, 0x1a,
+	0x5d, 0x9b, 0xeb, 0xbe, 0x2b, 0x90, 0xe7, 0x1e, 0xa8, 0xaf, 0x9a, 0xd3, 0x35, 0x94, 0x3c, 0x8f,
+	0x49, 0x7c, 0x3d, 0x24, 0x53, 0x32, 0x31, 0x55, 0x29, 0xa7, 0xd1, 0x93, 0x6c, 0x78, 0x6e, 0xee,
+	0xad, 0x2f, 0x99, 0xd9, 0xb5, 0xd0, 0xf9, 0x5b, 0x05, 0x3a, 0x67, 0xb1, 0xa0, 0x57, 0x34, 0xd0,
+	0xd7, 0xb5, 0xb8, 0xad, 0x0f, 0x01, 0x02, 0xb5, 0xed, 0xb3, 0x62, 0x73, 0x25, 0x8e, 0x94, 0x73,
+	0xc2, 0xa6, 0x84, 0x29, 0xb9, 0xde, 0x65, 0x89, 0x83, 0x9e, 0x98, 0x8c, 0x90, 0x7b, 0xdb, 0x38,
+	0xea, 0x15, 0x5e, 0x1c, 0xeb, 0x57, 0x86, 0x92, 0xe6, 0x99, 0x50, 0x2f, 0x65, 0x42, 0xbe, 0x81,
+	0xc6, 0x7d, 0x1b, 0x98, 0xc0, 0x56, 0xd9, 0xff, 0xa5, 0xcd, 0x04, 0x7d, 0x0e, 0xb5, 0x20, 0x0e,
+	0xb5, 0xfb, 0x1b, 0xe5, 0x59, 0xf2, 0x8e, 0x69, 0x3f, 0x0e, 0x09, 0x56, 0xca, 0xcb, 0xb2, 0xd3,
+	0xf9, 0x25, 0x74, 0xe7, 0xca, 0x91, 0xac, 0x14, 0x89, 0xf9, 0x54, 0x17, 0xcb, 0xa5, 0x34, 0x53,
+	0xe5, 0xdc, 0xc4, 0x81, 0x5c, 0x3b, 0x7f, 0xac, 0x80, 0x95, 0x47, 0xe1, 0xe2, 0x1b, 0x44, 0xdb,
+	0x96, 0x59, 0xf2, 0x5e, 0x69, 0x24, 0x08, 0x9b, 0xfa, 0x13, 0x83, 0x93, 0xd3, 0xc8, 0x91, 0x13,
+	0xae, 0x20, 0xdc, 0x4b, 0x08, 0xf3, 0x42, 0x7f, 0x66, 0x5e, 0x41, 0x6d, 0xc5, 0x3c, 0x27, 0xec,
+	0xc4, 0x57, 0x79, 0x42, 0xde, 0x05, 0x84, 0x84, 0x24, 0x34, 0x99, 0x9a, 0xd3, 0xce, 0x33, 0xe8,
+	0x94, 0x43, 0x5c, 0x56, 0x36, 0x35, 0xa9, 0x1a, 0x3f, 0x34, 0x21, 0x77, 0x71, 0xeb, 0xb3, 0x37,
+	0xea, 0xeb, 0x5d, 0xac, 0xd6, 0xce, 0x9f, 0x2a, 0xd0, 0x79, 0x45, 0xb9, 0x88, 0xd9, 0xec, 0xb7,
+	0x29, 0x61, 0x33, 0x19, 0xde, 0xa6, 0x1a, 0x9b, 0xfa, 0x92, 0x91, 0xd2, 0x5c, 0x75, 0x51, 0x73,
+	0x08, 0x72, 0x2d, 0x79, 0xf9, 0x1b, 0xd2, 0x32, 0x6f, 0x46, 0x04, 0xb5, 0x2b, 0x16, 0xdf, 0x9a,
+	0xf7, 0xa2, 0x5a, 0xcb, 0xcb, 0x13, 0xb1, 0x8a, 0x84, 0x75, 0x5c, 0x15, 0xb1, 0x74, 0x50, 0x27,
+	0xb6, 0x8e, 0x70, 0x4d, 0x38, 0x7f, 0x80, 0xa6, 0x99, 0x4e, 0x96, 0x9d, 0xe7, 0xc2, 0x9b, 0xee,
+	0x03, 0x68, 0xe9, 0x33, 0xa3, 0x91, 0x99, 0xc0, 0x9a, 0x8a, 0x1e, 0x44, 0xf2, 0x79, 0xa6, 0x45,
+	0x71, 0x2a, 0xcc, 0x51, 0x6a, 0xdd, 0x51, 0x2a, 0xe6, 0xea, 0x9a, 0x7e, 0x4d, 0xe6, 0xf4, 0xc1,
+	0xbf, 0x2b, 0xd0, 0xd0, 0x31, 0x8c, 0x5a, 0x50, 0x3b, 0x1b, 0x9d, 0xb9, 0xbd, 0x35, 0xb4, 0x05,
+	0xdd, 0xe1, 0xe8, 0xf8, 0xc4, 0x7b, 0x39, 0xc0, 0xee, 0x0f, 0xc7, 0xc3, 0x61, 0xaf, 0x82, 0xb6,
+	0x61, 0xf3, 0xf5, 0xd9, 0x3c, 0xb3, 0x2a, 0xf5, 0xfa, 0xaf, 0x8e, 0xcf, 0x4e, 0x5d, 0xaf, 0x3f,
+	0x3a, 0x7b, 0x39, 0x38, 0xed, 0xad, 0xa3, 0x4d, 0x68, 0xbb, 0x67, 0xc7, 0x2f, 0x86, 0xae, 0x87,
+	0x5f, 0x0f, 0xdd, 0x5e, 0x0d, 0xf5, 0xa0, 0x73, 0x32, 0xb8, 0x28, 0x38, 0x75, 0xa9, 0x72, 0xe2,
+	0x0e, 0xdd, 0x4b, 0xc3, 0x68, 0x48, 0x86, 0x81, 0x51, 0x8c, 0x26, 0xea, 0x82, 0x35, 0x1c, 0x9d,
+	0x7a, 0x43, 0xf7, 0x7b, 0x77, 0xd8, 0x6b, 0x49, 0xc7, 0x2e, 0x2e, 0x47, 0xe7, 0x3d, 0x4b, 0x7a,
+	0xf1, 0xdd, 0xe8, 0x6c, 0x70, 0x39, 0xc2, 0xde, 0x39, 0x1e, 0xf5, 0xdd, 0x8b, 0x8b, 0x1e, 0x20,
+	0x1b, 0x1e, 0x49, 0xb1, 0xb7, 0x28, 0x69, 0x1f, 0x1c, 0xc0, 0xce, 0xd2, 0xd4, 0x40, 0x0d, 0xa8,
+	0x8e, 0xbe, 0xed, 0xad, 0x21, 0x0b, 0xea, 0x2e, 0xc6, 0x23, 0xdc, 0xab, 0x1c, 0xfd, 0xa7, 0x02,
+	0xd5, 0xd7, 0x03, 0xf4, 0x05, 0xd4, 0xe4, 0x34, 0x87, 0x76, 0x4a, 0x9d, 0xbb, 0x18, 0x14, 0x77,
+	0xb7, 0x17, 0xd9, 0xc9, 0x64, 0xe6, 0xac, 0xa1, 0x5f, 0x40, 0xf3, 0x98, 0xbf, 0x51, 0xad, 0x6a,
+	0xe9, 0xbf, 0x10, 0xbb, 0x0b, 0x69, 0xef, 0xac, 0xa1, 0xaf, 0xc1, 0xba, 0x48, 0xc7, 0x3c, 0x60,
+	0x74, 0x4c, 0x50, 0xa9, 0x70, 0x97, 0xab, 0xf3, 0xee, 0x0a, 0xbe, 0xb3, 0x86, 0x7e, 0x03, 0xdd,
+	0xf2, 0xd6, 0x38, 0x7a, 0x7c, 0x4f, 0x39, 0xd8, 0x7d, 0x6f, 0xb9, 0xd0, 0x59, 0xdb, 0xaf, 0x7c,
+	0x56, 0x39, 0xfa, 0x7b, 0x15, 0x1a, 0x27, 0xea, 0x2f, 0x0b, 0xf9, 0x50, 0x3f, 0x25, 0x02, 0xeb,
+	0x7f, 0x28, 0x96, 0x1b, 0xad, 0x06, 0x43, 0xdf, 0x40, 0xf7, 0x94, 0x88, 0xd2, 0x3f, 0x24, 0xab,
+	0x20, 0x96, 0x0e, 0xd4, 0x0a, 0xa0, 0xa1, 0xf4, 0x66, 0x2b, 0x2d, 0xef, 0xdb, 0xa6, 0xb3, 0x86,
+	0x7e, 0x0d, 0x9d, 0x0b, 0xc1, 0x88, 0x7f, 0xeb, 0xea, 0x27, 0xed, 0x2a, 0x98, 0xc5, 0x37, 0xb0,
+	0xb3, 0xf6, 0x59, 0x45, 0x1a, 0xab, 0x8a, 0x60, 0xaa, 0x43, 0xd9, 0xb8, 0x5c, 0x30, 0x96, 0x1a,
+	0x8f, 0x1b, 0x8a, 0xf7, 0xf9, 0x7f, 0x07, 0x00, 0x4c, 0x2c, 0x3d, 0xbd, 0xcc, 0x12, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -1032,3 +1360,286 @@ var _UI_serviceDesc = grpc.ServiceDesc{
 	},
 	Metadata: "ui.proto",
 }
+
+// DaemonClient is the client API for Daemon service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type DaemonClient interface {
+	// the rules loaded in the rules field of the reply, and the configuration
+	// in the data field
+	GetRules(ctx context.Context, in *Notification, opts ...grpc.CallOption) (*Notification, error)
+	GetStatistics(ctx context.Context, in *Notification, opts ...grpc.CallOption) (*Statistics, error)
+	// applies an action: CHANGE_RULE, DELETE_RULE, ENABLE_RULE, DISABLE_RULE,
+	// CHANGE_CONFIG, LOAD_FIREWALL, UNLOAD_FIREWALL
+	Notify(ctx context.Context, in *Notification, opts ...grpc.CallOption) (*NotificationReply, error)
+	// the connections events, as the rules are applied
+	StreamEvents(ctx context.Context, in *Notification, opts ...grpc.CallOption) (Daemon_StreamEventsClient, error)
+	// the connections saved to the history, newest first
+	QueryHistory(ctx context.Context, in *HistoryQuery, opts ...grpc.CallOption) (Daemon_QueryHistoryClient, error)
+}
+
+type daemonClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewDaemonClient(cc *grpc.ClientConn) DaemonClient {
+	return &daemonClient{cc}
+}
+
+func (c *daemonClient) GetRules(ctx context.Context, in *Notification, opts ...grpc.CallOption) (*Notification, error) {
+	out := new(Notification)
+	err := c.cc.Invoke(ctx, "/protocol.Daemon/GetRules", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *daemonClient) GetStatistics(ctx context.Context, in *Notification, opts ...grpc.CallOption) (*Statistics, error) {
+	out := new(Statistics)
+	err := c.cc.Invoke(ctx, "/protocol.Daemon/GetStatistics", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *daemonClient) Notify(ctx context.Context, in *Notification, opts ...grpc.CallOption) (*NotificationReply, error) {
+	out := new(NotificationReply)
+	err := c.cc.Invoke(ctx, "/protocol.Daemon/Notify", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *daemonClient) StreamEvents(ctx context.Context, in *Notification, opts ...grpc.CallOption) (Daemon_StreamEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Daemon_serviceDesc.Streams[0], "/protocol.Daemon/StreamEvents", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &daemonStreamEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Daemon_StreamEventsClient interface {
+	Recv() (*Event, error)
+	grpc.ClientStream
+}
+
+type daemonStreamEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *daemonStreamEventsClient) Recv() (*Event, error) {
+	m := new(Event)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *daemonClient) QueryHistory(ctx context.Context, in *HistoryQuery, opts ...grpc.CallOption) (Daemon_QueryHistoryClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Daemon_serviceDesc.Streams[1], "/protocol.Daemon/QueryHistory", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &daemonQueryHistoryClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Daemon_QueryHistoryClient interface {
+	Recv() (*Event, error)
+	grpc.ClientStream
+}
+
+type daemonQueryHistoryClient struct {
+	grpc.ClientStream
+}
+
+func (x *daemonQueryHistoryClient) Recv() (*Event, error) {
+	m := new(Event)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// DaemonServer is the server API for Daemon service.
+type DaemonServer interface {
+	// the rules loaded in the rules field of the reply, and the configuration
+	// in the data field
+	GetRules(context.Context, *Notification) (*Notification, error)
+	GetStatistics(context.Context, *Notification) (*Statistics, error)
+	// applies an action: CHANGE_RULE, DELETE_RULE, ENABLE_RULE, DISABLE_RULE,
+	// CHANGE_CONFIG, LOAD_FIREWALL, UNLOAD_FIREWALL
+	Notify(context.Context, *Notification) (*NotificationReply, error)
+	// the connections events, as the rules are applied
+	StreamEvents(*Notification, Daemon_StreamEventsServer) error
+	// the connections saved to the history, newest first
+	QueryHistory(*HistoryQuery, Daemon_QueryHistoryServer) error
+}
+
+// UnimplementedDaemonServer can be embedded to have forward compatible implementations.
+type UnimplementedDaemonServer struct {
+}
+
+func (*UnimplementedDaemonServer) GetRules(ctx context.Context, req *Notification) (*Notification, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRules not implemented")
+}
+func (*UnimplementedDaemonServer) GetStatistics(ctx context.Context, req *Notification) (*Statistics, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStatistics not implemented")
+}
+func (*UnimplementedDaemonServer) Notify(ctx context.Context, req *Notification) (*NotificationReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Notify not implemented")
+}
+func (*UnimplementedDaemonServer) StreamEvents(req *Notification, srv Daemon_StreamEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamEvents not implemented")
+}
+func (*UnimplementedDaemonServer) QueryHistory(req *HistoryQuery, srv Daemon_QueryHistoryServer) error {
+	return status.Errorf(codes.Unimplemented, "method QueryHistory not implemented")
+}
+
+func RegisterDaemonServer(s *grpc.Server, srv DaemonServer) {
+	s.RegisterService(&_Daemon_serviceDesc, srv)
+}
+
+func _Daemon_GetRules_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Notification)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DaemonServer).GetRules(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/protocol.Daemon/GetRules",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DaemonServer).GetRules(ctx, req.(*Notification))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Daemon_GetStatistics_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Notification)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DaemonServer).GetStatistics(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/protocol.Daemon/GetStatistics",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DaemonServer).GetStatistics(ctx, req.(*Notification))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Daemon_Notify_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Notification)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DaemonServer).Notify(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/protocol.Daemon/Notify",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DaemonServer).Notify(ctx, req.(*Notification))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Daemon_StreamEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Notification)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DaemonServer).StreamEvents(m, &daemonStreamEventsServer{stream})
+}
+
+type Daemon_StreamEventsServer interface {
+	Send(*Event) error
+	grpc.ServerStream
+}
+
+type daemonStreamEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *daemonStreamEventsServer) Send(m *Event) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Daemon_QueryHistory_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(HistoryQuery)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DaemonServer).QueryHistory(m, &daemonQueryHistoryServer{stream})
+}
+
+type Daemon_QueryHistoryServer interface {
+	Send(*Event) error
+	grpc.ServerStream
+}
+
+type daemonQueryHistoryServer struct {
+	grpc.ServerStream
+}
+
+func (x *daemonQueryHistoryServer) Send(m *Event) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _Daemon_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "protocol.Daemon",
+	HandlerType: (*DaemonServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetRules",
+			Handler:    _Daemon_GetRules_Handler,
+		},
+		{
+			MethodName: "GetStatistics",
+			Handler:    _Daemon_GetStatistics_Handler,
+		},
+		{
+			MethodName: "Notify",
+			Handler:    _Daemon_Notify_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamEvents",
+			Handler:       _Daemon_StreamEvents_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "QueryHistory",
+			Handler:       _Daemon_QueryHistory_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "ui.proto",
+}
@@ -0,0 +1,232 @@
+package ui
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/evilsocket/opensnitch/daemon/core"
+	"github.com/evilsocket/opensnitch/daemon/log"
+	"github.com/evilsocket/opensnitch/daemon/ui/protocol"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+// serverConn is the connection to one of the UI services the daemon talks to.
+// Every connection is polled, pinged and subscribed to independently, and
+// has its own notifications stream, so the replies to the notifications of a
+// UI are only sent back to that UI.
+type serverConn struct {
+	sync.RWMutex
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	owner        *Client
+	address      string
+	socketPath   string
+	isUnixSocket bool
+	con          *grpc.ClientConn
+	client       protocol.UIClient
+
+	stopMonitoringProcess chan int
+}
+
+func newServerConn(owner *Client, address string) *serverConn {
+	s := &serverConn{
+		owner:                 owner,
+		address:               address,
+		socketPath:            address,
+		stopMonitoringProcess: make(chan int),
+	}
+	if strings.HasPrefix(address, "unix://") == true {
+		s.isUnixSocket = true
+		s.socketPath = address[7:]
+	}
+	s.ctx, s.cancel = context.WithCancel(owner.clientCtx)
+	return s
+}
+
+// Close stops polling the server and closes the connection.
+func (s *serverConn) Close() {
+	s.cancel()
+	s.disconnect()
+}
+
+// Connected checks if the connection with the server is established.
+func (s *serverConn) Connected() bool {
+	s.RLock()
+	defer s.RUnlock()
+	if s.con == nil || s.con.GetState() != connectivity.Ready {
+		return false
+	}
+	return true
+}
+
+func (s *serverConn) poller() {
+	log.Debug("UI service poller started for socket %s", s.socketPath)
+	wasConnected := false
+	for {
+		select {
+		case <-s.ctx.Done():
+			log.Info("Client.poller() exit, Done(): %s", s.address)
+			goto Exit
+		default:
+			isConnected := s.Connected()
+			if wasConnected != isConnected {
+				s.onStatusChange(isConnected)
+				wasConnected = isConnected
+			}
+
+			if s.Connected() == false {
+				// connect and create the client if needed
+				if err := s.connect(); err != nil {
+					log.Warning("Error while connecting to UI service %s: %s", s.address, err)
+				}
+			}
+			if s.Connected() == true {
+				// if the client is connected and ready, send a ping
+				if err := s.ping(time.Now()); err != nil {
+					log.Warning("Error while pinging UI service %s: %s", s.address, err)
+				}
+			}
+
+			time.Sleep(1 * time.Second)
+		}
+	}
+Exit:
+	log.Info("uiClient exit: %s", s.address)
+}
+
+func (s *serverConn) onStatusChange(connected bool) {
+	if connected {
+		log.Info("Connected to the UI service on %s", s.socketPath)
+		go s.Subscribe()
+	} else {
+		log.Error("Connection to the UI service %s lost.", s.address)
+		s.disconnect()
+	}
+}
+
+func (s *serverConn) connect() (err error) {
+	if s.Connected() {
+		return
+	}
+
+	s.RLock()
+	con := s.con
+	s.RUnlock()
+	if con != nil {
+		if con.GetState() == connectivity.TransientFailure || con.GetState() == connectivity.Shutdown {
+			s.disconnect()
+		} else {
+			return
+		}
+	}
+
+	if uid := s.owner.userID; uid != -1 {
+		if !s.isUnixSocket {
+			return fmt.Errorf("The GUI of the user %d must listen on a unix socket: %s", uid, s.socketPath)
+		}
+		// the GUI of the user is not running
+		if core.Exists(s.socketPath) == false {
+			return nil
+		}
+		if err := checkOwner(s.socketPath, uid); err != nil {
+			return err
+		}
+	}
+
+	if err := s.openSocket(); err != nil {
+		s.disconnect()
+		return err
+	}
+	return nil
+}
+
+func (s *serverConn) openSocket() (err error) {
+	var transport grpc.DialOption
+	if !s.isUnixSocket {
+		// before locking the connection, the owner locks it on config changes
+		if transport, err = s.owner.transportOption(); err != nil {
+			return err
+		}
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if s.isUnixSocket {
+		s.con, err = grpc.Dial(s.socketPath, grpc.WithInsecure(),
+			grpc.WithDialer(func(addr string, timeout time.Duration) (net.Conn, error) {
+				return net.DialTimeout("unix", addr, timeout)
+			}))
+	} else {
+		s.con, err = grpc.Dial(s.socketPath, transport)
+	}
+	if err == nil {
+		s.client = protocol.NewUIClient(s.con)
+	}
+
+	return err
+}
+
+func (s *serverConn) disconnect() {
+	s.Lock()
+	defer s.Unlock()
+
+	s.client = nil
+	if s.con != nil {
+		s.con.Close()
+		s.con = nil
+		log.Debug("client.disconnect(): %s", s.address)
+	}
+}
+
+// getClient returns the gRPC client of the connection, nil if it's not connected.
+func (s *serverConn) getClient() protocol.UIClient {
+	s.RLock()
+	defer s.RUnlock()
+	return s.client
+}
+
+func (s *serverConn) ping(ts time.Time) (err error) {
+	client := s.getClient()
+	if s.Connected() == false || client == nil {
+		return fmt.Errorf("service is not connected")
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, time.Second)
+	defer cancel()
+	reqID := uint64(ts.UnixNano())
+
+	stats := s.owner.stats
+	pReq := &protocol.PingRequest{
+		Id:    reqID,
+		Stats: stats.Serialize(),
+	}
+	stats.RLock()
+	pong, err := client.Ping(ctx, pReq)
+	stats.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	if pong.Id != reqID {
+		return fmt.Errorf("Expected pong with id 0x%x, got 0x%x", reqID, pong.Id)
+	}
+
+	return nil
+}
+
+// askRule sends a connection to the server, and waits for the rule to apply.
+func (s *serverConn) askRule(ctx context.Context, con *protocol.Connection) (*protocol.Rule, error) {
+	client := s.getClient()
+	if client == nil {
+		return nil, fmt.Errorf("service is not connected")
+	}
+	return client.AskRule(ctx, con)
+}
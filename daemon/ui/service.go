@@ -0,0 +1,212 @@
+package ui
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/evilsocket/opensnitch/daemon/core"
+	"github.com/evilsocket/opensnitch/daemon/history"
+	"github.com/evilsocket/opensnitch/daemon/log"
+	"github.com/evilsocket/opensnitch/daemon/ui/protocol"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+// Service is the gRPC service the daemon exposes, so it can be managed
+// without a GUI: it lists and changes the rules, returns the statistics,
+// streams the connections events and changes the configuration.
+//
+// The actions are applied to the rules and configuration of the system
+// client, the same way the notifications sent by the GUI are applied.
+type Service struct {
+	client       *Client
+	history      *history.DB
+	address      string
+	socketPath   string
+	isUnixSocket bool
+	// certificates of the service listening on a TCP address
+	tls      *tlsOptions
+	server   *grpc.Server
+	listener net.Listener
+}
+
+// replyCollector keeps the reply of a notification applied by the service,
+// instead of sending it to a stream.
+type replyCollector struct {
+	reply *protocol.NotificationReply
+}
+
+func (r *replyCollector) Send(reply *protocol.NotificationReply) error {
+	r.reply = reply
+	return nil
+}
+
+// NewService creates the service of the daemon, to be started with Serve().
+// The address is a unix socket (unix:///path/to/socket) or a TCP address
+// (host:port). The history is nil if the connections are not saved.
+func NewService(address string, client *Client, hist *history.DB) *Service {
+	s := &Service{
+		client:     client,
+		history:    hist,
+		address:    address,
+		socketPath: address,
+	}
+	if strings.HasPrefix(address, "unix://") == true {
+		s.isUnixSocket = true
+		s.socketPath = address[7:]
+	}
+	return s
+}
+
+// SetTLS sets the certificates of the service listening on a TCP address: the
+// certificate and key it presents, and the CA that must have signed the
+// certificates of the clients. A TCP address is refused without them.
+func (s *Service) SetTLS(caCert, cert, key string) {
+	s.tls = &tlsOptions{CACert: caCert, ClientCert: cert, ClientKey: key}
+}
+
+// Serve starts listening for requests.
+func (s *Service) Serve() (err error) {
+	opts := []grpc.ServerOption{}
+	if s.isUnixSocket {
+		// remove the socket of a previous run
+		if core.Exists(s.socketPath) {
+			os.Remove(s.socketPath)
+		}
+		if s.listener, err = net.Listen("unix", s.socketPath); err != nil {
+			return fmt.Errorf("Error listening on %s: %s", s.address, err)
+		}
+		// only root can manage the daemon
+		if err = os.Chmod(s.socketPath, 0600); err != nil {
+			s.listener.Close()
+			return fmt.Errorf("Error setting permissions of %s: %s", s.socketPath, err)
+		}
+	} else {
+		// anyone who can connect to the service can manage the daemon, so
+		// only the clients with a valid certificate are accepted
+		if s.tls == nil {
+			return fmt.Errorf("Refusing to listen on %s without TLS, set the CA of the clients, and the certificate and key of the service", s.address)
+		}
+		conf, err := s.tls.serverConfig()
+		if err != nil {
+			return fmt.Errorf("Error loading the certificates of %s: %s", s.address, err)
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(conf)))
+		if s.listener, err = net.Listen("tcp", s.socketPath); err != nil {
+			return fmt.Errorf("Error listening on %s: %s", s.address, err)
+		}
+	}
+
+	s.server = grpc.NewServer(opts...)
+	protocol.RegisterDaemonServer(s.server, s)
+
+	log.Info("Daemon service listening on %s", s.address)
+	go func() {
+		if err := s.server.Serve(s.listener); err != nil {
+			log.Warning("Daemon service %s stopped: %s", s.address, err)
+		}
+	}()
+	return nil
+}
+
+// Stop closes the service and the connections of its clients.
+func (s *Service) Stop() {
+	if s.server == nil {
+		return
+	}
+	s.server.Stop()
+	if s.isUnixSocket {
+		os.Remove(s.socketPath)
+	}
+}
+
+// GetRules returns the rules loaded in the Rules field of the reply, and the
+// configuration in the Data field.
+func (s *Service) GetRules(ctx context.Context, req *protocol.Notification) (*protocol.Notification, error) {
+	conf := s.client.getClientConfig()
+	return &protocol.Notification{
+		Id:         req.Id,
+		ServerName: conf.Name,
+		Type:       req.Type,
+		Data:       conf.Config,
+		Rules:      conf.Rules,
+	}, nil
+}
+
+// GetStatistics returns the statistics of the daemon.
+func (s *Service) GetStatistics(ctx context.Context, req *protocol.Notification) (*protocol.Statistics, error) {
+	return s.client.stats.Serialize(), nil
+}
+
+// Notify applies an action, the same way as the notifications sent by the GUI.
+func (s *Service) Notify(ctx context.Context, req *protocol.Notification) (*protocol.NotificationReply, error) {
+	switch req.Type {
+	case protocol.Action_MONITOR_PROCESS, protocol.Action_STOP_MONITOR_PROCESS:
+		// these replies are streamed, and there's no stream here
+		return NewReply(req.Id, protocol.NotificationReplyCode_ERROR, fmt.Sprintf("Action not supported: %s", req.Type)), nil
+	}
+
+	log.Info("[service] %s request from %s", req.Type, req.ClientName)
+	collector := &replyCollector{}
+	s.client.handleNotification(nil, collector, req)
+	if collector.reply == nil {
+		return NewReply(req.Id, protocol.NotificationReplyCode_ERROR, fmt.Sprintf("Unknown action: %s", req.Type)), nil
+	}
+	return collector.reply, nil
+}
+
+// StreamEvents sends the connections events as they're recorded, until the
+// client closes the stream.
+func (s *Service) StreamEvents(req *protocol.Notification, stream protocol.Daemon_StreamEventsServer) error {
+	events := s.client.stats.AddListener()
+	defer s.client.stats.RemoveListener(events)
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case ev := <-events:
+			if err := stream.Send(ev.Serialize()); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// QueryHistory sends the connections of the history matching the query,
+// newest first.
+func (s *Service) QueryHistory(req *protocol.HistoryQuery, stream protocol.Daemon_QueryHistoryServer) error {
+	if s.history == nil {
+		return status.Errorf(codes.Unavailable, "The connections history is not enabled")
+	}
+
+	q := history.Query{
+		Process: req.Process,
+		Host:    req.Host,
+		Rule:    req.Rule,
+		Limit:   int(req.Limit),
+	}
+	if req.From != 0 {
+		q.From = time.Unix(0, req.From)
+	}
+	if req.To != 0 {
+		q.To = time.Unix(0, req.To)
+	}
+
+	var sendErr error
+	err := s.history.Each(q, func(r *history.Record) bool {
+		sendErr = stream.Send(r.Serialize())
+		return sendErr == nil
+	})
+	if sendErr != nil {
+		return sendErr
+	}
+	return err
+}
@@ -0,0 +1,254 @@
+package ui
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/evilsocket/opensnitch/daemon/firewall"
+	"github.com/evilsocket/opensnitch/daemon/log"
+	"github.com/evilsocket/opensnitch/daemon/rule"
+	"github.com/evilsocket/opensnitch/daemon/statistics"
+)
+
+// UserSession holds the rules, statistics and GUI connection of a user.
+type UserSession struct {
+	UID    int
+	Rules  *rule.Loader
+	Stats  *statistics.Statistics
+	Client *Client
+}
+
+func (s *UserSession) close() {
+	s.Client.Close()
+	s.Rules.Close()
+	s.Stats.Close()
+}
+
+// userEntry is the session of a user, loaded once by the first connection.
+type userEntry struct {
+	once     sync.Once
+	session  *UserSession
+	lastUsed time.Time
+}
+
+var (
+	// the minimum uid of the regular users is read from it.
+	loginDefs = "/etc/login.defs"
+	// sessions idle for longer than it are closed, unless the GUI of the
+	// user is connected.
+	sessionTimeout = time.Hour
+	// how often the idle sessions are expired.
+	sessionsExpireInterval = time.Minute
+)
+
+const defaultUIDMin = 1000
+
+// Users manages the sessions of the users of the system, so every user can
+// have their own rules, and be asked about their own connections on their
+// own GUI.
+//
+// The rules of each user are saved to rulesPath/<uid>, which is only writable
+// by root, so the users can only change them from their GUI. The GUI of each
+// user listens on socketPath, where %d is replaced by the uid of the user.
+//
+// Only regular users (uid >= UID_MIN of /etc/login.defs) have a session, or
+// system users whose GUI is listening. Sessions idle for a while are closed.
+type Users struct {
+	sync.Mutex
+	rulesPath  string
+	socketPath string
+	liveReload bool
+	fw         firewall.Firewall
+	uidMin     int
+	sessions   map[int]*userEntry
+	quit       chan bool
+}
+
+// NewUsers creates the manager of the users sessions.
+func NewUsers(rulesPath, socketPath string, liveReload bool, fw firewall.Firewall) (*Users, error) {
+	if err := os.MkdirAll(rulesPath, 0755); err != nil {
+		return nil, fmt.Errorf("Error creating users rules path %s: %s", rulesPath, err)
+	}
+	u := &Users{
+		rulesPath:  rulesPath,
+		socketPath: socketPath,
+		liveReload: liveReload,
+		fw:         fw,
+		uidMin:     readUIDMin(loginDefs),
+		sessions:   make(map[int]*userEntry),
+		quit:       make(chan bool),
+	}
+	go u.expireWorker()
+	return u, nil
+}
+
+// readUIDMin returns the minimum uid of the regular users, as defined
+// by UID_MIN in the given login.defs file.
+func readUIDMin(path string) int {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return defaultUIDMin
+	}
+	for _, line := range strings.Split(string(raw), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || fields[0] != "UID_MIN" {
+			continue
+		}
+		if uid, err := strconv.Atoi(fields[1]); err == nil && uid > 0 {
+			return uid
+		}
+	}
+	return defaultUIDMin
+}
+
+// userSocketPath returns the address of the GUI of a user.
+func (u *Users) userSocketPath(uid int) string {
+	if strings.Contains(u.socketPath, "%d") {
+		return fmt.Sprintf(u.socketPath, uid)
+	}
+	return u.socketPath
+}
+
+// hasSession returns true if the user may have a session: regular users,
+// and system users whose GUI is listening.
+func (u *Users) hasSession(uid int) bool {
+	if uid >= u.uidMin {
+		return true
+	}
+	socketPath := strings.TrimPrefix(u.userSocketPath(uid), "unix://")
+	return checkOwner(socketPath, uid) == nil
+}
+
+// Get returns the session of a user, loading their rules and connecting to
+// their GUI the first time. The connections of root, or of system users,
+// only use the system rules and GUI, so nil is returned for them.
+func (u *Users) Get(uid int) *UserSession {
+	if uid <= 0 {
+		return nil
+	}
+
+	u.Lock()
+	e, found := u.sessions[uid]
+	if !found {
+		if !u.hasSession(uid) {
+			u.Unlock()
+			return nil
+		}
+		e = &userEntry{}
+		u.sessions[uid] = e
+	}
+	e.lastUsed = time.Now()
+	u.Unlock()
+
+	// loaded without the lock, so the connections of other users are not
+	// blocked meanwhile.
+	e.once.Do(func() {
+		s, err := u.newSession(uid)
+		if err != nil {
+			// on error, don't try again on every connection of the user
+			log.Error("Error loading the session of the user %d: %s", uid, err)
+			return
+		}
+		e.session = s
+	})
+	return e.session
+}
+
+func (u *Users) newSession(uid int) (*UserSession, error) {
+	path := filepath.Join(u.rulesPath, fmt.Sprint(uid))
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return nil, err
+	}
+	rules, err := rule.NewLoader(u.liveReload)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Loading rules of the user %d from %s ...", uid, path)
+	if err := rules.Load(path); err != nil {
+		rules.Close()
+		return nil, err
+	}
+	stats := statistics.New(rules)
+
+	return &UserSession{
+		UID:    uid,
+		Rules:  rules,
+		Stats:  stats,
+		Client: newUserClient(uid, u.userSocketPath(uid), stats, rules, u.fw),
+	}, nil
+}
+
+func (u *Users) expireWorker() {
+	t := time.NewTicker(sessionsExpireInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-u.quit:
+			return
+		case now := <-t.C:
+			u.expire(now)
+		}
+	}
+}
+
+// expire closes the sessions idle for longer than sessionTimeout, whose
+// GUI is not connected.
+func (u *Users) expire(now time.Time) {
+	var expired []*UserSession
+
+	u.Lock()
+	for uid, e := range u.sessions {
+		if now.Sub(e.lastUsed) < sessionTimeout {
+			continue
+		}
+		// a session being loaded is never idle, so it's already loaded
+		if e.session != nil && e.session.Client.Connected() {
+			continue
+		}
+		delete(u.sessions, uid)
+		if e.session != nil {
+			expired = append(expired, e.session)
+		}
+	}
+	u.Unlock()
+
+	for _, s := range expired {
+		log.Debug("Closing the idle session of the user %d", s.UID)
+		s.close()
+	}
+}
+
+// Close disconnects from the GUIs of the users.
+func (u *Users) Close() {
+	close(u.quit)
+
+	u.Lock()
+	defer u.Unlock()
+
+	for _, e := range u.sessions {
+		if e.session != nil {
+			e.session.close()
+		}
+	}
+}
+
+// checkOwner verifies that a path is owned by the given user, so other users
+// can't impersonate their GUI.
+func checkOwner(path string, uid int) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || int(st.Uid) != uid {
+		return fmt.Errorf("%s is not owned by the user %d", path, uid)
+	}
+	return nil
+}
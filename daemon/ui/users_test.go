@@ -0,0 +1,87 @@
+package ui
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReadUIDMin(t *testing.T) {
+	dir, err := ioutil.TempDir("", "opensnitch-users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "login.defs")
+	if err := ioutil.WriteFile(path, []byte("# UID_MIN 10\nUID_MIN\t\t 500\nUID_MAX 60000\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if uid := readUIDMin(path); uid != 500 {
+		t.Error("UID_MIN not read:", uid)
+	}
+	if uid := readUIDMin(filepath.Join(dir, "missing")); uid != defaultUIDMin {
+		t.Error("Default UID_MIN not used:", uid)
+	}
+}
+
+func TestUsersSessions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "opensnitch-users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	loginDefs = filepath.Join(dir, "login.defs")
+	defer func() { loginDefs = "/etc/login.defs" }()
+	if err := ioutil.WriteFile(loginDefs, []byte("UID_MIN 2000\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	socketPath := "unix://" + filepath.Join(dir, "gui-%d.sock")
+	users, err := NewUsers(filepath.Join(dir, "rules"), socketPath, false, nil)
+	if err != nil {
+		t.Fatal("Error creating users:", err)
+	}
+	defer users.Close()
+
+	if users.Get(0) != nil || users.Get(1500) != nil {
+		t.Error("Session created for a system user")
+	}
+	s := users.Get(2500)
+	if s == nil {
+		t.Fatal("Session not created for a regular user")
+	}
+	if users.Get(2500) != s {
+		t.Error("Session not reused")
+	}
+
+	// system users have a session if their GUI is listening
+	if os.Getuid() == 0 {
+		sock := filepath.Join(dir, "gui-1500.sock")
+		if err := ioutil.WriteFile(sock, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chown(sock, 1500, 1500); err != nil {
+			t.Fatal(err)
+		}
+		if users.Get(1500) == nil {
+			t.Error("Session not created for a system user with a GUI")
+		}
+	}
+
+	// only the idle sessions are expired
+	users.expire(time.Now())
+	if len(users.sessions) == 0 {
+		t.Error("Sessions in use expired")
+	}
+	users.expire(time.Now().Add(sessionTimeout))
+	if len(users.sessions) != 0 {
+		t.Error("Idle sessions not expired:", len(users.sessions))
+	}
+	if n := users.Get(2500); n == nil || n == s {
+		t.Error("Session not created again after expiring")
+	}
+}
@@ -111,6 +111,13 @@ class Database:
         q.exec_()
         q = QSqlQuery("create table if not exists users (what text primary key, hits integer)", self.db)
         q.exec_()
+        q = QSqlQuery("create table if not exists traffic (" \
+                "what text primary key, " \
+                "connections integer, " \
+                "bytes_in integer, " \
+                "bytes_out integer, " \
+                "duration integer)", self.db)
+        q.exec_()
         q = QSqlQuery("create table if not exists nodes (" \
                 "addr text primary key," \
                 "hostname text," \
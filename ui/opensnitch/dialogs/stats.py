@@ -175,7 +175,10 @@ class StatsDialog(QtWidgets.QDialog, uic.loadUiType(DIALOG_UI_PATH)[0]):
                 "filterLine": None,
                 "model": None,
                 "delegate": commonDelegateConf,
-                "display_fields": "*",
+                # the traffic is only sent by the daemons with -traffic-accounting
+                "display_fields": "what, hits, " \
+                    "(SELECT t.bytes_in FROM traffic as t WHERE t.what = procs.what) as bytes_in, " \
+                    "(SELECT t.bytes_out FROM traffic as t WHERE t.what = procs.what) as bytes_out",
                 "last_order_by": "2",
                 "last_order_to": 1
                 },
@@ -324,6 +327,7 @@ class StatsDialog(QtWidgets.QDialog, uic.loadUiType(DIALOG_UI_PATH)[0]):
                 order_by="2")
         self.TABLES[self.TAB_PROCS]['view'] = self._setup_table(QtWidgets.QTableView,
                 self.procsTable, "procs",
+                fields=self.TABLES[self.TAB_PROCS]['display_fields'],
                 resize_cols=(self.COL_WHAT,),
                 delegate=self.TABLES[self.TAB_PROCS]['delegate'],
                 order_by="2")
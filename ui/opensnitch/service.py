@@ -68,7 +68,8 @@ class UIService(ui_pb2_grpc.UIServicer, QtWidgets.QGraphicsObject):
                 'procs':{},
                 'addrs':{},
                 'ports':{},
-                'users':{}
+                'users':{},
+                'traffic':{}
                 }
 
     # https://gist.github.com/pklaus/289646
@@ -352,6 +353,32 @@ class UIService(ui_pb2_grpc.UIServicer, QtWidgets.QGraphicsObject):
         if changed: need_refresh = True
         changed = self._populate_stats_events(db, addr, stats, "users", ("what", "hits"), (1,2), stats.by_uid.items())
         if changed: need_refresh = True
+        changed = self._populate_stats_traffic(db, addr, stats.traffic_by_executable.items())
+        if changed: need_refresh = True
+
+        return need_refresh
+
+    def _populate_stats_traffic(self, db, addr, items):
+        """
+        Saves the data transferred by the processes with the most traffic.
+        The daemon only sends it with the option -traffic-accounting.
+        """
+        need_refresh = False
+        try:
+            last = self._last_items['traffic'].get(addr, {})
+            current = {}
+            for what, traffic in items:
+                row = (traffic.connections, traffic.bytes_in, traffic.bytes_out, traffic.duration)
+                current[what] = row
+                if last.get(what) == row:
+                    continue
+                need_refresh = True
+                db.insert("traffic",
+                        "(what, connections, bytes_in, bytes_out, duration)",
+                        (what,) + row)
+            self._last_items['traffic'][addr] = current
+        except Exception as e:
+            print("traffic exception: ", e)
 
         return need_refresh
 
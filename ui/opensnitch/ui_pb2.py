@@ -20,7 +20,7 @@ DESCRIPTOR = _descriptor.FileDescriptor(
   name='ui.proto',
   package='protocol',
   syntax='proto3',
-  serialized_pb=_b('\n\x08ui.proto\x12\x08protocol\"o\n\x05\x45vent\x12\x0c\n\x04time\x18\x01 \x01(\t\x12(\n\nconnection\x18\x02 \x01(\x0b\x32\x14.protocol.Connection\x12\x1c\n\x04rule\x18\x03 \x01(\x0b\x32\x0e.protocol.Rule\x12\x10\n\x08unixnano\x18\x04 \x01(\x03\"\xd3\x06\n\nStatistics\x12\x16\n\x0e\x64\x61\x65mon_version\x18\x01 \x01(\t\x12\r\n\x05rules\x18\x02 \x01(\x04\x12\x0e\n\x06uptime\x18\x03 \x01(\x04\x12\x15\n\rdns_responses\x18\x04 \x01(\x04\x12\x13\n\x0b\x63onnections\x18\x05 \x01(\x04\x12\x0f\n\x07ignored\x18\x06 \x01(\x04\x12\x10\n\x08\x61\x63\x63\x65pted\x18\x07 \x01(\x04\x12\x0f\n\x07\x64ropped\x18\x08 \x01(\x04\x12\x11\n\trule_hits\x18\t \x01(\x04\x12\x13\n\x0brule_misses\x18\n \x01(\x04\x12\x33\n\x08\x62y_proto\x18\x0b \x03(\x0b\x32!.protocol.Statistics.ByProtoEntry\x12\x37\n\nby_address\x18\x0c \x03(\x0b\x32#.protocol.Statistics.ByAddressEntry\x12\x31\n\x07\x62y_host\x18\r \x03(\x0b\x32 .protocol.Statistics.ByHostEntry\x12\x31\n\x07\x62y_port\x18\x0e \x03(\x0b\x32 .protocol.Statistics.ByPortEntry\x12/\n\x06\x62y_uid\x18\x0f \x03(\x0b\x32\x1f.protocol.Statistics.ByUidEntry\x12=\n\rby_executable\x18\x10 \x03(\x0b\x32&.protocol.Statistics.ByExecutableEntry\x12\x1f\n\x06\x65vents\x18\x11 \x03(\x0b\x32\x0f.protocol.Event\x1a.\n\x0c\x42yProtoEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\x04:\x02\x38\x01\x1a\x30\n\x0e\x42yAddressEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\x04:\x02\x38\x01\x1a-\n\x0b\x42yHostEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\x04:\x02\x38\x01\x1a-\n\x0b\x42yPortEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\x04:\x02\x38\x01\x1a,\n\nByUidEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\x04:\x02\x38\x01\x1a\x33\n\x11\x42yExecutableEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\x04:\x02\x38\x01\">\n\x0bPingRequest\x12\n\n\x02id\x18\x01 \x01(\x04\x12#\n\x05stats\x18\x02 \x01(\x0b\x32\x14.protocol.Statistics\"\x17\n\tPingReply\x12\n\n\x02id\x18\x01 \x01(\x04\"\xc8\x02\n\nConnection\x12\x10\n\x08protocol\x18\x01 \x01(\t\x12\x0e\n\x06src_ip\x18\x02 \x01(\t\x12\x10\n\x08src_port\x18\x03 \x01(\r\x12\x0e\n\x06\x64st_ip\x18\x04 \x01(\t\x12\x10\n\x08\x64st_host\x18\x05 \x01(\t\x12\x10\n\x08\x64st_port\x18\x06 \x01(\r\x12\x0f\n\x07user_id\x18\x07 \x01(\r\x12\x12\n\nprocess_id\x18\x08 \x01(\r\x12\x14\n\x0cprocess_path\x18\t \x01(\t\x12\x13\n\x0bprocess_cwd\x18\n \x01(\t\x12\x14\n\x0cprocess_args\x18\x0b \x03(\t\x12\x39\n\x0bprocess_env\x18\x0c \x03(\x0b\x32$.protocol.Connection.ProcessEnvEntry\x1a\x31\n\x0fProcessEnvEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\t:\x02\x38\x01\"J\n\x08Operator\x12\x0c\n\x04type\x18\x01 \x01(\t\x12\x0f\n\x07operand\x18\x02 \x01(\t\x12\x0c\n\x04\x64\x61ta\x18\x03 \x01(\t\x12\x11\n\tsensitive\x18\x04 \x01(\x08\"\x81\x01\n\x04Rule\x12\x0c\n\x04name\x18\x01 \x01(\t\x12\x0f\n\x07\x65nabled\x18\x02 \x01(\x08\x12\x12\n\nprecedence\x18\x03 \x01(\x08\x12\x0e\n\x06\x61\x63tion\x18\x04 \x01(\t\x12\x10\n\x08\x64uration\x18\x05 \x01(\t\x12$\n\x08operator\x18\x06 \x01(\x0b\x32\x12.protocol.Operator\"\x95\x01\n\x0c\x43lientConfig\x12\n\n\x02id\x18\x01 \x01(\x04\x12\x0c\n\x04name\x18\x02 \x01(\t\x12\x0f\n\x07version\x18\x03 \x01(\t\x12\x19\n\x11isFirewallRunning\x18\x04 \x01(\x08\x12\x0e\n\x06\x63onfig\x18\x05 \x01(\t\x12\x10\n\x08logLevel\x18\x06 \x01(\r\x12\x1d\n\x05rules\x18\x07 \x03(\x0b\x32\x0e.protocol.Rule\"\x8f\x01\n\x0cNotification\x12\n\n\x02id\x18\x01 \x01(\x04\x12\x12\n\nclientName\x18\x02 \x01(\t\x12\x12\n\nserverName\x18\x03 \x01(\t\x12\x1e\n\x04type\x18\x04 \x01(\x0e\x32\x10.protocol.Action\x12\x0c\n\x04\x64\x61ta\x18\x05 \x01(\t\x12\x1d\n\x05rules\x18\x06 \x03(\x0b\x32\x0e.protocol.Rule\"\\\n\x11NotificationReply\x12\n\n\x02id\x18\x01 \x01(\x04\x12-\n\x04\x63ode\x18\x02 \x01(\x0e\x32\x1f.protocol.NotificationReplyCode\x12\x0c\n\x04\x64\x61ta\x18\x03 \x01(\t*\xda\x01\n\x06\x41\x63tion\x12\x08\n\x04NONE\x10\x00\x12\x11\n\rLOAD_FIREWALL\x10\x01\x12\x13\n\x0fUNLOAD_FIREWALL\x10\x02\x12\x11\n\rCHANGE_CONFIG\x10\x03\x12\x0f\n\x0b\x45NABLE_RULE\x10\x04\x12\x10\n\x0c\x44ISABLE_RULE\x10\x05\x12\x0f\n\x0b\x44\x45LETE_RULE\x10\x06\x12\x0f\n\x0b\x43HANGE_RULE\x10\x07\x12\r\n\tLOG_LEVEL\x10\x08\x12\x08\n\x04STOP\x10\t\x12\x13\n\x0fMONITOR_PROCESS\x10\n\x12\x18\n\x14STOP_MONITOR_PROCESS\x10\x0b**\n\x15NotificationReplyCode\x12\x06\n\x02OK\x10\x00\x12\t\n\x05\x45RROR\x10\x01\x32\xf8\x01\n\x02UI\x12\x34\n\x04Ping\x12\x15.protocol.PingRequest\x1a\x13.protocol.PingReply\"\x00\x12\x31\n\x07\x41skRule\x12\x14.protocol.Connection\x1a\x0e.protocol.Rule\"\x00\x12=\n\tSubscribe\x12\x16.protocol.ClientConfig\x1a\x16.protocol.ClientConfig\"\x00\x12J\n\rNotifications\x12\x1b.protocol.NotificationReply\x1a\x16.protocol.Notification\"\x00(\x01\x30\x01\x62\x06proto3')
+  serialized_pb=_b('\n\x08ui.proto\x12\x08protocol\"o\n\x05\x45vent\x12\x0c\n\x04time\x18\x01 \x01(\t\x12(\n\nconnection\x18\x02 \x01(\x0b\x32\x14.protocol.Connection\x12\x1c\n\x04rule\x18\x03 \x01(\x0b\x32\x0e.protocol.Rule\x12\x10\n\x08unixnano\x18\x04 \x01(\x03\"\x8d\t\n\nStatistics\x12\x16\n\x0e\x64\x61\x65mon_version\x18\x01 \x01(\t\x12\r\n\x05rules\x18\x02 \x01(\x04\x12\x0e\n\x06uptime\x18\x03 \x01(\x04\x12\x15\n\rdns_responses\x18\x04 \x01(\x04\x12\x13\n\x0b\x63onnections\x18\x05 \x01(\x04\x12\x0f\n\x07ignored\x18\x06 \x01(\x04\x12\x10\n\x08\x61\x63\x63\x65pted\x18\x07 \x01(\x04\x12\x0f\n\x07\x64ropped\x18\x08 \x01(\x04\x12\x11\n\trule_hits\x18\t \x01(\x04\x12\x13\n\x0brule_misses\x18\n \x01(\x04\x12\x33\n\x08\x62y_proto\x18\x0b \x03(\x0b\x32!.protocol.Statistics.ByProtoEntry\x12\x37\n\nby_address\x18\x0c \x03(\x0b\x32#.protocol.Statistics.ByAddressEntry\x12\x31\n\x07\x62y_host\x18\r \x03(\x0b\x32 .protocol.Statistics.ByHostEntry\x12\x31\n\x07\x62y_port\x18\x0e \x03(\x0b\x32 .protocol.Statistics.ByPortEntry\x12/\n\x06\x62y_uid\x18\x0f \x03(\x0b\x32\x1f.protocol.Statistics.ByUidEntry\x12=\n\rby_executable\x18\x10 \x03(\x0b\x32&.protocol.Statistics.ByExecutableEntry\x12\x1f\n\x06\x65vents\x18\x11 \x03(\x0b\x32\x0f.protocol.Event\x12\x10\n\x08requeued\x18\x12 \x01(\x04\x12L\n\x15traffic_by_executable\x18\x13 \x03(\x0b\x32-.protocol.Statistics.TrafficByExecutableEntry\x12@\n\x0ftraffic_by_rule\x18\x14 \x03(\x0b\x32\'.protocol.Statistics.TrafficByRuleEntry\x1a.\n\x0c\x42yProtoEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\x04:\x02\x38\x01\x1a\x30\n\x0e\x42yAddressEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\x04:\x02\x38\x01\x1a-\n\x0b\x42yHostEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\x04:\x02\x38\x01\x1a-\n\x0b\x42yPortEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\x04:\x02\x38\x01\x1a,\n\nByUidEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\x04:\x02\x38\x01\x1a\x33\n\x11\x42yExecutableEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\x04:\x02\x38\x01\x1aM\n\x18TrafficByExecutableEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12 \n\x05value\x18\x02 \x01(\x0b\x32\x11.protocol.Traffic:\x02\x38\x01\x1aG\n\x12TrafficByRuleEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12 \n\x05value\x18\x02 \x01(\x0b\x32\x11.protocol.Traffic:\x02\x38\x01\">\n\x0bPingRequest\x12\n\n\x02id\x18\x01 \x01(\x04\x12#\n\x05stats\x18\x02 \x01(\x0b\x32\x14.protocol.Statistics\"\x17\n\tPingReply\x12\n\n\x02id\x18\x01 \x01(\x04\"\xf7\x03\n\nConnection\x12\x10\n\x08protocol\x18\x01 \x01(\t\x12\x0e\n\x06src_ip\x18\x02 \x01(\t\x12\x10\n\x08src_port\x18\x03 \x01(\r\x12\x0e\n\x06\x64st_ip\x18\x04 \x01(\t\x12\x10\n\x08\x64st_host\x18\x05 \x01(\t\x12\x10\n\x08\x64st_port\x18\x06 \x01(\r\x12\x0f\n\x07user_id\x18\x07 \x01(\r\x12\x12\n\nprocess_id\x18\x08 \x01(\r\x12\x14\n\x0cprocess_path\x18\t \x01(\t\x12\x13\n\x0bprocess_cwd\x18\n \x01(\t\x12\x14\n\x0cprocess_args\x18\x0b \x03(\t\x12\x39\n\x0bprocess_env\x18\x0c \x03(\x0b\x32$.protocol.Connection.ProcessEnvEntry\x12\x45\n\x11process_checksums\x18\r \x03(\x0b\x32*.protocol.Connection.ProcessChecksumsEntry\x12-\n\x0cprocess_tree\x18\x0e \x03(\x0b\x32\x17.protocol.ProcessParent\x1a\x31\n\x0fProcessEnvEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\t:\x02\x38\x01\x1a\x37\n\x15ProcessChecksumsEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\t:\x02\x38\x01\"l\n\x08Operator\x12\x0c\n\x04type\x18\x01 \x01(\t\x12\x0f\n\x07operand\x18\x02 \x01(\t\x12\x0c\n\x04\x64\x61ta\x18\x03 \x01(\t\x12\x11\n\tsensitive\x18\x04 \x01(\x08\x12 \n\x04list\x18\x05 \x03(\x0b\x32\x12.protocol.Operator\"\xf3\x01\n\x04Rule\x12\x0c\n\x04name\x18\x01 \x01(\t\x12\x0f\n\x07\x65nabled\x18\x02 \x01(\x08\x12\x12\n\nprecedence\x18\x03 \x01(\x08\x12\x0e\n\x06\x61\x63tion\x18\x04 \x01(\t\x12\x10\n\x08\x64uration\x18\x05 \x01(\t\x12$\n\x08operator\x18\x06 \x01(\x0b\x32\x12.protocol.Operator\x12\x10\n\x08priority\x18\x07 \x01(\x05\x12\x10\n\x08schedule\x18\x08 \x01(\t\x12\"\n\x05limit\x18\t \x01(\x0b\x32\x13.protocol.RuleLimit\x12(\n\x08redirect\x18\n \x01(\x0b\x32\x16.protocol.RuleRedirect\"\x95\x01\n\x0c\x43lientConfig\x12\n\n\x02id\x18\x01 \x01(\x04\x12\x0c\n\x04name\x18\x02 \x01(\t\x12\x0f\n\x07version\x18\x03 \x01(\t\x12\x19\n\x11isFirewallRunning\x18\x04 \x01(\x08\x12\x0e\n\x06\x63onfig\x18\x05 \x01(\t\x12\x10\n\x08logLevel\x18\x06 \x01(\r\x12\x1d\n\x05rules\x18\x07 \x03(\x0b\x32\x0e.protocol.Rule\"\x8f\x01\n\x0cNotification\x12\n\n\x02id\x18\x01 \x01(\x04\x12\x12\n\nclientName\x18\x02 \x01(\t\x12\x12\n\nserverName\x18\x03 \x01(\t\x12\x1e\n\x04type\x18\x04 \x01(\x0e\x32\x10.protocol.Action\x12\x0c\n\x04\x64\x61ta\x18\x05 \x01(\t\x12\x1d\n\x05rules\x18\x06 \x03(\x0b\x32\x0e.protocol.Rule\"\\\n\x11NotificationReply\x12\n\n\x02id\x18\x01 \x01(\x04\x12-\n\x04\x63ode\x18\x02 \x01(\x0e\x32\x1f.protocol.NotificationReplyCode\x12\x0c\n\x04\x64\x61ta\x18\x03 \x01(\t\"*\n\rProcessParent\x12\x0b\n\x03pid\x18\x01 \x01(\r\x12\x0c\n\x04path\x18\x02 \x01(\t\"[\n\tRuleLimit\x12\x13\n\x0b\x63onnections\x18\x01 \x01(\r\x12\x10\n\x08interval\x18\x02 \x01(\t\x12\x15\n\rbytes_per_day\x18\x03 \x01(\x04\x12\x10\n\x08\x65xceeded\x18\x04 \x01(\t\"+\n\x0cRuleRedirect\x12\r\n\x05queue\x18\x01 \x01(\r\x12\x0c\n\x04mark\x18\x02 \x01(\r\"d\n\x0cHistoryQuery\x12\x0f\n\x07process\x18\x01 \x01(\t\x12\x0c\n\x04host\x18\x02 \x01(\t\x12\x0c\n\x04rule\x18\x03 \x01(\t\x12\x0c\n\x04\x66rom\x18\x04 \x01(\x03\x12\n\n\x02to\x18\x05 \x01(\x03\x12\r\n\x05limit\x18\x06 \x01(\r\"U\n\x07Traffic\x12\x13\n\x0b\x63onnections\x18\x01 \x01(\x04\x12\x10\n\x08\x62ytes_in\x18\x02 \x01(\x04\x12\x11\n\tbytes_out\x18\x03 \x01(\x04\x12\x10\n\x08\x64uration\x18\x04 \x01(\x04*\xda\x01\n\x06\x41\x63tion\x12\x08\n\x04NONE\x10\x00\x12\x11\n\rLOAD_FIREWALL\x10\x01\x12\x13\n\x0fUNLOAD_FIREWALL\x10\x02\x12\x11\n\rCHANGE_CONFIG\x10\x03\x12\x0f\n\x0b\x45NABLE_RULE\x10\x04\x12\x10\n\x0c\x44ISABLE_RULE\x10\x05\x12\x0f\n\x0b\x44\x45LETE_RULE\x10\x06\x12\x0f\n\x0b\x43HANGE_RULE\x10\x07\x12\r\n\tLOG_LEVEL\x10\x08\x12\x08\n\x04STOP\x10\t\x12\x13\n\x0fMONITOR_PROCESS\x10\n\x12\x18\n\x14STOP_MONITOR_PROCESS\x10\x0b**\n\x15NotificationReplyCode\x12\x06\n\x02OK\x10\x00\x12\t\n\x05\x45RROR\x10\x01\x32\xf8\x01\n\x02UI\x12\x34\n\x04Ping\x12\x15.protocol.PingRequest\x1a\x13.protocol.PingReply\"\x00\x12\x31\n\x07\x41skRule\x12\x14.protocol.Connection\x1a\x0e.protocol.Rule\"\x00\x12=\n\tSubscribe\x12\x16.protocol.ClientConfig\x1a\x16.protocol.ClientConfig\"\x00\x12J\n\rNotifications\x12\x1b.protocol.NotificationReply\x1a\x16.protocol.Notification\"\x00(\x01\x30\x01\x32\xc2\x02\n\x06\x44\x61\x65mon\x12<\n\x08GetRules\x12\x16.protocol.Notification\x1a\x16.protocol.Notification\"\x00\x12?\n\rGetStatistics\x12\x16.protocol.Notification\x1a\x14.protocol.Statistics\"\x00\x12?\n\x06Notify\x12\x16.protocol.Notification\x1a\x1b.protocol.NotificationReply\"\x00\x12;\n\x0cStreamEvents\x12\x16.protocol.Notification\x1a\x0f.protocol.Event\"\x00\x30\x01\x12;\n\x0cQueryHistory\x12\x16.protocol.HistoryQuery\x1a\x0f.protocol.Event\"\x00\x30\x01\x62\x06proto3')
 )
 
 _ACTION = _descriptor.EnumDescriptor(
@@ -80,8 +80,8 @@ _ACTION = _descriptor.EnumDescriptor(
   ],
   containing_type=None,
   options=None,
-  serialized_start=2010,
-  serialized_end=2228,
+  serialized_start=3018,
+  serialized_end=3236,
 )
 _sym_db.RegisterEnumDescriptor(_ACTION)
 
@@ -103,8 +103,8 @@ _NOTIFICATIONREPLYCODE = _descriptor.EnumDescriptor(
   ],
   containing_type=None,
   options=None,
-  serialized_start=2230,
-  serialized_end=2272,
+  serialized_start=3238,
+  serialized_end=3280,
 )
 _sym_db.RegisterEnumDescriptor(_NOTIFICATIONREPLYCODE)
 
@@ -211,8 +211,8 @@ _STATISTICS_BYPROTOENTRY = _descriptor.Descriptor(
   extension_ranges=[],
   oneofs=[
   ],
-  serialized_start=698,
-  serialized_end=744,
+  serialized_start=860,
+  serialized_end=906,
 )
 
 _STATISTICS_BYADDRESSENTRY = _descriptor.Descriptor(
@@ -248,8 +248,8 @@ _STATISTICS_BYADDRESSENTRY = _descriptor.Descriptor(
   extension_ranges=[],
   oneofs=[
   ],
-  serialized_start=746,
-  serialized_end=794,
+  serialized_start=908,
+  serialized_end=956,
 )
 
 _STATISTICS_BYHOSTENTRY = _descriptor.Descriptor(
@@ -285,8 +285,8 @@ _STATISTICS_BYHOSTENTRY = _descriptor.Descriptor(
   extension_ranges=[],
   oneofs=[
   ],
-  serialized_start=796,
-  serialized_end=841,
+  serialized_start=958,
+  serialized_end=1003,
 )
 
 _STATISTICS_BYPORTENTRY = _descriptor.Descriptor(
@@ -322,8 +322,8 @@ _STATISTICS_BYPORTENTRY = _descriptor.Descriptor(
   extension_ranges=[],
   oneofs=[
   ],
-  serialized_start=843,
-  serialized_end=888,
+  serialized_start=1005,
+  serialized_end=1050,
 )
 
 _STATISTICS_BYUIDENTRY = _descriptor.Descriptor(
@@ -359,8 +359,8 @@ _STATISTICS_BYUIDENTRY = _descriptor.Descriptor(
   extension_ranges=[],
   oneofs=[
   ],
-  serialized_start=890,
-  serialized_end=934,
+  serialized_start=1052,
+  serialized_end=1096,
 )
 
 _STATISTICS_BYEXECUTABLEENTRY = _descriptor.Descriptor(
@@ -396,8 +396,82 @@ _STATISTICS_BYEXECUTABLEENTRY = _descriptor.Descriptor(
   extension_ranges=[],
   oneofs=[
   ],
-  serialized_start=936,
-  serialized_end=987,
+  serialized_start=1098,
+  serialized_end=1149,
+)
+
+_STATISTICS_TRAFFICBYEXECUTABLEENTRY = _descriptor.Descriptor(
+  name='TrafficByExecutableEntry',
+  full_name='protocol.Statistics.TrafficByExecutableEntry',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    _descriptor.FieldDescriptor(
+      name='key', full_name='protocol.Statistics.TrafficByExecutableEntry.key', index=0,
+      number=1, type=9, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b("").decode('utf-8'),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None, file=DESCRIPTOR),
+    _descriptor.FieldDescriptor(
+      name='value', full_name='protocol.Statistics.TrafficByExecutableEntry.value', index=1,
+      number=2, type=11, cpp_type=10, label=1,
+      has_default_value=False, default_value=None,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None, file=DESCRIPTOR),
+  ],
+  extensions=[
+  ],
+  nested_types=[],
+  enum_types=[
+  ],
+  options=_descriptor._ParseOptions(descriptor_pb2.MessageOptions(), _b('8\001')),
+  is_extendable=False,
+  syntax='proto3',
+  extension_ranges=[],
+  oneofs=[
+  ],
+  serialized_start=1151,
+  serialized_end=1228,
+)
+
+_STATISTICS_TRAFFICBYRULEENTRY = _descriptor.Descriptor(
+  name='TrafficByRuleEntry',
+  full_name='protocol.Statistics.TrafficByRuleEntry',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    _descriptor.FieldDescriptor(
+      name='key', full_name='protocol.Statistics.TrafficByRuleEntry.key', index=0,
+      number=1, type=9, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b("").decode('utf-8'),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None, file=DESCRIPTOR),
+    _descriptor.FieldDescriptor(
+      name='value', full_name='protocol.Statistics.TrafficByRuleEntry.value', index=1,
+      number=2, type=11, cpp_type=10, label=1,
+      has_default_value=False, default_value=None,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None, file=DESCRIPTOR),
+  ],
+  extensions=[
+  ],
+  nested_types=[],
+  enum_types=[
+  ],
+  options=_descriptor._ParseOptions(descriptor_pb2.MessageOptions(), _b('8\001')),
+  is_extendable=False,
+  syntax='proto3',
+  extension_ranges=[],
+  oneofs=[
+  ],
+  serialized_start=1230,
+  serialized_end=1301,
 )
 
 _STATISTICS = _descriptor.Descriptor(
@@ -526,10 +600,31 @@ _STATISTICS = _descriptor.Descriptor(
       message_type=None, enum_type=None, containing_type=None,
       is_extension=False, extension_scope=None,
       options=None, file=DESCRIPTOR),
+    _descriptor.FieldDescriptor(
+      name='requeued', full_name='protocol.Statistics.requeued', index=17,
+      number=18, type=4, cpp_type=4, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None, file=DESCRIPTOR),
+    _descriptor.FieldDescriptor(
+      name='traffic_by_executable', full_name='protocol.Statistics.traffic_by_executable', index=18,
+      number=19, type=11, cpp_type=10, label=3,
+      has_default_value=False, default_value=[],
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None, file=DESCRIPTOR),
+    _descriptor.FieldDescriptor(
+      name='traffic_by_rule', full_name='protocol.Statistics.traffic_by_rule', index=19,
+      number=20, type=11, cpp_type=10, label=3,
+      has_default_value=False, default_value=[],
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None, file=DESCRIPTOR),
   ],
   extensions=[
   ],
-  nested_types=[_STATISTICS_BYPROTOENTRY, _STATISTICS_BYADDRESSENTRY, _STATISTICS_BYHOSTENTRY, _STATISTICS_BYPORTENTRY, _STATISTICS_BYUIDENTRY, _STATISTICS_BYEXECUTABLEENTRY, ],
+  nested_types=[_STATISTICS_BYPROTOENTRY, _STATISTICS_BYADDRESSENTRY, _STATISTICS_BYHOSTENTRY, _STATISTICS_BYPORTENTRY, _STATISTICS_BYUIDENTRY, _STATISTICS_BYEXECUTABLEENTRY, _STATISTICS_TRAFFICBYEXECUTABLEENTRY, _STATISTICS_TRAFFICBYRULEENTRY, ],
   enum_types=[
   ],
   options=None,
@@ -539,7 +634,7 @@ _STATISTICS = _descriptor.Descriptor(
   oneofs=[
   ],
   serialized_start=136,
-  serialized_end=987,
+  serialized_end=1301,
 )
 
 
@@ -576,8 +671,8 @@ _PINGREQUEST = _descriptor.Descriptor(
   extension_ranges=[],
   oneofs=[
   ],
-  serialized_start=989,
-  serialized_end=1051,
+  serialized_start=1303,
+  serialized_end=1365,
 )
 
 
@@ -607,8 +702,8 @@ _PINGREPLY = _descriptor.Descriptor(
   extension_ranges=[],
   oneofs=[
   ],
-  serialized_start=1053,
-  serialized_end=1076,
+  serialized_start=1367,
+  serialized_end=1390,
 )
 
 
@@ -645,8 +740,45 @@ _CONNECTION_PROCESSENVENTRY = _descriptor.Descriptor(
   extension_ranges=[],
   oneofs=[
   ],
-  serialized_start=1358,
-  serialized_end=1407,
+  serialized_start=1790,
+  serialized_end=1839,
+)
+
+_CONNECTION_PROCESSCHECKSUMSENTRY = _descriptor.Descriptor(
+  name='ProcessChecksumsEntry',
+  full_name='protocol.Connection.ProcessChecksumsEntry',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    _descriptor.FieldDescriptor(
+      name='key', full_name='protocol.Connection.ProcessChecksumsEntry.key', index=0,
+      number=1, type=9, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b("").decode('utf-8'),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None, file=DESCRIPTOR),
+    _descriptor.FieldDescriptor(
+      name='value', full_name='protocol.Connection.ProcessChecksumsEntry.value', index=1,
+      number=2, type=9, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b("").decode('utf-8'),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None, file=DESCRIPTOR),
+  ],
+  extensions=[
+  ],
+  nested_types=[],
+  enum_types=[
+  ],
+  options=_descriptor._ParseOptions(descriptor_pb2.MessageOptions(), _b('8\001')),
+  is_extendable=False,
+  syntax='proto3',
+  extension_ranges=[],
+  oneofs=[
+  ],
+  serialized_start=1841,
+  serialized_end=1896,
 )
 
 _CONNECTION = _descriptor.Descriptor(
@@ -740,10 +872,24 @@ _CONNECTION = _descriptor.Descriptor(
       message_type=None, enum_type=None, containing_type=None,
       is_extension=False, extension_scope=None,
       options=None, file=DESCRIPTOR),
+    _descriptor.FieldDescriptor(
+      name='process_checksums', full_name='protocol.Connection.process_checksums', index=12,
+      number=13, type=11, cpp_type=10, label=3,
+      has_default_value=False, default_value=[],
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None, file=DESCRIPTOR),
+    _descriptor.FieldDescriptor(
+      name='process_tree', full_name='protocol.Connection.process_tree', index=13,
+      number=14, type=11, cpp_type=10, label=3,
+      has_default_value=False, default_value=[],
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None, file=DESCRIPTOR),
   ],
   extensions=[
   ],
-  nested_types=[_CONNECTION_PROCESSENVENTRY, ],
+  nested_types=[_CONNECTION_PROCESSENVENTRY, _CONNECTION_PROCESSCHECKSUMSENTRY, ],
   enum_types=[
   ],
   options=None,
@@ -752,8 +898,8 @@ _CONNECTION = _descriptor.Descriptor(
   extension_ranges=[],
   oneofs=[
   ],
-  serialized_start=1079,
-  serialized_end=1407,
+  serialized_start=1393,
+  serialized_end=1896,
 )
 
 
@@ -792,6 +938,13 @@ _OPERATOR = _descriptor.Descriptor(
       message_type=None, enum_type=None, containing_type=None,
       is_extension=False, extension_scope=None,
       options=None, file=DESCRIPTOR),
+    _descriptor.FieldDescriptor(
+      name='list', full_name='protocol.Operator.list', index=4,
+      number=5, type=11, cpp_type=10, label=3,
+      has_default_value=False, default_value=[],
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None, file=DESCRIPTOR),
   ],
   extensions=[
   ],
@@ -804,8 +957,8 @@ _OPERATOR = _descriptor.Descriptor(
   extension_ranges=[],
   oneofs=[
   ],
-  serialized_start=1409,
-  serialized_end=1483,
+  serialized_start=1898,
+  serialized_end=2006,
 )
 
 
@@ -858,6 +1011,34 @@ _RULE = _descriptor.Descriptor(
       message_type=None, enum_type=None, containing_type=None,
       is_extension=False, extension_scope=None,
       options=None, file=DESCRIPTOR),
+    _descriptor.FieldDescriptor(
+      name='priority', full_name='protocol.Rule.priority', index=6,
+      number=7, type=5, cpp_type=1, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None, file=DESCRIPTOR),
+    _descriptor.FieldDescriptor(
+      name='schedule', full_name='protocol.Rule.schedule', index=7,
+      number=8, type=9, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b("").decode('utf-8'),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None, file=DESCRIPTOR),
+    _descriptor.FieldDescriptor(
+      name='limit', full_name='protocol.Rule.limit', index=8,
+      number=9, type=11, cpp_type=10, label=1,
+      has_default_value=False, default_value=None,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None, file=DESCRIPTOR),
+    _descriptor.FieldDescriptor(
+      name='redirect', full_name='protocol.Rule.redirect', index=9,
+      number=10, type=11, cpp_type=10, label=1,
+      has_default_value=False, default_value=None,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None, file=DESCRIPTOR),
   ],
   extensions=[
   ],
@@ -870,8 +1051,8 @@ _RULE = _descriptor.Descriptor(
   extension_ranges=[],
   oneofs=[
   ],
-  serialized_start=1486,
-  serialized_end=1615,
+  serialized_start=2009,
+  serialized_end=2252,
 )
 
 
@@ -943,8 +1124,8 @@ _CLIENTCONFIG = _descriptor.Descriptor(
   extension_ranges=[],
   oneofs=[
   ],
-  serialized_start=1618,
-  serialized_end=1767,
+  serialized_start=2255,
+  serialized_end=2404,
 )
 
 
@@ -1009,8 +1190,8 @@ _NOTIFICATION = _descriptor.Descriptor(
   extension_ranges=[],
   oneofs=[
   ],
-  serialized_start=1770,
-  serialized_end=1913,
+  serialized_start=2407,
+  serialized_end=2550,
 )
 
 
@@ -1054,8 +1235,254 @@ _NOTIFICATIONREPLY = _descriptor.Descriptor(
   extension_ranges=[],
   oneofs=[
   ],
-  serialized_start=1915,
-  serialized_end=2007,
+  serialized_start=2552,
+  serialized_end=2644,
+)
+
+
+_PROCESSPARENT = _descriptor.Descriptor(
+  name='ProcessParent',
+  full_name='protocol.ProcessParent',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    _descriptor.FieldDescriptor(
+      name='pid', full_name='protocol.ProcessParent.pid', index=0,
+      number=1, type=13, cpp_type=3, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None, file=DESCRIPTOR),
+    _descriptor.FieldDescriptor(
+      name='path', full_name='protocol.ProcessParent.path', index=1,
+      number=2, type=9, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b("").decode('utf-8'),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None, file=DESCRIPTOR),
+  ],
+  extensions=[
+  ],
+  nested_types=[],
+  enum_types=[
+  ],
+  options=None,
+  is_extendable=False,
+  syntax='proto3',
+  extension_ranges=[],
+  oneofs=[
+  ],
+  serialized_start=2646,
+  serialized_end=2688,
+)
+
+
+_RULELIMIT = _descriptor.Descriptor(
+  name='RuleLimit',
+  full_name='protocol.RuleLimit',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    _descriptor.FieldDescriptor(
+      name='connections', full_name='protocol.RuleLimit.connections', index=0,
+      number=1, type=13, cpp_type=3, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None, file=DESCRIPTOR),
+    _descriptor.FieldDescriptor(
+      name='interval', full_name='protocol.RuleLimit.interval', index=1,
+      number=2, type=9, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b("").decode('utf-8'),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None, file=DESCRIPTOR),
+    _descriptor.FieldDescriptor(
+      name='bytes_per_day', full_name='protocol.RuleLimit.bytes_per_day', index=2,
+      number=3, type=4, cpp_type=4, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None, file=DESCRIPTOR),
+    _descriptor.FieldDescriptor(
+      name='exceeded', full_name='protocol.RuleLimit.exceeded', index=3,
+      number=4, type=9, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b("").decode('utf-8'),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None, file=DESCRIPTOR),
+  ],
+  extensions=[
+  ],
+  nested_types=[],
+  enum_types=[
+  ],
+  options=None,
+  is_extendable=False,
+  syntax='proto3',
+  extension_ranges=[],
+  oneofs=[
+  ],
+  serialized_start=2690,
+  serialized_end=2781,
+)
+
+
+_RULEREDIRECT = _descriptor.Descriptor(
+  name='RuleRedirect',
+  full_name='protocol.RuleRedirect',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    _descriptor.FieldDescriptor(
+      name='queue', full_name='protocol.RuleRedirect.queue', index=0,
+      number=1, type=13, cpp_type=3, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None, file=DESCRIPTOR),
+    _descriptor.FieldDescriptor(
+      name='mark', full_name='protocol.RuleRedirect.mark', index=1,
+      number=2, type=13, cpp_type=3, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None, file=DESCRIPTOR),
+  ],
+  extensions=[
+  ],
+  nested_types=[],
+  enum_types=[
+  ],
+  options=None,
+  is_extendable=False,
+  syntax='proto3',
+  extension_ranges=[],
+  oneofs=[
+  ],
+  serialized_start=2783,
+  serialized_end=2826,
+)
+
+
+_HISTORYQUERY = _descriptor.Descriptor(
+  name='HistoryQuery',
+  full_name='protocol.HistoryQuery',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    _descriptor.FieldDescriptor(
+      name='process', full_name='protocol.HistoryQuery.process', index=0,
+      number=1, type=9, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b("").decode('utf-8'),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None, file=DESCRIPTOR),
+    _descriptor.FieldDescriptor(
+      name='host', full_name='protocol.HistoryQuery.host', index=1,
+      number=2, type=9, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b("").decode('utf-8'),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None, file=DESCRIPTOR),
+    _descriptor.FieldDescriptor(
+      name='rule', full_name='protocol.HistoryQuery.rule', index=2,
+      number=3, type=9, cpp_type=9, label=1,
+      has_default_value=False, default_value=_b("").decode('utf-8'),
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None, file=DESCRIPTOR),
+    _descriptor.FieldDescriptor(
+      name='from', full_name='protocol.HistoryQuery.from', index=3,
+      number=4, type=3, cpp_type=2, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None, file=DESCRIPTOR),
+    _descriptor.FieldDescriptor(
+      name='to', full_name='protocol.HistoryQuery.to', index=4,
+      number=5, type=3, cpp_type=2, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None, file=DESCRIPTOR),
+    _descriptor.FieldDescriptor(
+      name='limit', full_name='protocol.HistoryQuery.limit', index=5,
+      number=6, type=13, cpp_type=3, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None, file=DESCRIPTOR),
+  ],
+  extensions=[
+  ],
+  nested_types=[],
+  enum_types=[
+  ],
+  options=None,
+  is_extendable=False,
+  syntax='proto3',
+  extension_ranges=[],
+  oneofs=[
+  ],
+  serialized_start=2828,
+  serialized_end=2928,
+)
+
+
+_TRAFFIC = _descriptor.Descriptor(
+  name='Traffic',
+  full_name='protocol.Traffic',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  fields=[
+    _descriptor.FieldDescriptor(
+      name='connections', full_name='protocol.Traffic.connections', index=0,
+      number=1, type=4, cpp_type=4, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None, file=DESCRIPTOR),
+    _descriptor.FieldDescriptor(
+      name='bytes_in', full_name='protocol.Traffic.bytes_in', index=1,
+      number=2, type=4, cpp_type=4, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None, file=DESCRIPTOR),
+    _descriptor.FieldDescriptor(
+      name='bytes_out', full_name='protocol.Traffic.bytes_out', index=2,
+      number=3, type=4, cpp_type=4, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None, file=DESCRIPTOR),
+    _descriptor.FieldDescriptor(
+      name='duration', full_name='protocol.Traffic.duration', index=3,
+      number=4, type=4, cpp_type=4, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      options=None, file=DESCRIPTOR),
+  ],
+  extensions=[
+  ],
+  nested_types=[],
+  enum_types=[
+  ],
+  options=None,
+  is_extendable=False,
+  syntax='proto3',
+  extension_ranges=[],
+  oneofs=[
+  ],
+  serialized_start=2930,
+  serialized_end=3015,
 )
 
 _EVENT.fields_by_name['connection'].message_type = _CONNECTION
@@ -1066,6 +1493,10 @@ _STATISTICS_BYHOSTENTRY.containing_type = _STATISTICS
 _STATISTICS_BYPORTENTRY.containing_type = _STATISTICS
 _STATISTICS_BYUIDENTRY.containing_type = _STATISTICS
 _STATISTICS_BYEXECUTABLEENTRY.containing_type = _STATISTICS
+_STATISTICS_TRAFFICBYEXECUTABLEENTRY.fields_by_name['value'].message_type = _TRAFFIC
+_STATISTICS_TRAFFICBYEXECUTABLEENTRY.containing_type = _STATISTICS
+_STATISTICS_TRAFFICBYRULEENTRY.fields_by_name['value'].message_type = _TRAFFIC
+_STATISTICS_TRAFFICBYRULEENTRY.containing_type = _STATISTICS
 _STATISTICS.fields_by_name['by_proto'].message_type = _STATISTICS_BYPROTOENTRY
 _STATISTICS.fields_by_name['by_address'].message_type = _STATISTICS_BYADDRESSENTRY
 _STATISTICS.fields_by_name['by_host'].message_type = _STATISTICS_BYHOSTENTRY
@@ -1073,10 +1504,18 @@ _STATISTICS.fields_by_name['by_port'].message_type = _STATISTICS_BYPORTENTRY
 _STATISTICS.fields_by_name['by_uid'].message_type = _STATISTICS_BYUIDENTRY
 _STATISTICS.fields_by_name['by_executable'].message_type = _STATISTICS_BYEXECUTABLEENTRY
 _STATISTICS.fields_by_name['events'].message_type = _EVENT
+_STATISTICS.fields_by_name['traffic_by_executable'].message_type = _STATISTICS_TRAFFICBYEXECUTABLEENTRY
+_STATISTICS.fields_by_name['traffic_by_rule'].message_type = _STATISTICS_TRAFFICBYRULEENTRY
 _PINGREQUEST.fields_by_name['stats'].message_type = _STATISTICS
 _CONNECTION_PROCESSENVENTRY.containing_type = _CONNECTION
+_CONNECTION_PROCESSCHECKSUMSENTRY.containing_type = _CONNECTION
 _CONNECTION.fields_by_name['process_env'].message_type = _CONNECTION_PROCESSENVENTRY
+_CONNECTION.fields_by_name['process_checksums'].message_type = _CONNECTION_PROCESSCHECKSUMSENTRY
+_CONNECTION.fields_by_name['process_tree'].message_type = _PROCESSPARENT
+_OPERATOR.fields_by_name['list'].message_type = _OPERATOR
 _RULE.fields_by_name['operator'].message_type = _OPERATOR
+_RULE.fields_by_name['limit'].message_type = _RULELIMIT
+_RULE.fields_by_name['redirect'].message_type = _RULEREDIRECT
 _CLIENTCONFIG.fields_by_name['rules'].message_type = _RULE
 _NOTIFICATION.fields_by_name['type'].enum_type = _ACTION
 _NOTIFICATION.fields_by_name['rules'].message_type = _RULE
@@ -1091,6 +1530,11 @@ DESCRIPTOR.message_types_by_name['Rule'] = _RULE
 DESCRIPTOR.message_types_by_name['ClientConfig'] = _CLIENTCONFIG
 DESCRIPTOR.message_types_by_name['Notification'] = _NOTIFICATION
 DESCRIPTOR.message_types_by_name['NotificationReply'] = _NOTIFICATIONREPLY
+DESCRIPTOR.message_types_by_name['ProcessParent'] = _PROCESSPARENT
+DESCRIPTOR.message_types_by_name['RuleLimit'] = _RULELIMIT
+DESCRIPTOR.message_types_by_name['RuleRedirect'] = _RULEREDIRECT
+DESCRIPTOR.message_types_by_name['HistoryQuery'] = _HISTORYQUERY
+DESCRIPTOR.message_types_by_name['Traffic'] = _TRAFFIC
 DESCRIPTOR.enum_types_by_name['Action'] = _ACTION
 DESCRIPTOR.enum_types_by_name['NotificationReplyCode'] = _NOTIFICATIONREPLYCODE
 _sym_db.RegisterFileDescriptor(DESCRIPTOR)
@@ -1145,6 +1589,20 @@ Statistics = _reflection.GeneratedProtocolMessageType('Statistics', (_message.Me
     # @@protoc_insertion_point(class_scope:protocol.Statistics.ByExecutableEntry)
     ))
   ,
+
+  TrafficByExecutableEntry = _reflection.GeneratedProtocolMessageType('TrafficByExecutableEntry', (_message.Message,), dict(
+    DESCRIPTOR = _STATISTICS_TRAFFICBYEXECUTABLEENTRY,
+    __module__ = 'ui_pb2'
+    # @@protoc_insertion_point(class_scope:protocol.Statistics.TrafficByExecutableEntry)
+    ))
+  ,
+
+  TrafficByRuleEntry = _reflection.GeneratedProtocolMessageType('TrafficByRuleEntry', (_message.Message,), dict(
+    DESCRIPTOR = _STATISTICS_TRAFFICBYRULEENTRY,
+    __module__ = 'ui_pb2'
+    # @@protoc_insertion_point(class_scope:protocol.Statistics.TrafficByRuleEntry)
+    ))
+  ,
   DESCRIPTOR = _STATISTICS,
   __module__ = 'ui_pb2'
   # @@protoc_insertion_point(class_scope:protocol.Statistics)
@@ -1156,6 +1614,8 @@ _sym_db.RegisterMessage(Statistics.ByHostEntry)
 _sym_db.RegisterMessage(Statistics.ByPortEntry)
 _sym_db.RegisterMessage(Statistics.ByUidEntry)
 _sym_db.RegisterMessage(Statistics.ByExecutableEntry)
+_sym_db.RegisterMessage(Statistics.TrafficByExecutableEntry)
+_sym_db.RegisterMessage(Statistics.TrafficByRuleEntry)
 
 PingRequest = _reflection.GeneratedProtocolMessageType('PingRequest', (_message.Message,), dict(
   DESCRIPTOR = _PINGREQUEST,
@@ -1179,12 +1639,20 @@ Connection = _reflection.GeneratedProtocolMessageType('Connection', (_message.Me
     # @@protoc_insertion_point(class_scope:protocol.Connection.ProcessEnvEntry)
     ))
   ,
+
+  ProcessChecksumsEntry = _reflection.GeneratedProtocolMessageType('ProcessChecksumsEntry', (_message.Message,), dict(
+    DESCRIPTOR = _CONNECTION_PROCESSCHECKSUMSENTRY,
+    __module__ = 'ui_pb2'
+    # @@protoc_insertion_point(class_scope:protocol.Connection.ProcessChecksumsEntry)
+    ))
+  ,
   DESCRIPTOR = _CONNECTION,
   __module__ = 'ui_pb2'
   # @@protoc_insertion_point(class_scope:protocol.Connection)
   ))
 _sym_db.RegisterMessage(Connection)
 _sym_db.RegisterMessage(Connection.ProcessEnvEntry)
+_sym_db.RegisterMessage(Connection.ProcessChecksumsEntry)
 
 Operator = _reflection.GeneratedProtocolMessageType('Operator', (_message.Message,), dict(
   DESCRIPTOR = _OPERATOR,
@@ -1221,6 +1689,41 @@ NotificationReply = _reflection.GeneratedProtocolMessageType('NotificationReply'
   ))
 _sym_db.RegisterMessage(NotificationReply)
 
+ProcessParent = _reflection.GeneratedProtocolMessageType('ProcessParent', (_message.Message,), dict(
+  DESCRIPTOR = _PROCESSPARENT,
+  __module__ = 'ui_pb2'
+  # @@protoc_insertion_point(class_scope:protocol.ProcessParent)
+  ))
+_sym_db.RegisterMessage(ProcessParent)
+
+RuleLimit = _reflection.GeneratedProtocolMessageType('RuleLimit', (_message.Message,), dict(
+  DESCRIPTOR = _RULELIMIT,
+  __module__ = 'ui_pb2'
+  # @@protoc_insertion_point(class_scope:protocol.RuleLimit)
+  ))
+_sym_db.RegisterMessage(RuleLimit)
+
+RuleRedirect = _reflection.GeneratedProtocolMessageType('RuleRedirect', (_message.Message,), dict(
+  DESCRIPTOR = _RULEREDIRECT,
+  __module__ = 'ui_pb2'
+  # @@protoc_insertion_point(class_scope:protocol.RuleRedirect)
+  ))
+_sym_db.RegisterMessage(RuleRedirect)
+
+HistoryQuery = _reflection.GeneratedProtocolMessageType('HistoryQuery', (_message.Message,), dict(
+  DESCRIPTOR = _HISTORYQUERY,
+  __module__ = 'ui_pb2'
+  # @@protoc_insertion_point(class_scope:protocol.HistoryQuery)
+  ))
+_sym_db.RegisterMessage(HistoryQuery)
+
+Traffic = _reflection.GeneratedProtocolMessageType('Traffic', (_message.Message,), dict(
+  DESCRIPTOR = _TRAFFIC,
+  __module__ = 'ui_pb2'
+  # @@protoc_insertion_point(class_scope:protocol.Traffic)
+  ))
+_sym_db.RegisterMessage(Traffic)
+
 
 _STATISTICS_BYPROTOENTRY.has_options = True
 _STATISTICS_BYPROTOENTRY._options = _descriptor._ParseOptions(descriptor_pb2.MessageOptions(), _b('8\001'))
@@ -1234,8 +1737,14 @@ _STATISTICS_BYUIDENTRY.has_options = True
 _STATISTICS_BYUIDENTRY._options = _descriptor._ParseOptions(descriptor_pb2.MessageOptions(), _b('8\001'))
 _STATISTICS_BYEXECUTABLEENTRY.has_options = True
 _STATISTICS_BYEXECUTABLEENTRY._options = _descriptor._ParseOptions(descriptor_pb2.MessageOptions(), _b('8\001'))
+_STATISTICS_TRAFFICBYEXECUTABLEENTRY.has_options = True
+_STATISTICS_TRAFFICBYEXECUTABLEENTRY._options = _descriptor._ParseOptions(descriptor_pb2.MessageOptions(), _b('8\001'))
+_STATISTICS_TRAFFICBYRULEENTRY.has_options = True
+_STATISTICS_TRAFFICBYRULEENTRY._options = _descriptor._ParseOptions(descriptor_pb2.MessageOptions(), _b('8\001'))
 _CONNECTION_PROCESSENVENTRY.has_options = True
 _CONNECTION_PROCESSENVENTRY._options = _descriptor._ParseOptions(descriptor_pb2.MessageOptions(), _b('8\001'))
+_CONNECTION_PROCESSCHECKSUMSENTRY.has_options = True
+_CONNECTION_PROCESSCHECKSUMSENTRY._options = _descriptor._ParseOptions(descriptor_pb2.MessageOptions(), _b('8\001'))
 
 _UI = _descriptor.ServiceDescriptor(
   name='UI',
@@ -1243,8 +1752,8 @@ _UI = _descriptor.ServiceDescriptor(
   file=DESCRIPTOR,
   index=0,
   options=None,
-  serialized_start=2275,
-  serialized_end=2523,
+  serialized_start=3283,
+  serialized_end=3531,
   methods=[
   _descriptor.MethodDescriptor(
     name='Ping',
@@ -1287,4 +1796,64 @@ _sym_db.RegisterServiceDescriptor(_UI)
 
 DESCRIPTOR.services_by_name['UI'] = _UI
 
+
+_DAEMON = _descriptor.ServiceDescriptor(
+  name='Daemon',
+  full_name='protocol.Daemon',
+  file=DESCRIPTOR,
+  index=1,
+  options=None,
+  serialized_start=3534,
+  serialized_end=3856,
+  methods=[
+  _descriptor.MethodDescriptor(
+    name='GetRules',
+    full_name='protocol.Daemon.GetRules',
+    index=0,
+    containing_service=None,
+    input_type=_NOTIFICATION,
+    output_type=_NOTIFICATION,
+    options=None,
+  ),
+  _descriptor.MethodDescriptor(
+    name='GetStatistics',
+    full_name='protocol.Daemon.GetStatistics',
+    index=1,
+    containing_service=None,
+    input_type=_NOTIFICATION,
+    output_type=_STATISTICS,
+    options=None,
+  ),
+  _descriptor.MethodDescriptor(
+    name='Notify',
+    full_name='protocol.Daemon.Notify',
+    index=2,
+    containing_service=None,
+    input_type=_NOTIFICATION,
+    output_type=_NOTIFICATIONREPLY,
+    options=None,
+  ),
+  _descriptor.MethodDescriptor(
+    name='StreamEvents',
+    full_name='protocol.Daemon.StreamEvents',
+    index=3,
+    containing_service=None,
+    input_type=_NOTIFICATION,
+    output_type=_EVENT,
+    options=None,
+  ),
+  _descriptor.MethodDescriptor(
+    name='QueryHistory',
+    full_name='protocol.Daemon.QueryHistory',
+    index=4,
+    containing_service=None,
+    input_type=_HISTORYQUERY,
+    output_type=_EVENT,
+    options=None,
+  ),
+])
+_sym_db.RegisterServiceDescriptor(_DAEMON)
+
+DESCRIPTOR.services_by_name['Daemon'] = _DAEMON
+
 # @@protoc_insertion_point(module_scope)
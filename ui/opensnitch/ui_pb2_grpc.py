@@ -95,3 +95,115 @@ def add_UIServicer_to_server(servicer, server):
   generic_handler = grpc.method_handlers_generic_handler(
       'protocol.UI', rpc_method_handlers)
   server.add_generic_rpc_handlers((generic_handler,))
+
+
+class DaemonStub(object):
+  """Daemon is the service the daemon exposes to be managed without a GUI.
+  """
+
+  def __init__(self, channel):
+    """Constructor.
+
+    Args:
+      channel: A grpc.Channel.
+    """
+    self.GetRules = channel.unary_unary(
+        '/protocol.Daemon/GetRules',
+        request_serializer=ui__pb2.Notification.SerializeToString,
+        response_deserializer=ui__pb2.Notification.FromString,
+        )
+    self.GetStatistics = channel.unary_unary(
+        '/protocol.Daemon/GetStatistics',
+        request_serializer=ui__pb2.Notification.SerializeToString,
+        response_deserializer=ui__pb2.Statistics.FromString,
+        )
+    self.Notify = channel.unary_unary(
+        '/protocol.Daemon/Notify',
+        request_serializer=ui__pb2.Notification.SerializeToString,
+        response_deserializer=ui__pb2.NotificationReply.FromString,
+        )
+    self.StreamEvents = channel.unary_stream(
+        '/protocol.Daemon/StreamEvents',
+        request_serializer=ui__pb2.Notification.SerializeToString,
+        response_deserializer=ui__pb2.Event.FromString,
+        )
+    self.QueryHistory = channel.unary_stream(
+        '/protocol.Daemon/QueryHistory',
+        request_serializer=ui__pb2.HistoryQuery.SerializeToString,
+        response_deserializer=ui__pb2.Event.FromString,
+        )
+
+
+class DaemonServicer(object):
+  """Daemon is the service the daemon exposes to be managed without a GUI.
+  """
+
+  def GetRules(self, request, context):
+    """the rules loaded in the rules field of the reply, and the configuration
+    in the data field
+    """
+    context.set_code(grpc.StatusCode.UNIMPLEMENTED)
+    context.set_details('Method not implemented!')
+    raise NotImplementedError('Method not implemented!')
+
+  def GetStatistics(self, request, context):
+    # missing associated documentation comment in .proto file
+    pass
+    context.set_code(grpc.StatusCode.UNIMPLEMENTED)
+    context.set_details('Method not implemented!')
+    raise NotImplementedError('Method not implemented!')
+
+  def Notify(self, request, context):
+    """applies an action: CHANGE_RULE, DELETE_RULE, ENABLE_RULE, DISABLE_RULE,
+    CHANGE_CONFIG, LOAD_FIREWALL, UNLOAD_FIREWALL
+    """
+    context.set_code(grpc.StatusCode.UNIMPLEMENTED)
+    context.set_details('Method not implemented!')
+    raise NotImplementedError('Method not implemented!')
+
+  def StreamEvents(self, request, context):
+    """the connections events, as the rules are applied
+    """
+    context.set_code(grpc.StatusCode.UNIMPLEMENTED)
+    context.set_details('Method not implemented!')
+    raise NotImplementedError('Method not implemented!')
+
+  def QueryHistory(self, request, context):
+    """the connections saved to the history, newest first
+    """
+    context.set_code(grpc.StatusCode.UNIMPLEMENTED)
+    context.set_details('Method not implemented!')
+    raise NotImplementedError('Method not implemented!')
+
+
+def add_DaemonServicer_to_server(servicer, server):
+  rpc_method_handlers = {
+      'GetRules': grpc.unary_unary_rpc_method_handler(
+          servicer.GetRules,
+          request_deserializer=ui__pb2.Notification.FromString,
+          response_serializer=ui__pb2.Notification.SerializeToString,
+      ),
+      'GetStatistics': grpc.unary_unary_rpc_method_handler(
+          servicer.GetStatistics,
+          request_deserializer=ui__pb2.Notification.FromString,
+          response_serializer=ui__pb2.Statistics.SerializeToString,
+      ),
+      'Notify': grpc.unary_unary_rpc_method_handler(
+          servicer.Notify,
+          request_deserializer=ui__pb2.Notification.FromString,
+          response_serializer=ui__pb2.NotificationReply.SerializeToString,
+      ),
+      'StreamEvents': grpc.unary_stream_rpc_method_handler(
+          servicer.StreamEvents,
+          request_deserializer=ui__pb2.Notification.FromString,
+          response_serializer=ui__pb2.Event.SerializeToString,
+      ),
+      'QueryHistory': grpc.unary_stream_rpc_method_handler(
+          servicer.QueryHistory,
+          request_deserializer=ui__pb2.HistoryQuery.FromString,
+          response_serializer=ui__pb2.Event.SerializeToString,
+      ),
+  }
+  generic_handler = grpc.method_handlers_generic_handler(
+      'protocol.Daemon', rpc_method_handlers)
+  server.add_generic_rpc_handlers((generic_handler,))